@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/local"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+const (
+	beeBlockstore   = "bee"
+	localBlockstore = "local"
+)
+
+// newBlockstoreClient creates the block store selected in the command line
+func newBlockstoreClient(logger logging.Logger) (blockstore.Client, error) {
+	switch strings.ToLower(blockstoreName) {
+	case beeBlockstore:
+		opts := bee.DefaultOptions()
+		opts.RequestTimeout = beeTimeout
+		opts.MaxRetries = beeRetries
+		opts.Cache = cache.New(cacheSize * 1024 * 1024)
+		if beeCacheDir != "" {
+			diskCache, err := bee.NewDiskCache(beeCacheDir, beeCacheSize*1024*1024, logger)
+			if err != nil {
+				return nil, err
+			}
+			opts.DiskCache = diskCache
+		}
+		if len(beeUrls) > 0 {
+			multiOpts := bee.DefaultMultiOptions()
+			multiOpts.Options = opts
+			multiOpts.ReadStrategy = strings.ToLower(beeReadStrategy)
+			return bee.NewMultiBeeClient(beeUrls, multiOpts, logger)
+		}
+		return bee.NewBeeClientWithOptions(beeHost, beePort, opts, logger), nil
+	case localBlockstore:
+		dir := blockstoreDir
+		if dir == "" {
+			dir = filepath.Join(dataDir, "blockstore")
+		}
+		return local.NewLocalClient(dir, logger)
+	default:
+		return nil, fmt.Errorf("unknown blockstore %s", blockstoreName)
+	}
+}
+
+// closeBlockstoreClient stops what the block store runs in the background,
+// like the health checks of the bee nodes.
+func closeBlockstoreClient(client blockstore.Client, logger logging.Logger) {
+	closer, ok := client.(io.Closer)
+	if !ok {
+		return
+	}
+	err := closer.Close()
+	if err != nil {
+		logger.Errorf("closing block store: %v", err)
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
@@ -27,7 +29,9 @@ import (
 	"time"
 
 	prompt "github.com/c-bata/go-prompt"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 	"github.com/jmozah/intOS-dfs/pkg/pod"
 	"github.com/jmozah/intOS-dfs/pkg/user"
@@ -44,11 +48,12 @@ const (
 )
 
 var (
-	currentUser    string
-	currentPodInfo *pod.Info
-	currentPrompt  string
-	dfsAPI         *dfs.DfsAPI
-	logger         logging.Logger
+	currentUser      string
+	currentPodInfo   *pod.Info
+	currentPrompt    string
+	dfsAPI           *dfs.DfsAPI
+	blockstoreClient blockstore.Client
+	logger           logging.Logger
 )
 
 // promptCmd represents the prompt command
@@ -59,13 +64,25 @@ var promptCmd = &cobra.Command{
 file system of the intOS.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		logger = logging.New(ioutil.Discard, 0)
-		api, err := dfs.NewDfsAPI(dataDir, beeHost, beePort, logger)
+		client, err := newBlockstoreClient(logger)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		format, err := feed.ParseFormat(feedFormat)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		api, err := dfs.NewDfsAPI(dataDir, client, format, logger)
 		if err != nil {
 			fmt.Println(err.Error())
 			return
 		}
 		dfsAPI = api
+		blockstoreClient = client
 		initPrompt()
+		closeBlockstoreClient(client, logger)
 	},
 }
 
@@ -110,6 +127,8 @@ var suggestions = []prompt.Suggest{
 	{Text: "pod ls", Description: "list all the existing pods of  auser"},
 	{Text: "pod stat", Description: "show the metadata of a pod of a user"},
 	{Text: "pod sync", Description: "sync the pod from swarm"},
+	{Text: "pod gc", Description: "unpin what is not reachable from the pod"},
+	{Text: "pod migrate", Description: "rewrite the directory feeds of the pod in bee's feed format"},
 	{Text: "cd", Description: "change path"},
 	{Text: "copyToLocal", Description: "copy file from dfs to local machine"},
 	{Text: "copyFromLocal", Description: "copy file from local machine to dfs"},
@@ -123,6 +142,13 @@ var suggestions = []prompt.Suggest{
 	{Text: "rmdir", Description: "remove a existing directory"},
 	{Text: "pwd", Description: "show the current working directory"},
 	{Text: "rm", Description: "remove a file"},
+	{Text: "append", Description: "add the contents of a local file to the end of a file"},
+	{Text: "write", Description: "write the contents of a local file in to a file at an offset, not past its end"},
+	{Text: "truncate", Description: "cut a file down to a size, not past its end"},
+	{Text: "versions", Description: "list the earlier versions of a file"},
+	{Text: "version download", Description: "copy an earlier version of a file to local machine"},
+	{Text: "version restore", Description: "make an earlier version of a file the current one"},
+	{Text: "version prune", Description: "drop the earlier versions of a file by age or count"},
 }
 
 func completer(in prompt.Document) []prompt.Suggest {
@@ -134,12 +160,14 @@ func completer(in prompt.Document) []prompt.Suggest {
 }
 
 func executor(in string) {
+	ctx := context.Background()
 	in = strings.TrimSpace(in)
 	blocks := strings.Split(in, " ")
 	switch blocks[0] {
 	case "help":
 		help()
 	case "exit":
+		closeBlockstoreClient(blockstoreClient, logger)
 		os.Exit(0)
 	case "user":
 		if len(blocks) < 2 {
@@ -154,7 +182,7 @@ func executor(in string) {
 				return
 			}
 			userName := blocks[2]
-			ref, mnemonic, err := dfsAPI.CreateUser(userName, "", "", nil, DefaultSessionId)
+			ref, mnemonic, err := dfsAPI.CreateUser(ctx, userName, "", "", nil, DefaultSessionId)
 			if err != nil {
 				fmt.Println("create user: ", err)
 				return
@@ -182,7 +210,7 @@ func executor(in string) {
 			if len(blocks) == 4 {
 				userName := blocks[2]
 				address := blocks[3]
-				err := dfsAPI.ImportUserUsingAddress(userName, "", address, nil, DefaultSessionId)
+				err := dfsAPI.ImportUserUsingAddress(ctx, userName, "", address, nil, DefaultSessionId)
 				if err != nil {
 					fmt.Println("import user: ", err)
 					return
@@ -203,7 +231,7 @@ func executor(in string) {
 				mnemonic = mnemonic + " " + blocks[i]
 			}
 			mnemonic = strings.TrimPrefix(mnemonic, " ")
-			_, err := dfsAPI.ImportUserUsingMnemonic(userName, "", mnemonic, nil, DefaultSessionId)
+			_, err := dfsAPI.ImportUserUsingMnemonic(ctx, userName, "", mnemonic, nil, DefaultSessionId)
 			if err != nil {
 				fmt.Println("import user: ", err)
 				return
@@ -212,7 +240,7 @@ func executor(in string) {
 			currentPodInfo = nil
 			currentPrompt = getCurrentPrompt()
 		case "del":
-			err := dfsAPI.DeleteUser("", DefaultSessionId, nil)
+			err := dfsAPI.DeleteUser(ctx, "", DefaultSessionId, nil)
 			if err != nil {
 				fmt.Println("delete user: ", err)
 				return
@@ -226,7 +254,7 @@ func executor(in string) {
 				return
 			}
 			userName := blocks[2]
-			err := dfsAPI.LoginUser(userName, "", nil, DefaultSessionId)
+			err := dfsAPI.LoginUser(ctx, userName, "", nil, DefaultSessionId)
 			if err != nil {
 				fmt.Println("login user: ", err)
 				return
@@ -270,13 +298,13 @@ func executor(in string) {
 				middleName := blocks[3]
 				lastName := blocks[4]
 				surNmae := blocks[5]
-				err := dfsAPI.SaveName(firstName, lastName, middleName, surNmae, DefaultSessionId)
+				err := dfsAPI.SaveName(ctx, firstName, lastName, middleName, surNmae, DefaultSessionId)
 				if err != nil {
 					fmt.Println("name: ", err)
 					return
 				}
 			} else if len(blocks) == 2 {
-				name, err := dfsAPI.GetName(DefaultSessionId)
+				name, err := dfsAPI.GetName(ctx, DefaultSessionId)
 				if err != nil {
 					fmt.Println("name: ", err)
 					return
@@ -301,13 +329,13 @@ func executor(in string) {
 					State:        state,
 					ZipCode:      zip,
 				}
-				err := dfsAPI.SaveContact(phone, mobile, addr, DefaultSessionId)
+				err := dfsAPI.SaveContact(ctx, phone, mobile, addr, DefaultSessionId)
 				if err != nil {
 					fmt.Println("contact: ", err)
 					return
 				}
 			} else if len(blocks) == 2 {
-				contacts, err := dfsAPI.GetContact(DefaultSessionId)
+				contacts, err := dfsAPI.GetContact(ctx, DefaultSessionId)
 				if err != nil {
 					fmt.Println("contact: ", err)
 					return
@@ -327,7 +355,7 @@ func executor(in string) {
 			}
 			switch blocks[2] {
 			case "inbox":
-				inbox, err := dfsAPI.GetUserSharingInbox(DefaultSessionId)
+				inbox, err := dfsAPI.GetUserSharingInbox(ctx, DefaultSessionId)
 				if err != nil {
 					fmt.Println("sharing inbox: ", err)
 					return
@@ -336,7 +364,7 @@ func executor(in string) {
 					fmt.Println(entry)
 				}
 			case "outbox":
-				outbox, err := dfsAPI.GetUserSharingOutbox(DefaultSessionId)
+				outbox, err := dfsAPI.GetUserSharingOutbox(ctx, DefaultSessionId)
 				if err != nil {
 					fmt.Println("sharing outbox: ", err)
 					return
@@ -366,7 +394,7 @@ func executor(in string) {
 				return
 			}
 			podName := blocks[2]
-			podInfo, err := dfsAPI.CreatePod(podName, "", DefaultSessionId)
+			podInfo, err := dfsAPI.CreatePod(ctx, podName, "", DefaultSessionId)
 			if err != nil {
 				fmt.Println("could not create pod: ", err)
 				return
@@ -380,7 +408,7 @@ func executor(in string) {
 				return
 			}
 			podName := blocks[2]
-			err := dfsAPI.DeletePod(podName, DefaultSessionId)
+			err := dfsAPI.DeletePod(ctx, podName, DefaultSessionId)
 			if err != nil {
 				fmt.Println("could not delete pod: ", err)
 				return
@@ -397,7 +425,7 @@ func executor(in string) {
 				return
 			}
 			podName := blocks[2]
-			podInfo, err := dfsAPI.OpenPod(podName, "", DefaultSessionId)
+			podInfo, err := dfsAPI.OpenPod(ctx, podName, "", DefaultSessionId)
 			if err != nil {
 				fmt.Println("Open failed: ", err)
 				return
@@ -450,15 +478,37 @@ func executor(in string) {
 			if !isPodOpened() {
 				return
 			}
-			err := dfsAPI.SyncPod(DefaultSessionId)
+			err := dfsAPI.SyncPod(ctx, DefaultSessionId)
 			if err != nil {
 				fmt.Println("could not sync pod: ", err)
 				return
 			}
 			fmt.Println("pod synced.")
 			currentPrompt = getCurrentPrompt()
+		case "gc":
+			if !isPodOpened() {
+				return
+			}
+			stats, err := dfsAPI.CollectPodGarbage(ctx, DefaultSessionId)
+			if err != nil {
+				fmt.Println("could not collect garbage of pod: ", err)
+				return
+			}
+			fmt.Printf("unpinned %d blobs and %d chunks, %d failed.\n", stats.Blobs, stats.Chunks, stats.Failed)
+			currentPrompt = getCurrentPrompt()
+		case "migrate":
+			if !isPodOpened() {
+				return
+			}
+			dirs, err := dfsAPI.MigratePodToBeeFeeds(ctx, DefaultSessionId)
+			if err != nil {
+				fmt.Println("could not migrate pod: ", err)
+				return
+			}
+			fmt.Printf("migrated the feeds of %d directories.\n", dirs)
+			currentPrompt = getCurrentPrompt()
 		case "ls":
-			pods, err := dfsAPI.ListPods(DefaultSessionId)
+			pods, err := dfsAPI.ListPods(ctx, DefaultSessionId)
 			if err != nil {
 				fmt.Println("error while listing pods: %w", err)
 				return
@@ -479,7 +529,7 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		podInfo, err := dfsAPI.ChangeDirectory(blocks[1], DefaultSessionId)
+		podInfo, err := dfsAPI.ChangeDirectory(ctx, blocks[1], DefaultSessionId)
 		if err != nil {
 			fmt.Println("cd failed: ", err)
 			return
@@ -490,7 +540,7 @@ func executor(in string) {
 		if !isPodOpened() {
 			return
 		}
-		entries, err := dfsAPI.ListDir("", DefaultSessionId)
+		entries, err := dfsAPI.ListDir(ctx, "", DefaultSessionId)
 		if err != nil {
 			fmt.Println("ls failed: ", err)
 			return
@@ -512,7 +562,7 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		err := dfsAPI.CopyToLocal(blocks[1], blocks[2], DefaultSessionId)
+		err := dfsAPI.CopyToLocal(ctx, blocks[1], blocks[2], DefaultSessionId)
 		if err != nil {
 			fmt.Println("download failed: ", err)
 			return
@@ -540,7 +590,7 @@ func executor(in string) {
 		podDir := blocks[2]
 		blockSize := blocks[3]
 		compression := blocks[4]
-		ref, err := dfsAPI.UploadFile(fileName, DefaultSessionId, fi.Size(), fd, podDir, blockSize, compression)
+		ref, err := dfsAPI.UploadFile(ctx, fileName, DefaultSessionId, fi.Size(), fd, podDir, blockSize, compression)
 		if err != nil {
 			fmt.Println("upload failed: ", err)
 			return
@@ -555,7 +605,7 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		err := dfsAPI.Mkdir(blocks[1], DefaultSessionId)
+		err := dfsAPI.Mkdir(ctx, blocks[1], DefaultSessionId)
 		if err != nil {
 			fmt.Println("mkdir failed: ", err)
 			return
@@ -569,7 +619,7 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		err := dfsAPI.RmDir(blocks[1], DefaultSessionId)
+		err := dfsAPI.RmDir(ctx, blocks[1], DefaultSessionId)
 		if err != nil {
 			fmt.Println("rmdir failed: ", err)
 			return
@@ -583,7 +633,7 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		err := dfsAPI.Cat(blocks[1], DefaultSessionId)
+		err := dfsAPI.Cat(ctx, blocks[1], DefaultSessionId)
 		if err != nil {
 			fmt.Println("cat failed: ", err)
 			return
@@ -597,10 +647,10 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		ds, err := dfsAPI.DirectoryStat(blocks[1], DefaultSessionId, true)
+		ds, err := dfsAPI.DirectoryStat(ctx, blocks[1], DefaultSessionId, true)
 		if err != nil {
 			if err.Error() == "directory not found" {
-				fs, err := dfsAPI.FileStat(blocks[1], DefaultSessionId)
+				fs, err := dfsAPI.FileStat(ctx, blocks[1], DefaultSessionId)
 				if err != nil {
 					fmt.Println("stat failed: ", err)
 					return
@@ -691,19 +741,182 @@ func executor(in string) {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
-		err := dfsAPI.DeleteFile(blocks[1], DefaultSessionId)
+		err := dfsAPI.DeleteFile(ctx, blocks[1], DefaultSessionId)
 		if err != nil {
 			fmt.Println("rm failed: ", err)
 			return
 		}
 		currentPrompt = getCurrentPrompt()
+	case "append":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		data, err := ioutil.ReadFile(blocks[2])
+		if err != nil {
+			fmt.Println("append failed: ", err)
+			return
+		}
+		ref, err := dfsAPI.AppendFile(ctx, blocks[1], data, DefaultSessionId)
+		if err != nil {
+			fmt.Println("append failed: ", err)
+			return
+		}
+		fmt.Println("reference : ", ref)
+		currentPrompt = getCurrentPrompt()
+	case "write":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 4 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		offset, err := strconv.ParseUint(blocks[2], 10, 64)
+		if err != nil {
+			fmt.Println("write failed: invalid offset: ", err)
+			return
+		}
+		data, err := ioutil.ReadFile(blocks[3])
+		if err != nil {
+			fmt.Println("write failed: ", err)
+			return
+		}
+		ref, err := dfsAPI.WriteFile(ctx, blocks[1], data, offset, DefaultSessionId)
+		if err != nil {
+			fmt.Println("write failed: ", err)
+			return
+		}
+		fmt.Println("reference : ", ref)
+		currentPrompt = getCurrentPrompt()
+	case "truncate":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		size, err := strconv.ParseUint(blocks[2], 10, 64)
+		if err != nil {
+			fmt.Println("truncate failed: invalid size: ", err)
+			return
+		}
+		ref, err := dfsAPI.TruncateFile(ctx, blocks[1], size, DefaultSessionId)
+		if err != nil {
+			fmt.Println("truncate failed: ", err)
+			return
+		}
+		fmt.Println("reference : ", ref)
+		currentPrompt = getCurrentPrompt()
+	case "versions":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 2 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		versions, err := dfsAPI.ListFileVersions(blocks[1], DefaultSessionId)
+		if err != nil {
+			fmt.Println("versions failed: ", err)
+			return
+		}
+		for _, version := range versions {
+			fmt.Println(utils.NewReference(version.Reference).String(), version.FileSize, time.Unix(version.ModificationTime, 0).String())
+		}
+		currentPrompt = getCurrentPrompt()
+	case "version":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		switch blocks[1] {
+		case "download":
+			if len(blocks) < 5 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			reader, _, _, err := dfsAPI.DownloadFileVersion(ctx, blocks[2], blocks[3], DefaultSessionId)
+			if err != nil {
+				fmt.Println("version download failed: ", err)
+				return
+			}
+			defer reader.Close()
+			out, err := os.Create(filepath.Join(blocks[4], filepath.Base(blocks[2])))
+			if err != nil {
+				fmt.Println("version download failed: ", err)
+				return
+			}
+			_, err = io.Copy(out, reader)
+			if err != nil {
+				_ = out.Close()
+				fmt.Println("version download failed: ", err)
+				return
+			}
+			err = out.Close()
+			if err != nil {
+				fmt.Println("version download failed: ", err)
+				return
+			}
+		case "restore":
+			if len(blocks) < 4 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			ref, err := dfsAPI.RestoreFileVersion(ctx, blocks[2], blocks[3], DefaultSessionId)
+			if err != nil {
+				fmt.Println("version restore failed: ", err)
+				return
+			}
+			fmt.Println("reference : ", ref)
+		case "prune":
+			if len(blocks) < 5 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			var maxAge time.Duration
+			if blocks[3] != "-" {
+				age, err := time.ParseDuration(blocks[3])
+				if err != nil {
+					fmt.Println("version prune failed: invalid max age: ", err)
+					return
+				}
+				maxAge = age
+			}
+			maxCount := -1
+			if blocks[4] != "-" {
+				count, err := strconv.Atoi(blocks[4])
+				if err != nil || count < 0 {
+					fmt.Println("version prune failed: invalid max count")
+					return
+				}
+				maxCount = count
+			}
+			ref, err := dfsAPI.PruneFileVersions(ctx, blocks[2], maxAge, maxCount, DefaultSessionId)
+			if err != nil {
+				fmt.Println("version prune failed: ", err)
+				return
+			}
+			fmt.Println("reference : ", ref)
+		default:
+			fmt.Println("invalid version command")
+			return
+		}
+		currentPrompt = getCurrentPrompt()
 	case "share":
 		if len(blocks) < 2 {
 			fmt.Println("invalid command. Missing one or more arguments")
 			return
 		}
 		podFile := blocks[1]
-		sharingRef, err := dfsAPI.ShareFile(podFile, currentUser, DefaultSessionId)
+		sharingRef, err := dfsAPI.ShareFile(ctx, podFile, currentUser, DefaultSessionId)
 		if err != nil {
 			fmt.Println("share: ", err)
 			return
@@ -723,7 +936,7 @@ func executor(in string) {
 			fmt.Println("receive: ", err)
 			return
 		}
-		filePath, metaRef, err := dfsAPI.ReceiveFile(DefaultSessionId, sharingRef, podDir)
+		filePath, metaRef, err := dfsAPI.ReceiveFile(ctx, DefaultSessionId, sharingRef, podDir)
 		if err != nil {
 			fmt.Println("receive: ", err)
 			return
@@ -742,7 +955,7 @@ func executor(in string) {
 			fmt.Println("receive info: ", err)
 			return
 		}
-		ri, err := dfsAPI.ReceiveInfo(DefaultSessionId, sharingRef)
+		ri, err := dfsAPI.ReceiveInfo(ctx, DefaultSessionId, sharingRef)
 		if err != nil {
 			fmt.Println("receive info: ", err)
 			return
@@ -798,19 +1011,28 @@ func help() {
 	fmt.Println(" - pod <open> (pod-name) - open a already created pod")
 	fmt.Println(" - pod <stat> (pod-name) - display meta information about a pod")
 	fmt.Println(" - pod <sync> (pod-name) - sync the contents of a logged in pod from Swarm")
+	fmt.Println(" - pod <gc> - unpin the blocks of a logged in pod which are not reachable any more")
+	fmt.Println(" - pod <migrate> - rewrite the directory feeds of a logged in pod in bee's feed format")
 	fmt.Println(" - pod <close>  - close a opened pod")
 	fmt.Println(" - pod <ls> - lists all the pods created for this account")
 
 	fmt.Println(" - cd <directory name>")
 	fmt.Println(" - ls ")
 	fmt.Println(" - download <relative path of source file in pod, destination dir in local fs>")
-	fmt.Println(" - upload <source file in local fs, destination directory in pod, block size (ex: 1Mb, 64Mb)>, compression true/false")
+	fmt.Println(" - upload <source file in local fs, destination directory in pod, block size (ex: 1Mb, 64Mb)>, compression true/false - a file already in the directory is replaced and kept as a version")
 	fmt.Println(" - share <file name> -  shares a file with another user")
 	fmt.Println(" - receive <sharing reference> <pod dir> - receives a file from another user")
 	fmt.Println(" - receiveinfo <sharing reference> - shows the received file info before accepting the receive")
 	fmt.Println(" - mkdir <directory name>")
 	fmt.Println(" - rmdir <directory name>")
 	fmt.Println(" - rm <file name>")
+	fmt.Println(" - append <file name> <local file> - adds the contents of the local file to the end of the file")
+	fmt.Println(" - write <file name> <offset> <local file> - writes the contents of the local file in to the file from the offset on, which may not be past its end")
+	fmt.Println(" - truncate <file name> <size> - cuts the file down to the size, dropping what is past it, which may not be past its end")
+	fmt.Println(" - versions <file name> - lists the earlier versions of the file, the latest first")
+	fmt.Println(" - version <download> <file name> <version reference> <destination dir in local fs> - copies an earlier version of the file")
+	fmt.Println(" - version <restore> <file name> <version reference> - makes an earlier version of the file the current one")
+	fmt.Println(" - version <prune> <file name> <max age (ex: 720h) or -> <max count or -> - drops the versions older than max age or past the latest max count")
 	fmt.Println(" - pwd - show present working directory")
 	fmt.Println(" - cat  - stream the file to stdout")
 	fmt.Println(" - stat <file name or directory name> - shows the information about a file or directory")
@@ -20,19 +20,34 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/metrics"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile   string
-	beeHost   string
-	beePort   string
-	httpPort  string
-	verbosity string
-	dataDir   string
+	cfgFile         string
+	beeHost         string
+	beePort         string
+	httpPort        string
+	verbosity       string
+	dataDir         string
+	blockstoreName  string
+	blockstoreDir   string
+	beeTimeout      time.Duration
+	beeRetries      int
+	beeUrls         []string
+	beeReadStrategy string
+	beeCacheDir     string
+	beeCacheSize    int64
+	cacheSize       int64
+	slowRequest     time.Duration
+	feedFormat      string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -93,6 +108,17 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&beePort, "beePort", "8080", "bee port (default 8080)")
 	rootCmd.PersistentFlags().StringVar(&httpPort, "httpPort", "9090", "http port (default 9090)")
 	rootCmd.PersistentFlags().StringVar(&verbosity, "verbosity", "5", "verbosity level (default 4)")
+	rootCmd.PersistentFlags().StringVar(&blockstoreName, "blockstore", "bee", "block store to use, bee or local (default bee)")
+	rootCmd.PersistentFlags().StringSliceVar(&beeUrls, "beeUrls", nil, "comma separated urls of bee nodes to use instead of beeHost and beePort, ex: http://10.0.0.1:8080,http://10.0.0.2:8080")
+	rootCmd.PersistentFlags().StringVar(&beeReadStrategy, "beeReadStrategy", bee.RoundRobinReads, "how reads are spread over beeUrls, roundrobin or latency (default roundrobin)")
+	rootCmd.PersistentFlags().DurationVar(&beeTimeout, "beeTimeout", bee.DefaultRequestTimeout, "timeout of a single request to bee, 0 for no timeout (default 60s)")
+	rootCmd.PersistentFlags().IntVar(&beeRetries, "beeRetries", bee.DefaultMaxRetries, "retries of a bee request on network errors and 5xx responses (default 3)")
+	rootCmd.PersistentFlags().Int64Var(&cacheSize, "cacheSize", cache.DefaultSize/(1024*1024), "size budget of the in memory cache of blocks and feeds in MB (default 256)")
+	rootCmd.PersistentFlags().StringVar(&beeCacheDir, "beeCacheDir", "", "keep chunks and blobs downloaded from bee in this dir across restarts (default disabled)")
+	rootCmd.PersistentFlags().Int64Var(&beeCacheSize, "beeCacheSize", bee.DefaultDiskCacheSize/(1024*1024), "size budget of the bee disk cache in MB (default 512)")
+	rootCmd.PersistentFlags().DurationVar(&slowRequest, "slowRequest", metrics.DefaultSlowThreshold, "log block store requests slower than this as warnings, 0 to disable (default 5s)")
+	rootCmd.PersistentFlags().StringVar(&blockstoreDir, "blockstoreDir", "", "store chunks in this dir when using the local block store (default <dataDir>/blockstore)")
+	rootCmd.PersistentFlags().StringVar(&feedFormat, "feedFormat", "dfs", "format of the directory feeds created, dfs or bee to resolve them through bee's /feeds endpoint (default dfs)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -17,19 +17,28 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jmozah/intOS-dfs/pkg/api"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/metrics"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+const shutdownTimeout = 10 * time.Second
+
 var handler *api.Handler
 
 // startCmd represents the start command
@@ -57,13 +66,25 @@ can consume it.`,
 			fmt.Println("unknown verbosity level ", v)
 			return
 		}
-		hdlr, err := api.NewHandler(dataDir, beeHost, beePort, logger)
+		client, err := newBlockstoreClient(logger)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		defer closeBlockstoreClient(client, logger)
+		format, err := feed.ParseFormat(feedFormat)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		instrumented := metrics.New(client, slowRequest, logger)
+		hdlr, err := api.NewHandler(dataDir, instrumented, format, logger)
 		if err != nil {
 			logger.Error(err.Error())
 			return
 		}
 		handler = hdlr
-		startHttpService(logger)
+		startHttpService(logger, instrumented)
 	},
 }
 
@@ -71,7 +92,7 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 }
 
-func startHttpService(logger logging.Logger) {
+func startHttpService(logger logging.Logger, instrumented *metrics.Client) {
 	fs := http.FileServer(http.Dir("build/static"))
 	http.Handle("/static/", fs)
 	router := mux.NewRouter()
@@ -79,6 +100,9 @@ func startHttpService(logger logging.Logger) {
 	// Web page handlers
 	router.HandleFunc("/", handler.WebHandlers.IndexPageHandler)
 
+	// block store and cache metrics in the Prometheus text format
+	router.Handle("/metrics", instrumented.Handler()).Methods("GET")
+
 	apiVersion := "v0"
 	baseRouter := router.PathPrefix("/" + apiVersion).Subrouter()
 	baseRouter.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
@@ -110,6 +134,7 @@ func startHttpService(logger logging.Logger) {
 	userRouter.HandleFunc("/contact", handler.GetUserContactHandler).Methods("GET")
 	userRouter.HandleFunc("/share/inbox", handler.GetUserSharingInboxHandler).Methods("GET")
 	userRouter.HandleFunc("/share/outbox", handler.GetUserSharingOutboxHandler).Methods("GET")
+	userRouter.HandleFunc("/watch", handler.UserWatchHandler).Methods("GET")
 
 	// pod related handlers
 	podRouter := baseRouter.PathPrefix("/pod/").Subrouter()
@@ -119,6 +144,8 @@ func startHttpService(logger logging.Logger) {
 	podRouter.HandleFunc("/open", handler.PodOpenHandler).Methods("POST")
 	podRouter.HandleFunc("/close", handler.PodCloseHandler).Methods("POST")
 	podRouter.HandleFunc("/sync", handler.PodSyncHandler).Methods("POST")
+	podRouter.HandleFunc("/gc", handler.PodGCHandler).Methods("POST")
+	podRouter.HandleFunc("/migrate", handler.PodMigrateHandler).Methods("POST")
 	podRouter.HandleFunc("/delete", handler.PodDeleteHandler).Methods("DELETE")
 	podRouter.HandleFunc("/ls", handler.PodListHandler).Methods("GET")
 	podRouter.HandleFunc("/stat", handler.PodStatHandler).Methods("GET")
@@ -136,8 +163,20 @@ func startHttpService(logger logging.Logger) {
 	fileRouter := baseRouter.PathPrefix("/file/").Subrouter()
 	fileRouter.Use(handler.LoginMiddleware)
 	fileRouter.Use(handler.LogMiddleware)
-	fileRouter.HandleFunc("/download", handler.FileDownloadHandler).Methods("POST")
+	fileRouter.HandleFunc("/download", handler.FileDownloadHandler).Methods("GET", "POST")
 	fileRouter.HandleFunc("/upload", handler.FileUploadHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/open", handler.FileUploadOpenHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/blocks", handler.FileUploadBlocksHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/status", handler.FileUploadStatusHandler).Methods("GET")
+	fileRouter.HandleFunc("/upload/commit", handler.FileUploadCommitHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/abort", handler.FileUploadAbortHandler).Methods("DELETE")
+	fileRouter.HandleFunc("/append", handler.FileAppendHandler).Methods("POST")
+	fileRouter.HandleFunc("/write", handler.FileWriteHandler).Methods("POST")
+	fileRouter.HandleFunc("/truncate", handler.FileTruncateHandler).Methods("POST")
+	fileRouter.HandleFunc("/versions", handler.FileVersionsHandler).Methods("GET")
+	fileRouter.HandleFunc("/versions/prune", handler.FileVersionsPruneHandler).Methods("POST")
+	fileRouter.HandleFunc("/version/download", handler.FileVersionDownloadHandler).Methods("GET", "POST")
+	fileRouter.HandleFunc("/version/restore", handler.FileVersionRestoreHandler).Methods("POST")
 	fileRouter.HandleFunc("/share", handler.FileShareHandler).Methods("POST")
 	fileRouter.HandleFunc("/receive", handler.FileReceiveHandler).Methods("POST")
 	fileRouter.HandleFunc("/receiveinfo", handler.FileReceiveInfoHandler).Methods("POST")
@@ -151,7 +190,8 @@ func startHttpService(logger logging.Logger) {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:8000", "http://localhost:9090", "http://fairdrive.org"},
 		AllowCredentials: true,
-		AllowedHeaders:   []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Requested-With", "Access-Control-Request-Headers", "Access-Control-Request-Method"},
+		AllowedHeaders:   []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Requested-With", "Access-Control-Request-Headers", "Access-Control-Request-Method", "Range", "If-Range"},
+		ExposedHeaders:   []string{"Content-Range", "Accept-Ranges", "ETag"},
 		AllowedMethods:   []string{"GET", "POST", "DELETE"},
 		MaxAge:           3600,
 	})
@@ -159,10 +199,24 @@ func startHttpService(logger logging.Logger) {
 	// Insert the middleware
 	handler := c.Handler(router)
 
+	// stop serving on an interrupt, so that the block store is closed
+	server := &http.Server{Addr: ":" + httpPort, Handler: handler}
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := server.Shutdown(ctx)
+		if err != nil {
+			logger.Errorf("shutdown: %v", err)
+		}
+	}()
+
 	logger.Infof("listening on port: %v", httpPort)
-	err := http.ListenAndServe(":"+httpPort, handler)
-	if err != nil {
-		logger.Errorf("listenAndServe: %w", err)
+	err := server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		logger.Errorf("listenAndServe: %v", err)
 		return
 	}
 }
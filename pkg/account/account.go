@@ -222,6 +222,13 @@ func (a *Account) DeletePodAccount(accountId int) {
 	delete(a.podAccounts, accountId)
 }
 
+// HasPodAccount tells if the account of a pod is already created, so that it
+// can be used without the password of the user.
+func (a *Account) HasPodAccount(accountId int) bool {
+	_, ok := a.podAccounts[accountId]
+	return ok
+}
+
 func (a *Account) encryptMnemonic(mnemonic, passPhrase string) (string, error) {
 	// get the password and hash it to 256 bits
 	password := passPhrase
@@ -62,7 +62,7 @@ func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// list directory
-	entries, err := h.dfsAPI.ListDir(directory, sessionId)
+	entries, err := h.dfsAPI.ListDir(r.Context(), directory, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrPodNotOpened {
@@ -48,7 +48,7 @@ func (h *Handler) DirectoryMkdirHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// make directory
-	err = h.dfsAPI.Mkdir(dirToCreate, sessionId)
+	err = h.dfsAPI.Mkdir(r.Context(), dirToCreate, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrInvalidDirectory ||
@@ -48,7 +48,7 @@ func (h *Handler) DirectoryRmdirHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// remove directory
-	err = h.dfsAPI.RmDir(dir, sessionId)
+	err = h.dfsAPI.RmDir(r.Context(), dir, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrPodNotOpened {
@@ -48,7 +48,7 @@ func (h *Handler) FileDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// delete file
-	err = h.dfsAPI.DeleteFile(podFile, sessionId)
+	err = h.dfsAPI.DeleteFile(r.Context(), podFile, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen {
 			h.logger.Errorf("file delete: %v", err)
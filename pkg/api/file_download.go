@@ -18,8 +18,9 @@ package api
 
 import (
 	"fmt"
-	"io"
 	"net/http"
+	"path/filepath"
+	"time"
 
 	"github.com/jmozah/intOS-dfs/pkg/dfs"
 
@@ -50,7 +51,7 @@ func (h *Handler) FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// download file from bee
-	reader, reference, size, err := h.dfsAPI.DownloadFile(podFile, sessionId)
+	reader, reference, _, err := h.dfsAPI.DownloadFile(r.Context(), podFile, sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen {
 			h.logger.Errorf("download: %v", err)
@@ -62,13 +63,11 @@ func (h *Handler) FileDownloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("ETag", fmt.Sprintf("%q", reference))
-	w.Header().Set("Content-Length", size)
+	defer reader.Close()
 
-	_, err = io.Copy(w, reader)
-	if err != nil {
-		h.logger.Errorf("download: %v", err)
-		w.Header().Set("Content-Type", " application/json")
-		jsonhttp.InternalServerError(w, "stat dir: "+err.Error())
-	}
+	// the reader seeks, so the ranges asked for are served as partial content
+	// and only the blocks under them are read. the etag tells If-Range
+	// requests whether the file is still the same
+	w.Header().Set("ETag", fmt.Sprintf("%q", reference))
+	http.ServeContent(w, r, filepath.Base(podFile), time.Time{}, reader)
 }
@@ -0,0 +1,151 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/cookie"
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+)
+
+// FileAppendHandler adds the body of the request to the end of a file.
+func (h *Handler) FileAppendHandler(w http.ResponseWriter, r *http.Request) {
+	// the body is the data, so the arguments are only in the url
+	podFile := r.URL.Query().Get("file")
+	if podFile == "" {
+		h.logger.Errorf("file append: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file append: \"file\" argument missing")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file append")
+	if !ok {
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Errorf("file append: %v", err)
+		jsonhttp.BadRequest(w, "file append: "+err.Error())
+		return
+	}
+
+	ref, err := h.dfsAPI.AppendFile(r.Context(), podFile, data, sessionId)
+	h.fileModified(w, "file append", podFile, ref, err)
+}
+
+// FileWriteHandler writes the body of the request in to a file from
+// "offset" on. An offset past the end of the file is rejected with 400, as
+// the file is not grown with zeros.
+func (h *Handler) FileWriteHandler(w http.ResponseWriter, r *http.Request) {
+	// the body is the data, so the arguments are only in the url
+	podFile := r.URL.Query().Get("file")
+	if podFile == "" {
+		h.logger.Errorf("file write: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file write: \"file\" argument missing")
+		return
+	}
+	offset, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		h.logger.Errorf("file write: invalid \"offset\" argument")
+		jsonhttp.BadRequest(w, "file write: invalid \"offset\" argument")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file write")
+	if !ok {
+		return
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		h.logger.Errorf("file write: %v", err)
+		jsonhttp.BadRequest(w, "file write: "+err.Error())
+		return
+	}
+
+	ref, err := h.dfsAPI.WriteFile(r.Context(), podFile, data, offset, sessionId)
+	h.fileModified(w, "file write", podFile, ref, err)
+}
+
+// FileTruncateHandler cuts a file down to "size". A size past the end of the
+// file is rejected with 400, as the file is not grown with zeros.
+func (h *Handler) FileTruncateHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file truncate: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file truncate: \"file\" argument missing")
+		return
+	}
+	size, err := strconv.ParseUint(r.FormValue("size"), 10, 64)
+	if err != nil {
+		h.logger.Errorf("file truncate: invalid \"size\" argument")
+		jsonhttp.BadRequest(w, "file truncate: invalid \"size\" argument")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file truncate")
+	if !ok {
+		return
+	}
+
+	ref, err := h.dfsAPI.TruncateFile(r.Context(), podFile, size, sessionId)
+	h.fileModified(w, "file truncate", podFile, ref, err)
+}
+
+// requestSessionId returns the session id in the cookie of the request, or
+// responds with the error if there is none.
+func (h *Handler) requestSessionId(w http.ResponseWriter, r *http.Request, op string) (string, bool) {
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("%s: invalid cookie: %v", op, err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return "", false
+	}
+	if sessionId == "" {
+		h.logger.Errorf("%s: \"cookie-id\" parameter missing in cookie", op)
+		jsonhttp.BadRequest(w, op+": \"cookie-id\" parameter missing in cookie")
+		return "", false
+	}
+	return sessionId, true
+}
+
+// fileModified responds with the new reference of a modified file.
+func (h *Handler) fileModified(w http.ResponseWriter, op, podFile, ref string, err error) {
+	if err != nil {
+		h.logger.Errorf("%s: %v", op, err)
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn || err == file.ErrInvalidOffset {
+			jsonhttp.BadRequest(w, op+": "+err.Error())
+			return
+		}
+		if err == file.ErrVersionNotFound {
+			jsonhttp.NotFound(w, op+": "+err.Error())
+			return
+		}
+		jsonhttp.InternalServerError(w, op+": "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &Reference{
+		FileName:  podFile,
+		Reference: ref,
+	})
+}
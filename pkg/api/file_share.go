@@ -61,7 +61,7 @@ func (h *Handler) FileShareHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sharingRef, err := h.dfsAPI.ShareFile(podFile, destinationRef, sessionId)
+	sharingRef, err := h.dfsAPI.ShareFile(r.Context(), podFile, destinationRef, sessionId)
 	if err != nil {
 		h.logger.Errorf("file share: %v", err)
 		jsonhttp.InternalServerError(w, "file share: "+err.Error())
@@ -109,7 +109,7 @@ func (h *Handler) FileReceiveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filePath, fileRef, err := h.dfsAPI.ReceiveFile(sessionId, sharingRef, dir)
+	filePath, fileRef, err := h.dfsAPI.ReceiveFile(r.Context(), sessionId, sharingRef, dir)
 	if err != nil {
 		h.logger.Errorf("file receive: %v", err)
 		jsonhttp.InternalServerError(w, "file receive: "+err.Error())
@@ -151,7 +151,7 @@ func (h *Handler) FileReceiveInfoHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	receiveInfo, err := h.dfsAPI.ReceiveInfo(sessionId, sharingRef)
+	receiveInfo, err := h.dfsAPI.ReceiveInfo(r.Context(), sessionId, sharingRef)
 	if err != nil {
 		h.logger.Errorf("file receive info: %v", err)
 		jsonhttp.InternalServerError(w, "file receive info: "+err.Error())
@@ -0,0 +1,270 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/cookie"
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	p "github.com/jmozah/intOS-dfs/pkg/pod"
+)
+
+type UploadSessionResponse struct {
+	UploadId      string `json:"upload_id"`
+	FileName      string `json:"file_name"`
+	FileSize      uint64 `json:"file_size"`
+	BlockSize     uint32 `json:"block_size"`
+	NoOfBlocks    int    `json:"no_of_blocks"`
+	StoredBlocks  []int  `json:"stored_blocks"`
+	MissingBlocks []int  `json:"missing_blocks"`
+}
+
+type UploadBlocksResponse struct {
+	Stored int `json:"stored"`
+}
+
+func newUploadSessionResponse(session *file.UploadSession) *UploadSessionResponse {
+	return &UploadSessionResponse{
+		UploadId:      session.Id,
+		FileName:      session.FileName,
+		FileSize:      session.FileSize,
+		BlockSize:     session.BlockSize,
+		NoOfBlocks:    session.NoOfBlocks(),
+		StoredBlocks:  session.StoredBlocks(),
+		MissingBlocks: session.MissingBlocks(),
+	}
+}
+
+// FileUploadOpenHandler starts a resumable upload of a file. Its blocks are
+// then sent with FileUploadBlocksHandler, in any order, and the file is made
+// of them with FileUploadCommitHandler.
+func (h *Handler) FileUploadOpenHandler(w http.ResponseWriter, r *http.Request) {
+	podDir := r.FormValue("pod_dir")
+	fileName := r.FormValue("file_name")
+	fileSize := r.FormValue("file_size")
+	blockSize := r.FormValue("block_size")
+	compression := r.Header.Get(compressionHeader)
+	if podDir == "" {
+		h.logger.Errorf("file upload open: \"pod_dir\" argument missing")
+		jsonhttp.BadRequest(w, "file upload open: \"pod_dir\" argument missing")
+		return
+	}
+	if fileName == "" {
+		h.logger.Errorf("file upload open: \"file_name\" argument missing")
+		jsonhttp.BadRequest(w, "file upload open: \"file_name\" argument missing")
+		return
+	}
+	size, err := strconv.ParseInt(fileSize, 10, 64)
+	if err != nil {
+		h.logger.Errorf("file upload open: invalid \"file_size\" argument")
+		jsonhttp.BadRequest(w, "file upload open: invalid \"file_size\" argument")
+		return
+	}
+	if blockSize == "" {
+		h.logger.Errorf("file upload open: \"block_size\" argument missing")
+		jsonhttp.BadRequest(w, "file upload open: \"block_size\" argument missing")
+		return
+	}
+	if compression != "" {
+		if compression != "snappy" && compression != "gzip" {
+			h.logger.Errorf("file upload open: invalid value for \"compression\" header")
+			jsonhttp.BadRequest(w, "file upload open: invalid value for \"compression\" header")
+			return
+		}
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file upload open: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file upload open: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file upload open: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.OpenUpload(r.Context(), sessionId, fileName, size, podDir, blockSize, compression)
+	if err != nil {
+		h.uploadSessionError(w, "file upload open", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.Created(w, newUploadSessionResponse(session))
+}
+
+// FileUploadBlocksHandler stores the blocks of a resumable upload sent in the
+// body, one after the other from the block at "index" on.
+func (h *Handler) FileUploadBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	// the body is the blocks, so the arguments are only in the url
+	uploadId := r.URL.Query().Get("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("file upload blocks: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "file upload blocks: \"upload_id\" argument missing")
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		h.logger.Errorf("file upload blocks: invalid \"index\" argument")
+		jsonhttp.BadRequest(w, "file upload blocks: invalid \"index\" argument")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file upload blocks: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file upload blocks: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file upload blocks: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	stored, err := h.dfsAPI.UploadBlocks(r.Context(), sessionId, uploadId, index, r.Body)
+	if err != nil {
+		h.uploadSessionError(w, "file upload blocks", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &UploadBlocksResponse{Stored: stored})
+}
+
+// FileUploadStatusHandler tells which blocks of a resumable upload are stored
+// and which are still to be sent.
+func (h *Handler) FileUploadStatusHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("file upload status: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "file upload status: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file upload status: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file upload status: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file upload status: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.UploadStatus(sessionId, uploadId)
+	if err != nil {
+		h.uploadSessionError(w, "file upload status", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// FileUploadCommitHandler makes the file of a resumable upload whose blocks
+// are all stored, in the directory the upload was opened for.
+func (h *Handler) FileUploadCommitHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("file upload commit: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "file upload commit: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file upload commit: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file upload commit: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file upload commit: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	session, err := h.dfsAPI.UploadStatus(sessionId, uploadId)
+	if err != nil {
+		h.uploadSessionError(w, "file upload commit", err)
+		return
+	}
+	reference, err := h.dfsAPI.CommitUpload(r.Context(), sessionId, uploadId)
+	if err != nil {
+		h.uploadSessionError(w, "file upload commit", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &Reference{
+		FileName:  session.FileName,
+		Reference: reference,
+	})
+}
+
+// FileUploadAbortHandler ends a resumable upload without making its file.
+func (h *Handler) FileUploadAbortHandler(w http.ResponseWriter, r *http.Request) {
+	uploadId := r.FormValue("upload_id")
+	if uploadId == "" {
+		h.logger.Errorf("file upload abort: \"upload_id\" argument missing")
+		jsonhttp.BadRequest(w, "file upload abort: \"upload_id\" argument missing")
+		return
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("file upload abort: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("file upload abort: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "file upload abort: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	err = h.dfsAPI.AbortUpload(r.Context(), sessionId, uploadId)
+	if err != nil {
+		h.uploadSessionError(w, "file upload abort", err)
+		return
+	}
+	jsonhttp.OK(w, "upload aborted successfully")
+}
+
+// uploadSessionError responds with the error of a resumable upload request.
+func (h *Handler) uploadSessionError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	switch err {
+	case file.ErrUploadNotFound:
+		jsonhttp.NotFound(w, op+": "+err.Error())
+	case dfs.ErrPodNotOpen, dfs.ErrUserNotLoggedIn, p.ErrPodNotOpened,
+		file.ErrInvalidBlock, file.ErrUploadIncomplete:
+		jsonhttp.BadRequest(w, op+": "+err.Error())
+	default:
+		jsonhttp.InternalServerError(w, op+": "+err.Error())
+	}
+}
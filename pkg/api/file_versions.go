@@ -0,0 +1,192 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+type FileVersionsResponse struct {
+	Versions []FileVersion `json:"versions"`
+}
+
+type FileVersion struct {
+	Reference        string `json:"reference"`
+	FileSize         uint64 `json:"file_size"`
+	ModificationTime int64  `json:"modification_time"`
+}
+
+// FileVersionsHandler lists the earlier versions of a file, the latest first.
+func (h *Handler) FileVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file versions: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file versions: \"file\" argument missing")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file versions")
+	if !ok {
+		return
+	}
+
+	versions, err := h.dfsAPI.ListFileVersions(podFile, sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn {
+			h.logger.Errorf("file versions: %v", err)
+			jsonhttp.BadRequest(w, "file versions: "+err.Error())
+			return
+		}
+		h.logger.Errorf("file versions: %v", err)
+		jsonhttp.InternalServerError(w, "file versions: "+err.Error())
+		return
+	}
+
+	resp := &FileVersionsResponse{
+		Versions: make([]FileVersion, 0, len(versions)),
+	}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, FileVersion{
+			Reference:        utils.NewReference(version.Reference).String(),
+			FileSize:         version.FileSize,
+			ModificationTime: version.ModificationTime,
+		})
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, resp)
+}
+
+// FileVersionDownloadHandler serves an earlier version of a file, the way
+// FileDownloadHandler serves the file.
+func (h *Handler) FileVersionDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file version download: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file version download: \"file\" argument missing")
+		return
+	}
+	version := r.FormValue("version")
+	if version == "" {
+		h.logger.Errorf("file version download: \"version\" argument missing")
+		jsonhttp.BadRequest(w, "file version download: \"version\" argument missing")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file version download")
+	if !ok {
+		return
+	}
+
+	reader, reference, _, err := h.dfsAPI.DownloadFileVersion(r.Context(), podFile, version, sessionId)
+	if err != nil {
+		h.logger.Errorf("file version download: %v", err)
+		switch err {
+		case dfs.ErrPodNotOpen, dfs.ErrUserNotLoggedIn:
+			jsonhttp.BadRequest(w, "file version download: "+err.Error())
+		case file.ErrVersionNotFound:
+			jsonhttp.NotFound(w, "file version download: "+err.Error())
+		default:
+			jsonhttp.InternalServerError(w, "file version download: "+err.Error())
+		}
+		return
+	}
+
+	defer reader.Close()
+	w.Header().Set("ETag", fmt.Sprintf("%q", reference))
+	http.ServeContent(w, r, filepath.Base(podFile), time.Time{}, reader)
+}
+
+// FileVersionRestoreHandler makes an earlier version of a file its current
+// one.
+func (h *Handler) FileVersionRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file version restore: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file version restore: \"file\" argument missing")
+		return
+	}
+	version := r.FormValue("version")
+	if version == "" {
+		h.logger.Errorf("file version restore: \"version\" argument missing")
+		jsonhttp.BadRequest(w, "file version restore: \"version\" argument missing")
+		return
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file version restore")
+	if !ok {
+		return
+	}
+
+	ref, err := h.dfsAPI.RestoreFileVersion(r.Context(), podFile, version, sessionId)
+	h.fileModified(w, "file version restore", podFile, ref, err)
+}
+
+// FileVersionsPruneHandler drops the versions of a file older than "max_age"
+// and the ones past the latest "max_count" of them.
+func (h *Handler) FileVersionsPruneHandler(w http.ResponseWriter, r *http.Request) {
+	podFile := r.FormValue("file")
+	if podFile == "" {
+		h.logger.Errorf("file versions prune: \"file\" argument missing")
+		jsonhttp.BadRequest(w, "file versions prune: \"file\" argument missing")
+		return
+	}
+	maxAgeValue := r.FormValue("max_age")
+	maxCountValue := r.FormValue("max_count")
+	if maxAgeValue == "" && maxCountValue == "" {
+		h.logger.Errorf("file versions prune: \"max_age\" or \"max_count\" argument missing")
+		jsonhttp.BadRequest(w, "file versions prune: \"max_age\" or \"max_count\" argument missing")
+		return
+	}
+	var maxAge time.Duration
+	if maxAgeValue != "" {
+		var err error
+		maxAge, err = time.ParseDuration(maxAgeValue)
+		if err != nil || maxAge <= 0 {
+			h.logger.Errorf("file versions prune: invalid \"max_age\" argument")
+			jsonhttp.BadRequest(w, "file versions prune: invalid \"max_age\" argument")
+			return
+		}
+	}
+	maxCount := -1
+	if maxCountValue != "" {
+		var err error
+		maxCount, err = strconv.Atoi(maxCountValue)
+		if err != nil || maxCount < 0 {
+			h.logger.Errorf("file versions prune: invalid \"max_count\" argument")
+			jsonhttp.BadRequest(w, "file versions prune: invalid \"max_count\" argument")
+			return
+		}
+	}
+
+	sessionId, ok := h.requestSessionId(w, r, "file versions prune")
+	if !ok {
+		return
+	}
+
+	ref, err := h.dfsAPI.PruneFileVersions(r.Context(), podFile, maxAge, maxCount, sessionId)
+	h.fileModified(w, "file versions prune", podFile, ref, err)
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package api
 
 import (
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 	"github.com/jmozah/intOS-dfs/pkg/web"
 )
@@ -28,8 +30,8 @@ type Handler struct {
 	logger      logging.Logger
 }
 
-func NewHandler(dataDir, beeHost, beePort string, logger logging.Logger) (*Handler, error) {
-	api, err := dfs.NewDfsAPI(dataDir, beeHost, beePort, logger)
+func NewHandler(dataDir string, client blockstore.Client, feedFormat feed.Format, logger logging.Logger) (*Handler, error) {
+	api, err := dfs.NewDfsAPI(dataDir, client, feedFormat, logger)
 	if err != nil {
 		return nil, dfs.ErrBeeClient
 	}
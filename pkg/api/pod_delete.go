@@ -47,7 +47,7 @@ func (h *Handler) PodDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// delete pod
-	err = h.dfsAPI.DeletePod(podName, sessionId)
+	err = h.dfsAPI.DeletePod(r.Context(), podName, sessionId)
 	if err != nil {
 		if err == dfs.ErrUserNotLoggedIn {
 			h.logger.Errorf("delete pod: %v", err)
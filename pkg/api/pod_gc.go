@@ -0,0 +1,58 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/cookie"
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	p "github.com/jmozah/intOS-dfs/pkg/pod"
+)
+
+func (h *Handler) PodGCHandler(w http.ResponseWriter, r *http.Request) {
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod gc: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod gc: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod gc: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// unpin what is not reachable from the pod
+	stats, err := h.dfsAPI.CollectPodGarbage(r.Context(), sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened {
+			h.logger.Errorf("pod gc: %v", err)
+			jsonhttp.BadRequest(w, "pod gc: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod gc: %v", err)
+		jsonhttp.InternalServerError(w, "pod gc: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, stats)
+}
@@ -45,7 +45,7 @@ func (h *Handler) PodListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// fetch pods and list them
-	pods, err := h.dfsAPI.ListPods(sessionId)
+	pods, err := h.dfsAPI.ListPods(r.Context(), sessionId)
 	if err != nil {
 		if err == dfs.ErrUserNotLoggedIn ||
 			err == pod.ErrPodNotOpened {
@@ -0,0 +1,61 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"net/http"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/cookie"
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	p "github.com/jmozah/intOS-dfs/pkg/pod"
+)
+
+type PodMigrateResponse struct {
+	Directories int `json:"directories"`
+}
+
+func (h *Handler) PodMigrateHandler(w http.ResponseWriter, r *http.Request) {
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("pod migrate: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("pod migrate: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "pod migrate: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	// rewrite the directory feeds of the pod in the format of bee
+	dirs, err := h.dfsAPI.MigratePodToBeeFeeds(r.Context(), sessionId)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened {
+			h.logger.Errorf("pod migrate: %v", err)
+			jsonhttp.BadRequest(w, "pod migrate: "+err.Error())
+			return
+		}
+		h.logger.Errorf("pod migrate: %v", err)
+		jsonhttp.InternalServerError(w, "pod migrate: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodMigrateResponse{Directories: dirs})
+}
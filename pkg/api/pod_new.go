@@ -58,7 +58,7 @@ func (h *Handler) PodCreateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create pod
-	_, err = h.dfsAPI.CreatePod(pod, password, sessionId)
+	_, err = h.dfsAPI.CreatePod(r.Context(), pod, password, sessionId)
 	if err != nil {
 		if err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrInvalidPodName ||
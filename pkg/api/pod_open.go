@@ -58,7 +58,7 @@ func (h *Handler) PodOpenHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// open pod
-	_, err = h.dfsAPI.OpenPod(pod, password, sessionId)
+	_, err = h.dfsAPI.OpenPod(r.Context(), pod, password, sessionId)
 	if err != nil {
 		if err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrInvalidPodName {
@@ -41,7 +41,7 @@ func (h *Handler) PodSyncHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// fetch pods and list them
-	err = h.dfsAPI.SyncPod(sessionId)
+	err = h.dfsAPI.SyncPod(r.Context(), sessionId)
 	if err != nil {
 		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
 			err == p.ErrInvalidPodName ||
@@ -105,7 +105,7 @@ func (h *Handler) SaveUserAvatarHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// save avatar with .avatar extension
-	err = h.dfsAPI.SaveAvatar(sessionId, data)
+	err = h.dfsAPI.SaveAvatar(r.Context(), sessionId, data)
 	if err != nil {
 		h.logger.Errorf("user avatar: %v", err)
 		jsonhttp.BadRequest(w, "user avatar: "+err.Error())
@@ -128,7 +128,7 @@ func (h *Handler) GetUserAvatarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := h.dfsAPI.GetAvatar(sessionId)
+	data, err := h.dfsAPI.GetAvatar(r.Context(), sessionId)
 	if err != nil {
 		h.logger.Errorf("user get avatar: %v", err)
 		jsonhttp.InternalServerError(w, "user get avatar: "+err.Error())
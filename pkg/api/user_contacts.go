@@ -78,7 +78,7 @@ func (h *Handler) SaveUserContactHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	err = h.dfsAPI.SaveContact(phone, mobile, address, sessionId)
+	err = h.dfsAPI.SaveContact(r.Context(), phone, mobile, address, sessionId)
 	if err != nil {
 		h.logger.Errorf("user save contact: %v", err)
 		jsonhttp.InternalServerError(w, "user save contact: "+err.Error())
@@ -101,7 +101,7 @@ func (h *Handler) GetUserContactHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	contacts, err := h.dfsAPI.GetContact(sessionId)
+	contacts, err := h.dfsAPI.GetContact(r.Context(), sessionId)
 	if err != nil {
 		h.logger.Errorf("user get contact: %v", err)
 		jsonhttp.InternalServerError(w, "user get contact: "+err.Error())
@@ -47,7 +47,7 @@ func (h *Handler) UserDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// delete user
-	err = h.dfsAPI.DeleteUser(password, sessionId, w)
+	err = h.dfsAPI.DeleteUser(r.Context(), password, sessionId, w)
 	if err != nil {
 		if err == u.ErrInvalidUserName ||
 			err == u.ErrInvalidPassword ||
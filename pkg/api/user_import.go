@@ -48,7 +48,7 @@ func (h *Handler) ImportUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if mnemonic != "" && address == "" {
-		address, _, err := h.dfsAPI.CreateUser(user, password, mnemonic, w, "")
+		address, _, err := h.dfsAPI.CreateUser(r.Context(), user, password, mnemonic, w, "")
 		if err != nil {
 			if err == u.ErrUserAlreadyPresent {
 				h.logger.Errorf("user import: %v", err)
@@ -68,7 +68,7 @@ func (h *Handler) ImportUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if address != "" {
-		err := h.dfsAPI.ImportUserUsingAddress(user, password, address, w, "")
+		err := h.dfsAPI.ImportUserUsingAddress(r.Context(), user, password, address, w, "")
 		if err != nil {
 			h.logger.Errorf("user import: %v", err)
 			jsonhttp.InternalServerError(w, "user import: "+err.Error())
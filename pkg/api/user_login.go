@@ -39,7 +39,7 @@ func (h *Handler) UserLoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// login user
-	err := h.dfsAPI.LoginUser(user, password, w, "")
+	err := h.dfsAPI.LoginUser(r.Context(), user, password, w, "")
 	if err != nil {
 		if err == u.ErrUserAlreadyLoggedIn ||
 			err == u.ErrInvalidUserName ||
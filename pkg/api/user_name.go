@@ -43,7 +43,7 @@ func (h *Handler) SaveUserNameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.dfsAPI.SaveName(firstName, lastName, middleName, surname, sessionId)
+	err = h.dfsAPI.SaveName(r.Context(), firstName, lastName, middleName, surname, sessionId)
 	if err != nil {
 		h.logger.Errorf("user save name: %v", err)
 		jsonhttp.InternalServerError(w, "user save name: "+err.Error())
@@ -66,7 +66,7 @@ func (h *Handler) GetUserNameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name, err := h.dfsAPI.GetName(sessionId)
+	name, err := h.dfsAPI.GetName(r.Context(), sessionId)
 	if err != nil {
 		h.logger.Errorf("user get name: %v", err)
 		jsonhttp.InternalServerError(w, "user get name: "+err.Error())
@@ -38,7 +38,7 @@ func (h *Handler) GetUserSharingInboxHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	sharingInbox, err := h.dfsAPI.GetUserSharingInbox(sessionId)
+	sharingInbox, err := h.dfsAPI.GetUserSharingInbox(r.Context(), sessionId)
 	if err != nil {
 		h.logger.Errorf("user get share inbox: %v", err)
 		jsonhttp.InternalServerError(w, "user get share inbox: "+err.Error())
@@ -63,7 +63,7 @@ func (h *Handler) GetUserSharingOutboxHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	sharingOutbox, err := h.dfsAPI.GetUserSharingOutbox(sessionId)
+	sharingOutbox, err := h.dfsAPI.GetUserSharingOutbox(r.Context(), sessionId)
 	if err != nil {
 		h.logger.Errorf("user get share outbox: %v", err)
 		jsonhttp.InternalServerError(w, "user get share outbox: "+err.Error())
@@ -45,7 +45,7 @@ func (h *Handler) UserSignupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// create user
-	address, createdMnemonic, err := h.dfsAPI.CreateUser(user, password, mnemonic, w, "")
+	address, createdMnemonic, err := h.dfsAPI.CreateUser(r.Context(), user, password, mnemonic, w, "")
 	if err != nil {
 		if err == u.ErrUserAlreadyPresent {
 			h.logger.Errorf("user signup: %v", err)
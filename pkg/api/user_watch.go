@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"resenje.org/jsonhttp"
+
+	"github.com/jmozah/intOS-dfs/pkg/cookie"
+	"github.com/jmozah/intOS-dfs/pkg/dfs"
+	p "github.com/jmozah/intOS-dfs/pkg/pod"
+)
+
+// UserWatchHandler streams as Server-Sent Events the changes of the sharing
+// inbox of the user, of the list of pods and of the directories of the open
+// pod given in "dir" arguments, the root of the pod if none are given. An
+// "interval" argument sets how many seconds there are between two polls of
+// the feeds. Each event is named after its kind and its data is the JSON of
+// the dfs.WatchEvent.
+func (h *Handler) UserWatchHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		h.logger.Errorf("user watch: %v", err)
+		jsonhttp.BadRequest(w, "user watch: "+err.Error())
+		return
+	}
+	dirs := r.Form["dir"]
+	var interval time.Duration
+	if v := r.FormValue("interval"); v != "" {
+		seconds, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || seconds == 0 {
+			h.logger.Errorf("user watch: invalid \"interval\" argument %q", v)
+			jsonhttp.BadRequest(w, "user watch: invalid \"interval\" argument")
+			return
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	// get values from cookie
+	sessionId, err := cookie.GetSessionIdFromCookie(r)
+	if err != nil {
+		h.logger.Errorf("user watch: invalid cookie: %v", err)
+		jsonhttp.BadRequest(w, ErrInvalidCookie)
+		return
+	}
+	if sessionId == "" {
+		h.logger.Errorf("user watch: \"cookie-id\" parameter missing in cookie")
+		jsonhttp.BadRequest(w, "user watch: \"cookie-id\" parameter missing in cookie")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.logger.Errorf("user watch: streaming not supported")
+		jsonhttp.InternalServerError(w, "user watch: streaming not supported")
+		return
+	}
+
+	// watch until the client goes away
+	events, err := h.dfsAPI.Watch(r.Context(), sessionId, dirs, interval)
+	if err != nil {
+		if err == dfs.ErrPodNotOpen || err == dfs.ErrUserNotLoggedIn ||
+			err == p.ErrPodNotOpened {
+			h.logger.Errorf("user watch: %v", err)
+			jsonhttp.BadRequest(w, "user watch: "+err.Error())
+			return
+		}
+		h.logger.Errorf("user watch: %v", err)
+		jsonhttp.InternalServerError(w, "user watch: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			h.logger.Errorf("user watch: %v", err)
+			return
+		}
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Kind, data)
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
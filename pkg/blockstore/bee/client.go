@@ -19,49 +19,81 @@ package bee
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"hash"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"time"
 
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 
+	encstore "github.com/ethersphere/bee/pkg/encryption/store"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	bmtlegacy "github.com/ethersphere/bmt/legacy"
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/sha3"
 
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 )
 
 const (
 	MaxIdleConnections     int = 20
-	RequestTimeout         int = 1
-	chunkCacheSize             = 1024
-	uploadBlockCacheSize       = 100
-	downloadBlockCacheSize     = 100
 	ChunkUploadDownloadUrl     = "/chunks/"
 	BytesUploadDownloadUrl     = "/bytes"
 	pinChunksUrl               = "/pinning/chunks/"
-	pinBlobsUrl                = "/pinning/chunks/" // need to change this when bee supports it
 	SwarmPinHeader             = "Swarm-Pin"
 	SwarmEncryptHeader         = "Swarm-Encrypt"
+
+	ChunkCacheSegment        = "bee-chunk"
+	UploadBlobCacheSegment   = "bee-upload-blob"
+	DownloadBlobCacheSegment = "bee-download-blob"
+
+	DefaultRequestTimeout  = 60 * time.Second
+	DefaultMaxRetries      = 3
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultMaxRetryBackoff = 5 * time.Second
 )
 
+// Options controls how long a single request to bee may take and how
+// transient failures (network errors and 5xx responses) are retried.
+type Options struct {
+	RequestTimeout  time.Duration // timeout of every attempt, 0 means no timeout
+	MaxRetries      int           // retries after the first attempt
+	RetryBackoff    time.Duration // wait before the first retry, doubled on every retry
+	MaxRetryBackoff time.Duration // upper bound of the wait between retries
+	Cache           *cache.Cache  // in memory cache, a new one of cache.DefaultSize bytes if nil
+	DiskCache       *DiskCache    // optional persistent cache under the in memory cache
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RequestTimeout:  DefaultRequestTimeout,
+		MaxRetries:      DefaultMaxRetries,
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxRetryBackoff: DefaultMaxRetryBackoff,
+	}
+}
+
 type BeeClient struct {
 	host               string
 	port               string
 	url                string
 	client             *http.Client
+	opts               Options
 	hasher             *bmtlegacy.Hasher
-	chunkCache         *lru.Cache
-	uploadBlockCache   *lru.Cache
-	downloadBlockCache *lru.Cache
+	cache              *cache.Cache
+	chunkCache         *cache.Segment
+	uploadBlockCache   *cache.Segment
+	downloadBlockCache *cache.Segment
 	logger             logging.Logger
 }
 
@@ -69,57 +101,87 @@ func hashFunc() hash.Hash {
 	return sha3.NewLegacyKeccak256()
 }
 
+// statusError is a request which bee answered with a status other than the
+// one expected.
+type statusError struct {
+	msg  string
+	code int
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
 type bytesPostResponse struct {
 	Reference swarm.Address `json:"reference"`
 }
 
 func NewBeeClient(host, port string, logger logging.Logger) *BeeClient {
-	p := bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize)
-	cache, err := lru.New(chunkCacheSize)
+	return NewBeeClientWithOptions(host, port, DefaultOptions(), logger)
+}
+
+func NewBeeClientWithOptions(host, port string, opts Options, logger logging.Logger) *BeeClient {
+	return newBeeClient(host, port, fmt.Sprintf("http://"+host+":"+port), opts, logger)
+}
+
+// NewBeeClientFromURL creates a client for the bee node at the given url, ex: http://127.0.0.1:1633
+func NewBeeClientFromURL(rawURL string, opts Options, logger logging.Logger) (*BeeClient, error) {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		logger.Warningf("could not initialise chunkCache. system will be slow")
+		return nil, err
 	}
-	uploadBlockCache, err := lru.New(uploadBlockCacheSize)
-	if err != nil {
-		logger.Warningf("could not initialise blockCache. system will be slow")
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid bee url %s", rawURL)
 	}
-	downloadBlockCache, err := lru.New(downloadBlockCacheSize)
-	if err != nil {
-		logger.Warningf("could not initialise blockCache. system will be slow")
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid bee url %s", rawURL)
+	}
+	return newBeeClient(u.Hostname(), u.Port(), u.Scheme+"://"+u.Host, opts, logger), nil
+}
+
+func newBeeClient(host, port, beeUrl string, opts Options, logger logging.Logger) *BeeClient {
+	p := bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize)
+	c := opts.Cache
+	if c == nil {
+		c = cache.New(cache.DefaultSize)
 	}
 
 	return &BeeClient{
 		host:               host,
 		port:               port,
-		url:                fmt.Sprintf("http://" + host + ":" + port),
+		url:                beeUrl,
 		client:             createHTTPClient(),
+		opts:               opts,
 		hasher:             bmtlegacy.New(p),
-		chunkCache:         cache,
-		uploadBlockCache:   uploadBlockCache,
-		downloadBlockCache: downloadBlockCache,
+		cache:              c,
+		chunkCache:         c.Segment(ChunkCacheSegment),
+		uploadBlockCache:   c.Segment(UploadBlobCacheSegment),
+		downloadBlockCache: c.Segment(DownloadBlobCacheSegment),
 		logger:             logger,
 	}
 }
 
-func (s *BeeClient) CheckConnection() bool {
-	req, err := http.NewRequest(http.MethodGet, s.url, nil)
-	if err != nil {
-		return false
-	}
+// Cache returns the in memory cache of the client, so that it can be shared
+// with the layers above.
+func (s *BeeClient) Cache() *cache.Cache {
+	return s.cache
+}
 
-	response, err := s.client.Do(req)
+// URL returns the address of the bee node this client talks to.
+func (s *BeeClient) URL() string {
+	return s.url
+}
+
+func (s *BeeClient) CheckConnection() bool {
+	respCode, data, err := s.doOnce(context.Background(), http.MethodGet, s.url, nil, nil)
 	if err != nil {
 		return false
 	}
 
-	if response.StatusCode != http.StatusOK {
+	if respCode != http.StatusOK {
 		return false
 	}
 
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return false
-	}
 	if string(data) != "Ethereum Swarm Bee\n" {
 		return false
 	}
@@ -127,30 +189,29 @@ func (s *BeeClient) CheckConnection() bool {
 }
 
 // upload a chunk in bee
-func (s *BeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error) {
+func (s *BeeClient) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
 	to := time.Now()
 	path := filepath.Join(ChunkUploadDownloadUrl, ch.Address().String())
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodPost, fullUrl, bytes.NewBuffer(ch.Data()))
-	if err != nil {
-		return nil, err
-	}
-
+	headers := make(map[string]string)
 	if pin {
-		req.Header.Set(SwarmPinHeader, "true")
+		headers[SwarmPinHeader] = "true"
 	}
 
-	response, err := s.client.Do(req)
+	respCode, _, err := s.do(ctx, http.MethodPost, fullUrl, ch.Data(), headers)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("error uploading data")
+	if respCode != http.StatusOK {
+		return nil, &statusError{msg: "error uploading data", code: respCode}
 	}
 
-	if s.inChunkCache(ch.Address().String()) {
-		s.addToChunkCache(ch.Address().String(), ch.Data())
+	if s.chunkCache.Contains(ch.Address().String()) {
+		s.chunkCache.AddBytes(ch.Address().String(), ch.Data())
+	}
+	if s.opts.DiskCache != nil {
+		s.opts.DiskCache.PutChunk(ch.Address().Bytes(), ch.Data())
 	}
 	fields := logrus.Fields{
 		"reference": ch.Address().String(),
@@ -164,34 +225,34 @@ func (s *BeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err e
 func (s *BeeClient) DownloadChunk(ctx context.Context, address []byte) (data []byte, err error) {
 	to := time.Now()
 	addrString := swarm.NewAddress(address).String()
-	if s.inChunkCache(addrString) {
-		return s.getFromChunkCache(swarm.NewAddress(address).String()), nil
+	if data, ok := s.chunkCache.GetBytes(addrString); ok {
+		return data, nil
+	}
+	if s.opts.DiskCache != nil {
+		if data, ok := s.opts.DiskCache.GetChunk(address); ok {
+			s.chunkCache.AddBytes(addrString, data)
+			return data, nil
+		}
 	}
 
 	path := filepath.Join(ChunkUploadDownloadUrl, addrString)
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodGet, fullUrl, bytes.NewBuffer(data))
+	respCode, data, err := s.do(ctx, http.MethodGet, fullUrl, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req = req.WithContext(ctx)
-
-	response, err := s.client.Do(req)
-	if err != nil {
-		return nil, err
+	if respCode == http.StatusNotFound {
+		return nil, blockstore.ErrNotFound
 	}
-
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("error downloading data")
+	if respCode != http.StatusOK {
+		return nil, &statusError{msg: fmt.Sprintf("error downloading data: status %d", respCode), code: respCode}
 	}
 
-	data, err = ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, errors.New("error downloading data")
+	s.chunkCache.AddBytes(addrString, data)
+	if s.opts.DiskCache != nil {
+		s.opts.DiskCache.PutChunk(address, data)
 	}
-
-	s.addToChunkCache(addrString, data)
 	fields := logrus.Fields{
 		"reference": addrString,
 		"duration":  time.Since(to).String(),
@@ -201,40 +262,40 @@ func (s *BeeClient) DownloadChunk(ctx context.Context, address []byte) (data []b
 }
 
 // upload a chunk in bee
-func (s *BeeClient) UploadBlob(data []byte, pin bool, encrypt bool) (address []byte, err error) {
+func (s *BeeClient) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error) {
 	to := time.Now()
 
 	// return the ref if this data is already in swarm
-	if s.inBlockCache(s.uploadBlockCache, string(data)) {
-		return s.getFromBlockCache(s.uploadBlockCache, string(data)), nil
+	dataHash := sha256.Sum256(data)
+	uploadKey := string(dataHash[:])
+	if ref, ok := s.uploadBlockCache.GetBytes(uploadKey); ok {
+		// every upload asking for a pin holds one on every chunk of the blob,
+		// so that unpinning one of them does not release the others
+		if !pin {
+			return ref, nil
+		}
+		if s.PinBlob(ctx, utils.NewReference(ref)) == nil {
+			return ref, nil
+		}
 	}
 
 	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl)
-	req, err := http.NewRequest(http.MethodPost, fullUrl, bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-
+	headers := make(map[string]string)
 	if pin {
-		req.Header.Set(SwarmPinHeader, "true")
+		headers[SwarmPinHeader] = "true"
 	}
 
 	if encrypt {
-		req.Header.Set(SwarmEncryptHeader, "true")
+		headers[SwarmEncryptHeader] = "true"
 	}
 
-	response, err := s.client.Do(req)
+	respCode, respData, err := s.do(ctx, http.MethodPost, fullUrl, data, headers)
 	if err != nil {
 		return nil, err
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.New("error uploading blob")
-	}
-
-	respData, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, errors.New("error uploading blob")
+	if respCode != http.StatusOK {
+		return nil, &statusError{msg: "error uploading blob", code: respCode}
 	}
 
 	var resp bytesPostResponse
@@ -249,147 +310,407 @@ func (s *BeeClient) UploadBlob(data []byte, pin bool, encrypt bool) (address []b
 	}
 	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload blob: ")
 
-	// add the data and ref if itis not in cache
-	if !s.inBlockCache(s.uploadBlockCache, string(data)) {
-		s.addToBlockCache(s.uploadBlockCache, string(data), resp.Reference.Bytes())
+	// remember the ref against the hash of the data, not the data itself
+	s.uploadBlockCache.AddBytes(uploadKey, resp.Reference.Bytes())
+	if s.opts.DiskCache != nil {
+		s.opts.DiskCache.PutBlob(resp.Reference.Bytes(), data)
 	}
 	return resp.Reference.Bytes(), nil
 }
 
-func (s *BeeClient) DownloadBlob(address []byte) ([]byte, int, error) {
+func (s *BeeClient) DownloadBlob(ctx context.Context, address []byte) ([]byte, int, error) {
 	to := time.Now()
 
 	// return the data if this address is already in cache
 	addrString := swarm.NewAddress(address).String()
-	if s.inBlockCache(s.downloadBlockCache, addrString) {
-		return s.getFromBlockCache(s.downloadBlockCache, addrString), 200, nil
+	if data, ok := s.downloadBlockCache.GetBytes(addrString); ok {
+		return data, http.StatusOK, nil
+	}
+	if s.opts.DiskCache != nil {
+		if data, ok := s.opts.DiskCache.GetBlob(address); ok {
+			s.downloadBlockCache.AddBytes(addrString, data)
+			return data, http.StatusOK, nil
+		}
 	}
 
 	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl + "/" + addrString)
-	req, err := http.NewRequest(http.MethodGet, fullUrl, nil)
+	respCode, respData, err := s.do(ctx, http.MethodGet, fullUrl, nil, nil)
 	if err != nil {
 		return nil, http.StatusNotFound, err
 	}
 
-	response, err := s.client.Do(req)
+	if respCode != http.StatusOK {
+		return nil, respCode, &statusError{msg: "error downloading blob ", code: respCode}
+	}
+
+	fields := logrus.Fields{
+		"reference": addrString,
+		"size":      len(respData),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download blob: ")
+
+	// add the data to the cache
+	s.downloadBlockCache.AddBytes(addrString, respData)
+	if s.opts.DiskCache != nil {
+		s.opts.DiskCache.PutBlob(address, respData)
+	}
+	return respData, respCode, nil
+}
+
+// UploadBlobStream uploads the blob read from r without keeping it in memory.
+// The upload is retried only if r is an io.Seeker.
+func (s *BeeClient) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error) {
+	to := time.Now()
+	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl)
+	headers := make(map[string]string)
+	if pin {
+		headers[SwarmPinHeader] = "true"
+	}
+
+	if encrypt {
+		headers[SwarmEncryptHeader] = "true"
+	}
+
+	response, err := s.doStream(ctx, http.MethodPost, fullUrl, r, headers)
 	if err != nil {
-		return nil, http.StatusNotFound, err
+		return nil, err
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, response.StatusCode, errors.New("error downloading blob ")
+		return nil, &statusError{msg: "error uploading blob", code: response.StatusCode}
 	}
 
-	respData, err := ioutil.ReadAll(response.Body)
+	var resp bytesPostResponse
+	err = json.NewDecoder(response.Body).Decode(&resp)
 	if err != nil {
-		return nil, response.StatusCode, errors.New("error downloading blob")
+		return nil, fmt.Errorf("error unmarshalling response")
 	}
 	fields := logrus.Fields{
-		"reference": addrString,
-		"size":      len(respData),
+		"reference": resp.Reference.String(),
 		"duration":  time.Since(to).String(),
 	}
-	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download blob: ")
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload blob stream: ")
+	return resp.Reference.Bytes(), nil
+}
 
-	// add the data and ref if it is not in cache
-	if !s.inBlockCache(s.downloadBlockCache, addrString) {
-		s.addToBlockCache(s.downloadBlockCache, addrString, respData)
+// DownloadBlobStream returns the blob as it is read from bee. The caller must
+// close the returned reader.
+func (s *BeeClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	// return the data if this address is already in cache
+	addrString := swarm.NewAddress(address).String()
+	if data, ok := s.downloadBlockCache.GetBytes(addrString); ok {
+		return ioutil.NopCloser(bytes.NewReader(data)), http.StatusOK, nil
+	}
+	if s.opts.DiskCache != nil {
+		if data, ok := s.opts.DiskCache.GetBlob(address); ok {
+			return ioutil.NopCloser(bytes.NewReader(data)), http.StatusOK, nil
+		}
 	}
-	return respData, response.StatusCode, nil
-}
 
-func (s *BeeClient) UnpinChunk(ref utils.Reference) error {
-	path := filepath.Join(pinChunksUrl, ref.String())
-	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodDelete, fullUrl, nil)
+	fullUrl := fmt.Sprintf(s.url + BytesUploadDownloadUrl + "/" + addrString)
+	response, err := s.doStream(ctx, http.MethodGet, fullUrl, nil, nil)
 	if err != nil {
-		return err
+		return nil, http.StatusNotFound, err
 	}
 
-	response, err := s.client.Do(req)
+	if response.StatusCode != http.StatusOK {
+		_, _ = io.Copy(ioutil.Discard, response.Body)
+		_ = response.Body.Close()
+		return nil, response.StatusCode, &statusError{msg: "error downloading blob ", code: response.StatusCode}
+	}
+
+	fields := logrus.Fields{
+		"reference": addrString,
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download blob stream: ")
+	return response.Body, response.StatusCode, nil
+}
+
+// PinBlob pins every chunk of a blob which is already in bee. Bee pins only
+// single chunks, so the chunk tree of the blob is walked to find them. If
+// pinning any of them fails, the ones already pinned are unpinned again.
+func (s *BeeClient) PinBlob(ctx context.Context, ref utils.Reference) error {
+	addresses, err := s.blobChunks(ctx, ref.Bytes())
 	if err != nil {
 		return err
 	}
+	for i, address := range addresses {
+		err = s.pinChunk(ctx, address)
+		if err != nil {
+			for _, pinned := range addresses[:i] {
+				_ = s.UnpinChunk(ctx, utils.NewReference(pinned))
+			}
+			return err
+		}
+	}
+	return nil
+}
 
-	if response.StatusCode != http.StatusOK {
+func (s *BeeClient) pinChunk(ctx context.Context, address []byte) error {
+	path := filepath.Join(pinChunksUrl, swarm.NewAddress(address).String())
+	fullUrl := fmt.Sprintf(s.url + path)
+	respCode, _, err := s.do(ctx, http.MethodPost, fullUrl, nil, nil)
+	if err != nil {
 		return err
 	}
+	if respCode != http.StatusOK && respCode != http.StatusCreated {
+		return &statusError{msg: "error pinning blob", code: respCode}
+	}
 	return nil
 }
 
-func (s *BeeClient) UnpinBlob(ref utils.Reference) error {
-	path := filepath.Join(pinBlobsUrl, ref.String())
+func (s *BeeClient) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	path := filepath.Join(pinChunksUrl, ref.String())
 	fullUrl := fmt.Sprintf(s.url + path)
-	req, err := http.NewRequest(http.MethodDelete, fullUrl, nil)
+	respCode, _, err := s.do(ctx, http.MethodDelete, fullUrl, nil, nil)
 	if err != nil {
 		return err
 	}
+	if respCode != http.StatusOK {
+		return &statusError{msg: fmt.Sprintf("error unpinning chunk: status %d", respCode), code: respCode}
+	}
+	return nil
+}
 
-	response, err := s.client.Do(req)
+// UnpinBlob unpins every chunk of a blob. All of them are tried, and the first
+// error is returned.
+func (s *BeeClient) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	addresses, err := s.blobChunks(ctx, ref.Bytes())
 	if err != nil {
 		return err
 	}
+	var firstErr error
+	for _, address := range addresses {
+		err = s.UnpinChunk(ctx, utils.NewReference(address))
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
 
-	if response.StatusCode != http.StatusOK {
-		return err
+// blobChunks returns the addresses of all the chunks in the tree of a blob,
+// whose reference may be encrypted. Like the joiner of bee, it learns the span
+// of every chunk from the chunk itself, so all of them are downloaded.
+func (s *BeeClient) blobChunks(ctx context.Context, ref []byte) ([][]byte, error) {
+	getter := encstore.New(chunkGetter{client: s})
+	var addresses [][]byte
+	var walk func(ref []byte) error
+	walk = func(ref []byte) error {
+		ch, err := getter.Get(ctx, storage.ModeGetRequest, swarm.NewAddress(ref))
+		if err != nil {
+			return err
+		}
+		addresses = append(addresses, ref[:swarm.HashSize])
+		data := ch.Data()
+		if len(data) < swarm.SpanSize {
+			return blockstore.ErrInvalidChunk
+		}
+		span := binary.LittleEndian.Uint64(data[:swarm.SpanSize])
+		payload := data[swarm.SpanSize:]
+		if span <= uint64(len(payload)) {
+			return nil
+		}
+
+		// intermediate chunk, the children have references as long as its own
+		if len(payload)%len(ref) != 0 {
+			return blockstore.ErrInvalidChunk
+		}
+		for cursor := 0; cursor < len(payload); cursor += len(ref) {
+			err = walk(payload[cursor : cursor+len(ref)])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	return nil
+	if err := walk(ref); err != nil {
+		return nil, err
+	}
+	return addresses, nil
 }
 
-// createHTTPClient for connection re-use
-func createHTTPClient() *http.Client {
-	client := &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost: MaxIdleConnections,
-		},
-		//Timeout: time.Duration(RequestTimeout) * time.Second,
+// chunkGetter lets the decrypting store of bee fetch chunks through the client.
+type chunkGetter struct {
+	client *BeeClient
+}
+
+func (g chunkGetter) Get(ctx context.Context, _ storage.ModeGet, addr swarm.Address) (swarm.Chunk, error) {
+	data, err := g.client.DownloadChunk(ctx, addr.Bytes())
+	if err != nil {
+		return nil, err
 	}
-	return client
+	return swarm.NewChunk(addr, data), nil
 }
 
-func (s *BeeClient) addToChunkCache(key string, value []byte) {
-	if s.chunkCache != nil {
-		s.chunkCache.Add(key, value)
+// do sends a request to bee and returns the response code and body. Network
+// errors and 5xx responses are retried with exponential backoff till the
+// retries are exhausted or the context is done.
+func (s *BeeClient) do(ctx context.Context, method, url string, body []byte, headers map[string]string) (int, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	response, err := s.doStream(ctx, method, url, bodyReader, headers)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, nil, err
 	}
+	return response.StatusCode, data, nil
 }
 
-func (s *BeeClient) inChunkCache(key string) bool {
-	if s.chunkCache != nil {
-		return s.chunkCache.Contains(key)
+// doOnce makes a single attempt of the request, bounded by the request timeout.
+func (s *BeeClient) doOnce(ctx context.Context, method, url string, body []byte, headers map[string]string) (int, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	response, err := s.send(ctx, method, url, bodyReader, headers)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, nil, err
 	}
-	return false
+	return response.StatusCode, data, nil
 }
 
-func (s *BeeClient) getFromChunkCache(key string) []byte {
-	if s.chunkCache != nil {
-		value, ok := s.chunkCache.Get(key)
+// doStream is like do, but returns the response with its body unread. The
+// caller must close the body. A body which is not an io.Seeker can be sent
+// only once, so such requests are not retried.
+func (s *BeeClient) doStream(ctx context.Context, method, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	maxRetries := s.opts.MaxRetries
+	var seeker io.Seeker
+	var start int64
+	if body != nil {
+		var ok bool
+		seeker, ok = body.(io.Seeker)
 		if ok {
-			return value.([]byte)
+			var err error
+			start, err = seeker.Seek(0, io.SeekCurrent)
+			if err != nil {
+				seeker = nil
+			}
+		}
+		if seeker == nil {
+			maxRetries = 0
+		}
+	}
+
+	backoff := s.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && seeker != nil {
+			_, err := seeker.Seek(start, io.SeekStart)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		response, err := s.send(ctx, method, url, body, headers)
+		respCode := 0
+		if err == nil {
+			respCode = response.StatusCode
+		}
+		if attempt >= maxRetries || !isRetryable(ctx, respCode, err) {
+			return response, err
+		}
+		if response != nil {
+			_, _ = io.Copy(ioutil.Discard, response.Body)
+			_ = response.Body.Close()
+		}
+
+		fields := logrus.Fields{
+			"method":  method,
+			"url":     url,
+			"attempt": attempt + 1,
+			"code":    respCode,
+			"error":   err,
+			"backoff": backoff.String(),
+		}
+		s.logger.WithFields(fields).Log(logrus.DebugLevel, "retrying request: ")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if s.opts.MaxRetryBackoff > 0 && backoff > s.opts.MaxRetryBackoff {
+			backoff = s.opts.MaxRetryBackoff
 		}
-		return nil
 	}
-	return nil
 }
 
-func (s *BeeClient) addToBlockCache(cache *lru.Cache, key string, value []byte) {
-	if cache != nil {
-		cache.Add(key, value)
+// send makes a single attempt of the request. The request timeout covers
+// reading the response body too, it is released when the body is closed.
+func (s *BeeClient) send(ctx context.Context, method, url string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if s.opts.RequestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.opts.RequestTimeout)
+	}
+
+	// don't let the http client close a body which belongs to the caller
+	if _, ok := body.(io.Closer); ok {
+		body = struct{ io.Reader }{body}
 	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	response, err := s.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+	return response, nil
 }
 
-func (s *BeeClient) inBlockCache(cache *lru.Cache, key string) bool {
-	if cache != nil {
-		return cache.Contains(key)
+// cancelOnClose releases the request context when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// isRetryable reports if a failed attempt is worth retrying. Nothing is
+// retried once the caller's context is done.
+func isRetryable(ctx context.Context, respCode int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
 	}
-	return false
+	return respCode >= http.StatusInternalServerError
 }
-func (s *BeeClient) getFromBlockCache(cache *lru.Cache, key string) []byte {
-	if cache != nil {
-		value, ok := cache.Get(key)
-		if ok {
-			return value.([]byte)
-		}
-		return nil
+
+// createHTTPClient for connection re-use
+func createHTTPClient() *http.Client {
+	client := &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: MaxIdleConnections,
+		},
 	}
-	return nil
+	return client
 }
@@ -0,0 +1,635 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bee_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/file/pipeline"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func newTestBeeClient(t *testing.T) (*bee.BeeClient, *mock.MockBeeClient) {
+	t.Helper()
+	store := mock.NewMockBeeClient()
+	return newTestBeeClientWithHandler(t, mock.NewBeeServer(store), bee.DefaultOptions()), store
+}
+
+func newTestBeeClientWithHandler(t *testing.T, handler http.Handler, opts bee.Options) *bee.BeeClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.New(ioutil.Discard, 0)
+	return bee.NewBeeClientWithOptions(u.Hostname(), u.Port(), opts, logger)
+}
+
+func TestBeeClient(t *testing.T) {
+	client, store := newTestBeeClient(t)
+
+	t.Run("check-connection", func(t *testing.T) {
+		if !client.CheckConnection() {
+			t.Fatalf("could not connect to the bee emulator")
+		}
+	})
+
+	t.Run("upload-download-blob", func(t *testing.T) {
+		for _, size := range []int{1, swarm.ChunkSize, swarm.ChunkSize*swarm.Branches + 1} {
+			data := make([]byte, size)
+			_, err := rand.Read(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr, err := client.UploadBlob(context.Background(), data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mockAddr, err := store.UploadBlob(context.Background(), data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(addr, mockAddr) {
+				t.Fatalf("address mismatch for size %d", size)
+			}
+			rcvdData, respCode, err := client.DownloadBlob(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respCode != http.StatusOK {
+				t.Fatalf("invalid response code %d", respCode)
+			}
+			if !bytes.Equal(data, rcvdData) {
+				t.Fatalf("data mismatch for size %d", size)
+			}
+		}
+	})
+
+	t.Run("download-missing-blob", func(t *testing.T) {
+		_, respCode, err := client.DownloadBlob(context.Background(), make([]byte, swarm.HashSize))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if respCode != http.StatusNotFound {
+			t.Fatalf("invalid response code %d", respCode)
+		}
+	})
+
+	t.Run("upload-download-blob-stream", func(t *testing.T) {
+		data := make([]byte, swarm.ChunkSize*3+17)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mockAddr, err := store.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, mockAddr) {
+			t.Fatalf("address mismatch")
+		}
+		body, respCode, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+		if respCode != http.StatusOK {
+			t.Fatalf("invalid response code %d", respCode)
+		}
+		rcvdData, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("data mismatch")
+		}
+	})
+
+	t.Run("download-missing-blob-stream", func(t *testing.T) {
+		body, respCode, err := client.DownloadBlobStream(context.Background(), make([]byte, swarm.HashSize))
+		if err == nil {
+			body.Close()
+			t.Fatalf("expected error")
+		}
+		if respCode != http.StatusNotFound {
+			t.Fatalf("invalid response code %d", respCode)
+		}
+	})
+
+	t.Run("upload-download-chunk", func(t *testing.T) {
+		addr := make([]byte, swarm.HashSize)
+		_, err := rand.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := []byte("chunk data")
+		_, err = client.UploadChunk(context.Background(), swarm.NewChunk(swarm.NewAddress(addr), data), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcvdData, err := client.DownloadChunk(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("chunk data mismatch")
+		}
+	})
+
+	t.Run("download-missing-chunk", func(t *testing.T) {
+		_, err := client.DownloadChunk(context.Background(), make([]byte, swarm.HashSize))
+		if !errors.Is(err, blockstore.ErrNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+	})
+
+	t.Run("pin-unpin", func(t *testing.T) {
+		data := []byte("pinned blob")
+		addr, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !store.IsBlobPinned(addr) {
+			t.Fatalf("blob not pinned")
+		}
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if store.PinCount(addr) != 0 {
+			t.Fatalf("blob still pinned")
+		}
+
+		// bee refusing the unpin is an error, not an unpin
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err == nil {
+			t.Fatalf("unpinned a blob which is not pinned")
+		}
+		err = client.UnpinChunk(context.Background(), utils.NewReference(addr))
+		if err == nil {
+			t.Fatalf("unpinned a chunk which is not pinned")
+		}
+	})
+
+	t.Run("pin-blob", func(t *testing.T) {
+		addr, err := client.UploadBlob(context.Background(), []byte("blob pinned later"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.PinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if store.PinCount(addr) != 1 {
+			t.Fatalf("blob not pinned")
+		}
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		missing := make([]byte, 32)
+		err = client.PinBlob(context.Background(), utils.NewReference(missing))
+		if err == nil {
+			t.Fatalf("pinned a missing blob")
+		}
+	})
+}
+
+// TestBeeClientPinBlobTree checks that every chunk of a blob is pinned, as bee
+// pins only single chunks.
+func TestBeeClientPinBlobTree(t *testing.T) {
+	// two levels of intermediate chunks
+	data := make([]byte, swarm.ChunkSize*swarm.Branches+100)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encrypt := range []bool{false, true} {
+		client, store := newTestBeeClient(t)
+		var chunks int
+		storer := chunkStorer{put: func(ch swarm.Chunk) error {
+			chunks++
+			_, err := store.UploadChunk(context.Background(), ch, false)
+			return err
+		}}
+		pipe := pipeline.NewPipelineBuilder(context.Background(), storer, storage.ModePutUpload, encrypt)
+		root, err := pipeline.FeedPipeline(context.Background(), pipe, bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref := utils.NewReference(root.Bytes())
+
+		err = client.PinBlob(context.Background(), ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pinned := len(store.PinnedChunks()); pinned != chunks {
+			t.Fatalf("encrypt %v: pinned %d of %d chunks", encrypt, pinned, chunks)
+		}
+
+		err = client.UnpinBlob(context.Background(), ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pinned := len(store.PinnedChunks()); pinned != 0 {
+			t.Fatalf("encrypt %v: %d chunks still pinned", encrypt, pinned)
+		}
+	}
+}
+
+// chunkStorer stores the chunks bee splits a blob into, the way bee does on
+// an upload.
+type chunkStorer struct {
+	storage.Storer
+	put func(ch swarm.Chunk) error
+}
+
+func (s chunkStorer) Put(_ context.Context, _ storage.ModePut, chs ...swarm.Chunk) ([]bool, error) {
+	for _, ch := range chs {
+		if err := s.put(ch); err != nil {
+			return nil, err
+		}
+	}
+	return make([]bool, len(chs)), nil
+}
+
+func TestMockBeeClient(t *testing.T) {
+	t.Run("deterministic-address", func(t *testing.T) {
+		data := []byte("same data same address")
+		addr1, err := mock.NewMockBeeClient().UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr2, err := mock.NewMockBeeClient().UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr1, addr2) {
+			t.Fatalf("same data uploaded to different addresses")
+		}
+	})
+
+	t.Run("pin-count", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		data := make([]byte, swarm.ChunkSize*2)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := store.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if store.PinCount(addr) != 2 {
+			t.Fatalf("expected pin count 2, got %d", store.PinCount(addr))
+		}
+		err = store.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !store.IsBlobPinned(addr) {
+			t.Fatalf("blob should still be pinned once")
+		}
+		err = store.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(store.PinnedChunks()) != 0 {
+			t.Fatalf("chunks still pinned")
+		}
+		err = store.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != mock.ErrNotPinned {
+			t.Fatalf("expected not pinned error, got %v", err)
+		}
+	})
+}
+
+func TestBeeClientRetry(t *testing.T) {
+	opts := bee.Options{
+		RequestTimeout:  time.Second,
+		MaxRetries:      3,
+		RetryBackoff:    time.Millisecond,
+		MaxRetryBackoff: 5 * time.Millisecond,
+	}
+
+	t.Run("retry-on-server-error", func(t *testing.T) {
+		var requests int32
+		server := mock.NewBeeServer(mock.NewMockBeeClient())
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			server.ServeHTTP(w, r)
+		}), opts)
+
+		data := []byte("uploaded after retries")
+		addr, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if atomic.LoadInt32(&requests) != 3 {
+			t.Fatalf("expected 3 requests, got %d", requests)
+		}
+		rcvdData, _, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("data mismatch")
+		}
+	})
+
+	t.Run("retries-exhausted", func(t *testing.T) {
+		var requests int32
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}), opts)
+
+		_, err := client.UploadBlob(context.Background(), []byte("never uploaded"), false, false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if atomic.LoadInt32(&requests) != int32(opts.MaxRetries+1) {
+			t.Fatalf("expected %d requests, got %d", opts.MaxRetries+1, requests)
+		}
+	})
+
+	t.Run("no-retry-on-client-error", func(t *testing.T) {
+		var requests int32
+		server := mock.NewBeeServer(mock.NewMockBeeClient())
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			server.ServeHTTP(w, r)
+		}), opts)
+
+		_, err := client.DownloadChunk(context.Background(), make([]byte, swarm.HashSize))
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if atomic.LoadInt32(&requests) != 1 {
+			t.Fatalf("not found should not be retried, got %d requests", requests)
+		}
+	})
+
+	t.Run("request-timeout", func(t *testing.T) {
+		var requests int32
+		done := make(chan struct{})
+		defer close(done)
+		timeoutOpts := opts
+		timeoutOpts.RequestTimeout = 20 * time.Millisecond
+		timeoutOpts.MaxRetries = 1
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			select {
+			case <-done:
+			case <-r.Context().Done():
+			}
+		}), timeoutOpts)
+
+		_, _, err := client.DownloadBlob(context.Background(), make([]byte, swarm.HashSize))
+		if err == nil {
+			t.Fatalf("expected timeout error")
+		}
+		if atomic.LoadInt32(&requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", requests)
+		}
+	})
+
+	t.Run("retry-seekable-stream", func(t *testing.T) {
+		var requests int32
+		server := mock.NewBeeServer(mock.NewMockBeeClient())
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				_, _ = ioutil.ReadAll(r.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			server.ServeHTTP(w, r)
+		}), opts)
+
+		data := []byte("stream uploaded after a retry")
+		addr, err := client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if atomic.LoadInt32(&requests) != 2 {
+			t.Fatalf("expected 2 requests, got %d", requests)
+		}
+		rcvdData, _, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("data mismatch")
+		}
+	})
+
+	t.Run("no-retry-unseekable-stream", func(t *testing.T) {
+		var requests int32
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			_, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}), opts)
+
+		r := struct{ io.Reader }{bytes.NewReader([]byte("read only once"))}
+		_, err := client.UploadBlobStream(context.Background(), r, false, false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if atomic.LoadInt32(&requests) != 1 {
+			t.Fatalf("unseekable stream should not be retried, got %d requests", requests)
+		}
+	})
+
+	t.Run("cancelled-context", func(t *testing.T) {
+		var requests int32
+		client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		}), opts)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.UploadChunk(ctx, swarm.NewChunk(swarm.NewAddress(make([]byte, swarm.HashSize)), []byte("data")), false)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if atomic.LoadInt32(&requests) != 0 {
+			t.Fatalf("cancelled request reached the server")
+		}
+	})
+}
+
+func TestBeeClientCache(t *testing.T) {
+	var uploads int32
+	store := mock.NewMockBeeClient()
+	server := mock.NewBeeServer(store)
+	opts := bee.DefaultOptions()
+	opts.Cache = cache.New(10 * swarm.ChunkSize)
+	client := newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&uploads, 1)
+		}
+		server.ServeHTTP(w, r)
+	}), opts)
+
+	t.Run("upload-dedup", func(t *testing.T) {
+		data := make([]byte, swarm.ChunkSize*4)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr1, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr2, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr1, addr2) {
+			t.Fatalf("address mismatch")
+		}
+		if atomic.LoadInt32(&uploads) != 1 {
+			t.Fatalf("same data uploaded %d times", uploads)
+		}
+		stats := client.Cache().Stats()[bee.UploadBlobCacheSegment]
+		if stats.Hits != 1 {
+			t.Fatalf("expected 1 hit, got %+v", stats)
+		}
+		// the key is a hash, not the data
+		if stats.Size >= int64(len(data)) {
+			t.Fatalf("upload cache holds the data, size %d", stats.Size)
+		}
+	})
+
+	t.Run("upload-dedup-pins", func(t *testing.T) {
+		// four leaves under a root, all of which a pin must hold
+		data := make([]byte, swarm.ChunkSize*4)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr1, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr2, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr1, addr2) {
+			t.Fatalf("address mismatch")
+		}
+		pinCounts := func() map[string]int {
+			counts := make(map[string]int)
+			for _, chunk := range store.PinnedChunks() {
+				addr, err := swarm.ParseHexAddress(chunk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				counts[chunk] = store.PinCount(addr.Bytes())
+			}
+			return counts
+		}
+		counts := pinCounts()
+		if len(counts) != 5 {
+			t.Fatalf("expected 5 pinned chunks, got %d", len(counts))
+		}
+		for chunk, count := range counts {
+			if count != 2 {
+				t.Fatalf("expected 2 pins of chunk %s, got %d", chunk, count)
+			}
+		}
+
+		// unpinning one of the uploads keeps the other one pinned
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts = pinCounts()
+		if len(counts) != 5 {
+			t.Fatalf("expected 5 pinned chunks, got %d", len(counts))
+		}
+		for chunk, count := range counts {
+			if count != 1 {
+				t.Fatalf("expected 1 pin of chunk %s, got %d", chunk, count)
+			}
+		}
+		if !store.IsBlobPinned(addr1) {
+			t.Fatalf("blob not pinned")
+		}
+	})
+
+	t.Run("download-budget", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			data := make([]byte, swarm.ChunkSize*3)
+			_, err := rand.Read(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr, err := client.UploadBlob(context.Background(), data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = client.DownloadBlob(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		c := client.Cache()
+		if c.Size() > c.MaxSize() {
+			t.Fatalf("cache over budget %d", c.Size())
+		}
+		if c.Stats()[bee.DownloadBlobCacheSegment].Evictions == 0 {
+			t.Fatalf("expected evictions")
+		}
+	})
+}
@@ -0,0 +1,321 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bee
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	DefaultDiskCacheSize = 512 * 1024 * 1024
+
+	diskCacheChunksDir = "chunks"
+	diskCacheBlobsDir  = "blobs"
+	diskCacheDirPerm   = 0700
+	diskCacheFilePerm  = 0600
+	digestSize         = 32
+)
+
+// DiskCache is a persistent cache tier for chunks and blobs downloaded from
+// bee. It sits under the in memory LRUs of BeeClient so that data survives a
+// restart. The total size of the cached data is kept under a budget by
+// evicting the least recently used entries.
+//
+// Every entry is stored along with the keccak256 digest of its address and
+// data, which is checked whenever it is read back, so a corrupted or
+// misplaced file is dropped instead of being served. Chunks are also checked
+// against their BMT address both when they are cached and read back. Blobs
+// are cached under the address bee gave for them, as bee hashes a blob when
+// it is uploaded and checks every chunk when it is downloaded, so they are
+// not hashed again.
+// Single owner chunks, which hold the updates of feeds, are not content
+// addressed and can change, so they are never cached.
+//
+// A DiskCache is safe for concurrent use and can be shared by many clients.
+type DiskCache struct {
+	dir      string
+	maxSize  int64
+	size     int64
+	entries  map[string]*list.Element
+	lru      *list.List // front is the most recently used
+	mu       sync.Mutex
+	splitter *blockstore.Splitter
+	logger   logging.Logger
+}
+
+type diskCacheEntry struct {
+	path string
+	size int64
+}
+
+// NewDiskCache opens the cache in dir, picking up the entries left by an
+// earlier run. maxSize is the budget in bytes for the files in the cache.
+func NewDiskCache(dir string, maxSize int64, logger logging.Logger) (*DiskCache, error) {
+	for _, d := range []string{diskCacheChunksDir, diskCacheBlobsDir} {
+		err := os.MkdirAll(filepath.Join(dir, d), diskCacheDirPerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := &DiskCache{
+		dir:      dir,
+		maxSize:  maxSize,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		splitter: blockstore.NewSplitter(),
+		logger:   logger,
+	}
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// GetChunk returns the chunk data cached for the address.
+func (c *DiskCache) GetChunk(address []byte) ([]byte, bool) {
+	return c.get(c.path(diskCacheChunksDir, address), address, c.isContentAddressed)
+}
+
+// PutChunk caches the data of a chunk. Chunks which don't hash to their
+// address, like single owner chunks, are not cached.
+func (c *DiskCache) PutChunk(address, data []byte) {
+	if !c.isContentAddressed(address, data) {
+		return
+	}
+	c.put(c.path(diskCacheChunksDir, address), address, data)
+}
+
+// GetBlob returns the blob cached for the address.
+func (c *DiskCache) GetBlob(address []byte) ([]byte, bool) {
+	return c.get(c.path(diskCacheBlobsDir, address), address, nil)
+}
+
+// PutBlob caches a blob under the address bee gave for it.
+func (c *DiskCache) PutBlob(address, data []byte) {
+	c.put(c.path(diskCacheBlobsDir, address), address, data)
+}
+
+// Size returns the number of bytes used by the cache.
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns the number of entries in the cache.
+func (c *DiskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// get reads an entry, checking it with verify too if it is not nil. The file
+// is read without holding the lock, so that reads don't wait on each other.
+func (c *DiskCache) get(path string, address []byte, verify func(address, data []byte) bool) ([]byte, bool) {
+	c.mu.Lock()
+	_, ok := c.entries[path]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		c.logger.Warningf("disk cache: %v", err)
+		c.drop(path)
+		return nil, false
+	}
+	if len(contents) < digestSize || !bytes.Equal(contents[:digestSize], entryDigest(address, contents[digestSize:])) ||
+		(verify != nil && !verify(address, contents[digestSize:])) {
+		c.logger.Warningf("disk cache: dropping corrupted entry %s", hex.EncodeToString(address))
+		c.drop(path)
+		return nil, false
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[path]; ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	// the modification time keeps the order of use across restarts
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return contents[digestSize:], true
+}
+
+// put writes an entry. The file is written without holding the lock and
+// replaces the earlier one of the address, if any, at once.
+func (c *DiskCache) put(path string, address, data []byte) {
+	size := int64(digestSize + len(data))
+	if size > c.maxSize {
+		return
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), diskCacheDirPerm)
+	if err == nil {
+		contents := make([]byte, 0, size)
+		contents = append(contents, entryDigest(address, data)...)
+		contents = append(contents, data...)
+		err = utils.WriteFileAtomic(path, contents, diskCacheFilePerm)
+	}
+	if err != nil {
+		c.logger.Warningf("disk cache: %v", err)
+		c.drop(path)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, path)
+		c.size -= elem.Value.(*diskCacheEntry).size
+	}
+	c.entries[path] = c.lru.PushFront(&diskCacheEntry{path: path, size: size})
+	c.size += size
+	c.evict()
+}
+
+// drop removes the entry of path, if any, and its file.
+func (c *DiskCache) drop(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[path]; ok {
+		c.remove(elem)
+		return
+	}
+	_ = os.Remove(path)
+}
+
+// isContentAddressed tells if data is a span prefixed chunk whose BMT hash
+// is address.
+func (c *DiskCache) isContentAddressed(address, data []byte) bool {
+	if len(data) < swarm.SpanSize || len(data) > swarm.SpanSize+swarm.ChunkSize {
+		return false
+	}
+	span := binary.LittleEndian.Uint64(data[:swarm.SpanSize])
+	addr, err := c.splitter.ChunkAddress(span, data[swarm.SpanSize:])
+	return err == nil && bytes.Equal(addr.Bytes(), address)
+}
+
+// evict removes the least recently used entries till the cache is within
+// its budget. The caller must hold the lock.
+func (c *DiskCache) evict() {
+	for c.size > c.maxSize {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		c.remove(elem)
+	}
+}
+
+// remove deletes an entry and its file. The caller must hold the lock.
+func (c *DiskCache) remove(elem *list.Element) {
+	entry := elem.Value.(*diskCacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.path)
+	c.size -= entry.size
+	err := os.Remove(entry.path)
+	if err != nil && !os.IsNotExist(err) {
+		c.logger.Warningf("disk cache: %v", err)
+	}
+}
+
+// load indexes the files already in the cache, oldest first.
+func (c *DiskCache) load() error {
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	for _, d := range []string{diskCacheChunksDir, diskCacheBlobsDir} {
+		err := filepath.Walk(filepath.Join(c.dir, d), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			// left behind by a crash in the middle of a write
+			if strings.HasPrefix(info.Name(), utils.TmpFilePrefix) {
+				return os.Remove(path)
+			}
+			files = append(files, file{path: path, size: info.Size(), modTime: info.ModTime()})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.entries[f.path] = c.lru.PushFront(&diskCacheEntry{path: f.path, size: f.size})
+		c.size += f.size
+	}
+	fields := logrus.Fields{
+		"dir":     c.dir,
+		"entries": len(files),
+		"size":    c.size,
+	}
+	c.logger.WithFields(fields).Log(logrus.DebugLevel, "disk cache loaded: ")
+	return nil
+}
+
+func (c *DiskCache) path(kind string, address []byte) string {
+	addrString := hex.EncodeToString(address)
+	if len(addrString) < 2 {
+		return filepath.Join(c.dir, kind, addrString)
+	}
+	return filepath.Join(c.dir, kind, addrString[:2], addrString)
+}
+
+func entryDigest(address, data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	_, _ = h.Write(address)
+	_, _ = h.Write(data)
+	return h.Sum(nil)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bee_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func newTestBlob(t *testing.T, size int) ([]byte, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _, err := blockstore.NewSplitter().Split(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root.Bytes(), data
+}
+
+// newTestChunk returns the address and data of a content addressed chunk.
+func newTestChunk(t *testing.T, payload []byte) ([]byte, []byte) {
+	t.Helper()
+	_, chunks, err := blockstore.NewSplitter().Split(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunks[0].Address().Bytes(), chunks[0].Data()
+}
+
+func newTestCacheDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "bee-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func TestDiskCache(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	t.Run("persist-across-restart", func(t *testing.T) {
+		dir := newTestCacheDir(t)
+		cache, err := bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, data := newTestBlob(t, swarm.ChunkSize+1)
+		cache.PutBlob(addr, data)
+		chunkAddr, chunkData := newTestChunk(t, []byte("chunk data"))
+		cache.PutChunk(chunkAddr, chunkData)
+
+		cache, err = bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cache.Len() != 2 {
+			t.Fatalf("expected 2 entries, got %d", cache.Len())
+		}
+		rcvdData, ok := cache.GetBlob(addr)
+		if !ok || !bytes.Equal(data, rcvdData) {
+			t.Fatalf("blob not found after restart")
+		}
+		rcvdChunk, ok := cache.GetChunk(chunkAddr)
+		if !ok || !bytes.Equal(chunkData, rcvdChunk) {
+			t.Fatalf("chunk not found after restart")
+		}
+	})
+
+	t.Run("evict-least-recently-used", func(t *testing.T) {
+		cache, err := bee.NewDiskCache(newTestCacheDir(t), 3*(swarm.ChunkSize+64), logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var addrs [][]byte
+		for i := 0; i < 3; i++ {
+			addr, data := newTestBlob(t, swarm.ChunkSize)
+			cache.PutBlob(addr, data)
+			addrs = append(addrs, addr)
+		}
+		// use the first one, so the second is the oldest
+		if _, ok := cache.GetBlob(addrs[0]); !ok {
+			t.Fatalf("blob not cached")
+		}
+		addr, data := newTestBlob(t, swarm.ChunkSize)
+		cache.PutBlob(addr, data)
+
+		if cache.Size() > 3*(swarm.ChunkSize+64) {
+			t.Fatalf("cache over budget %d", cache.Size())
+		}
+		if _, ok := cache.GetBlob(addrs[1]); ok {
+			t.Fatalf("least recently used blob not evicted")
+		}
+		for _, a := range [][]byte{addrs[0], addrs[2], addr} {
+			if _, ok := cache.GetBlob(a); !ok {
+				t.Fatalf("recently used blob evicted")
+			}
+		}
+	})
+
+	t.Run("corrupted-entry", func(t *testing.T) {
+		dir := newTestCacheDir(t)
+		cache, err := bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, data := newTestBlob(t, 100)
+		cache.PutBlob(addr, data)
+
+		var path string
+		err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				path = p
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[len(contents)-1] ^= 0xff
+		err = ioutil.WriteFile(path, contents, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := cache.GetBlob(addr); ok {
+			t.Fatalf("corrupted blob served")
+		}
+		if cache.Len() != 0 {
+			t.Fatalf("corrupted entry not dropped")
+		}
+	})
+
+	t.Run("reject-chunk-not-of-its-address", func(t *testing.T) {
+		cache, err := bee.NewDiskCache(newTestCacheDir(t), bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// like a single owner chunk, whose address is not the hash of its data
+		addr := make([]byte, swarm.HashSize)
+		_, err = rand.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, data := newTestChunk(t, []byte("feed update"))
+		cache.PutChunk(addr, data)
+		if _, ok := cache.GetChunk(addr); ok || cache.Len() != 0 {
+			t.Fatalf("chunk cached under an address which is not its hash")
+		}
+	})
+
+	t.Run("bee-client-reads-from-disk", func(t *testing.T) {
+		dir := newTestCacheDir(t)
+		cache, err := bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := bee.DefaultOptions()
+		opts.DiskCache = cache
+		store := mock.NewMockBeeClient()
+		client := newTestBeeClientWithHandler(t, mock.NewBeeServer(store), opts)
+
+		data := []byte("metadata blob")
+		addr, err := store.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a new client, as after a restart, with bee not having the data
+		cache, err = bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.DiskCache = cache
+		client = newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}), opts)
+		rcvdData, respCode, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if respCode != http.StatusOK || !bytes.Equal(data, rcvdData) {
+			t.Fatalf("blob not served from the disk cache")
+		}
+	})
+
+	t.Run("bee-client-caches-uploads", func(t *testing.T) {
+		dir := newTestCacheDir(t)
+		cache, err := bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := bee.DefaultOptions()
+		opts.DiskCache = cache
+		client := newTestBeeClientWithHandler(t, mock.NewBeeServer(mock.NewMockBeeClient()), opts)
+
+		addr, data := newTestBlob(t, swarm.ChunkSize*3)
+		uploaded, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, uploaded) {
+			t.Fatalf("address mismatch")
+		}
+
+		// a new client, as after a restart, with bee not having the data
+		cache, err = bee.NewDiskCache(dir, bee.DefaultDiskCacheSize, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.DiskCache = cache
+		client = newTestBeeClientWithHandler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}), opts)
+		rcvdData, respCode, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if respCode != http.StatusOK || !bytes.Equal(data, rcvdData) {
+			t.Fatalf("uploaded blob not served from the disk cache")
+		}
+	})
+}
@@ -17,26 +17,40 @@ limitations under the License.
 package mock
 
 import (
+	"bytes"
 	"context"
-	"crypto/rand"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"sync"
 
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 )
 
+var (
+	ErrNotFound  = blockstore.ErrNotFound
+	ErrNotPinned = fmt.Errorf("reference not pinned")
+)
+
+// MockBeeClient is an in memory blockstore.Client. Blobs are split in to
+// chunks just like Bee does, so the same data always gets the same address.
 type MockBeeClient struct {
 	storer   map[string][]byte
+	pins     map[string]int
 	storerMu sync.RWMutex
+	splitter *blockstore.Splitter
 }
 
 func NewMockBeeClient() *MockBeeClient {
 	return &MockBeeClient{
 		storer:   make(map[string][]byte),
+		pins:     make(map[string]int),
 		storerMu: sync.RWMutex{},
+		splitter: blockstore.NewSplitter(),
 	}
 }
 
@@ -44,44 +58,196 @@ func (m *MockBeeClient) CheckConnection() bool {
 	return true
 }
 
-func (m *MockBeeClient) UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error) {
+func (m *MockBeeClient) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	m.storerMu.Lock()
 	defer m.storerMu.Unlock()
 	m.storer[ch.Address().String()] = ch.Data()
+	if pin {
+		m.pins[ch.Address().String()]++
+	}
 	return ch.Address().Bytes(), nil
 }
 
 func (m *MockBeeClient) DownloadChunk(ctx context.Context, address []byte) (data []byte, err error) {
-	m.storerMu.Lock()
-	defer m.storerMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.storerMu.RLock()
+	defer m.storerMu.RUnlock()
 	if data, ok := m.storer[swarm.NewAddress(address).String()]; ok {
 		return data, nil
 	}
-	return nil, fmt.Errorf("error downloading data")
+	return nil, ErrNotFound
 }
 
-func (m *MockBeeClient) UploadBlob(data []byte, pin bool, encrypt bool) (address []byte, err error) {
+func (m *MockBeeClient) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	root, chunks, err := m.splitter.Split(data)
+	if err != nil {
+		return nil, err
+	}
 	m.storerMu.Lock()
 	defer m.storerMu.Unlock()
-	address = make([]byte, 32)
-	_, err = rand.Read(address)
-	m.storer[swarm.NewAddress(address).String()] = data
-	return address, nil
+	for _, ch := range chunks {
+		m.storer[ch.Address().String()] = ch.Data()
+		if pin {
+			m.pins[ch.Address().String()]++
+		}
+	}
+	return root.Bytes(), nil
+}
+
+func (m *MockBeeClient) DownloadBlob(ctx context.Context, address []byte) (data []byte, respCode int, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	m.storerMu.RLock()
+	defer m.storerMu.RUnlock()
+	data, err = blockstore.Join(address, m.getChunk)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return data, http.StatusOK, nil
 }
 
-func (m *MockBeeClient) DownloadBlob(address []byte) (data []byte, respCode int, err error) {
+func (m *MockBeeClient) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return m.UploadBlob(ctx, data, pin, encrypt)
+}
+
+func (m *MockBeeClient) DownloadBlobStream(ctx context.Context, address []byte) (data io.ReadCloser, respCode int, err error) {
+	blob, respCode, err := m.DownloadBlob(ctx, address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(blob)), respCode, nil
+}
+
+func (m *MockBeeClient) PinBlob(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	m.storerMu.Lock()
 	defer m.storerMu.Unlock()
-	if data, ok := m.storer[swarm.NewAddress(address).String()]; ok {
-		return data, http.StatusOK, nil
+	var addresses []string
+	_, err := blockstore.Join(ref.Bytes(), func(address []byte) ([]byte, error) {
+		addresses = append(addresses, swarm.NewAddress(address).String())
+		return m.getChunk(address)
+	})
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		m.pins[addr]++
+	}
+	return nil
+}
+
+func (m *MockBeeClient) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
-	return nil, http.StatusInternalServerError, fmt.Errorf("error downloading data")
+	m.storerMu.Lock()
+	defer m.storerMu.Unlock()
+	return m.unpin(ref.String())
+}
+
+func (m *MockBeeClient) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.storerMu.Lock()
+	defer m.storerMu.Unlock()
+	var addresses []string
+	_, err := blockstore.Join(ref.Bytes(), func(address []byte) ([]byte, error) {
+		addresses = append(addresses, swarm.NewAddress(address).String())
+		return m.getChunk(address)
+	})
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		err = m.unpin(addr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (m *MockBeeClient) UnpinChunk(ref utils.Reference) error {
+// PinChunk pins an already stored chunk.
+func (m *MockBeeClient) PinChunk(address []byte) error {
+	m.storerMu.Lock()
+	defer m.storerMu.Unlock()
+	addr := swarm.NewAddress(address).String()
+	if _, ok := m.storer[addr]; !ok {
+		return ErrNotFound
+	}
+	m.pins[addr]++
 	return nil
 }
 
-func (m *MockBeeClient) UnpinBlob(ref utils.Reference) error {
+// PinCount returns the number of times a chunk is pinned.
+func (m *MockBeeClient) PinCount(address []byte) int {
+	m.storerMu.RLock()
+	defer m.storerMu.RUnlock()
+	return m.pins[swarm.NewAddress(address).String()]
+}
+
+// IsBlobPinned returns true if every chunk of the blob rooted at the address is pinned.
+func (m *MockBeeClient) IsBlobPinned(address []byte) bool {
+	m.storerMu.RLock()
+	defer m.storerMu.RUnlock()
+	pinned := true
+	_, err := blockstore.Join(address, func(address []byte) ([]byte, error) {
+		if m.pins[swarm.NewAddress(address).String()] == 0 {
+			pinned = false
+		}
+		return m.getChunk(address)
+	})
+	return err == nil && pinned
+}
+
+// PinnedChunks returns the addresses of all the pinned chunks.
+func (m *MockBeeClient) PinnedChunks() []string {
+	m.storerMu.RLock()
+	defer m.storerMu.RUnlock()
+	var pinned []string
+	for addr, count := range m.pins {
+		if count > 0 {
+			pinned = append(pinned, addr)
+		}
+	}
+	return pinned
+}
+
+func (m *MockBeeClient) getChunk(address []byte) ([]byte, error) {
+	if data, ok := m.storer[swarm.NewAddress(address).String()]; ok {
+		return data, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockBeeClient) unpin(addr string) error {
+	count, ok := m.pins[addr]
+	if !ok || count == 0 {
+		return ErrNotPinned
+	}
+	if count == 1 {
+		delete(m.pins, addr)
+		return nil
+	}
+	m.pins[addr] = count - 1
 	return nil
 }
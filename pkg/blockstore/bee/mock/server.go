@@ -0,0 +1,190 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	bytesPath      = "/bytes"
+	chunksPath     = "/chunks/"
+	pinChunksPath  = "/pinning/chunks/"
+	beeGreeting    = "Ethereum Swarm Bee\n"
+	swarmPinHeader = "Swarm-Pin"
+)
+
+type referenceResponse struct {
+	Reference swarm.Address `json:"reference"`
+}
+
+type pinResponse struct {
+	Address    swarm.Address `json:"address"`
+	PinCounter int           `json:"pinCounter"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+}
+
+// BeeServer emulates the subset of the Bee HTTP API used by bee.BeeClient.
+// It is backed by a MockBeeClient, so it can be used with httptest to
+// exercise the real client end to end without a Bee node.
+type BeeServer struct {
+	store *MockBeeClient
+}
+
+func NewBeeServer(store *MockBeeClient) *BeeServer {
+	return &BeeServer{
+		store: store,
+	}
+}
+
+func (b *BeeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(beeGreeting))
+	case r.URL.Path == bytesPath && r.Method == http.MethodPost:
+		b.uploadBytes(w, r)
+	case strings.HasPrefix(r.URL.Path, bytesPath+"/") && r.Method == http.MethodGet:
+		b.downloadBytes(w, r, strings.TrimPrefix(r.URL.Path, bytesPath+"/"))
+	case strings.HasPrefix(r.URL.Path, chunksPath):
+		b.chunks(w, r, strings.TrimPrefix(r.URL.Path, chunksPath))
+	case strings.HasPrefix(r.URL.Path, pinChunksPath):
+		b.pinChunks(w, r, strings.TrimPrefix(r.URL.Path, pinChunksPath))
+	default:
+		writeMessage(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+func (b *BeeServer) uploadBytes(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "could not read body")
+		return
+	}
+	addr, err := b.store.UploadBlob(r.Context(), data, isPinned(r), false)
+	if err != nil {
+		writeMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, referenceResponse{Reference: swarm.NewAddress(addr)})
+}
+
+func (b *BeeServer) downloadBytes(w http.ResponseWriter, r *http.Request, ref string) {
+	addr, err := swarm.ParseHexAddress(ref)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "invalid address")
+		return
+	}
+	data, respCode, err := b.store.DownloadBlob(r.Context(), addr.Bytes())
+	if err != nil {
+		writeMessage(w, respCode, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func (b *BeeServer) chunks(w http.ResponseWriter, r *http.Request, ref string) {
+	addr, err := swarm.ParseHexAddress(ref)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "invalid address")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeMessage(w, http.StatusBadRequest, "could not read body")
+			return
+		}
+		_, err = b.store.UploadChunk(r.Context(), swarm.NewChunk(addr, data), isPinned(r))
+		if err != nil {
+			writeMessage(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, referenceResponse{Reference: addr})
+	case http.MethodGet:
+		data, err := b.store.DownloadChunk(r.Context(), addr.Bytes())
+		if err != nil {
+			writeMessage(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (b *BeeServer) pinChunks(w http.ResponseWriter, r *http.Request, ref string) {
+	addr, err := swarm.ParseHexAddress(ref)
+	if err != nil {
+		writeMessage(w, http.StatusBadRequest, "invalid address")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		err = b.store.PinChunk(addr.Bytes())
+		if err != nil {
+			writeMessage(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeMessage(w, http.StatusOK, "OK")
+	case http.MethodDelete:
+		err = b.store.UnpinChunk(r.Context(), utils.NewReference(addr.Bytes()))
+		if err != nil {
+			writeMessage(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeMessage(w, http.StatusOK, "OK")
+	case http.MethodGet:
+		count := b.store.PinCount(addr.Bytes())
+		if count == 0 {
+			writeMessage(w, http.StatusNotFound, ErrNotPinned.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, pinResponse{Address: addr, PinCounter: count})
+	default:
+		writeMessage(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func isPinned(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get(swarmPinHeader)) == "true"
+}
+
+func writeMessage(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, messageResponse{Message: message, Code: code})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,460 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	RoundRobinReads = "roundrobin"
+	LatencyReads    = "latency"
+
+	DefaultHealthCheckInterval = 10 * time.Second
+	latencyWeight              = 8 // new latency samples count as 1/latencyWeight of the average
+)
+
+var (
+	ErrNoEndpoints = errors.New("no bee endpoints given")
+)
+
+// MultiOptions configures a MultiBeeClient.
+type MultiOptions struct {
+	Options                           // timeouts and retries of every endpoint
+	ReadStrategy        string        // RoundRobinReads or LatencyReads
+	HealthCheckInterval time.Duration // how often the endpoints are checked, 0 to disable
+}
+
+func DefaultMultiOptions() MultiOptions {
+	return MultiOptions{
+		Options:             DefaultOptions(),
+		ReadStrategy:        RoundRobinReads,
+		HealthCheckInterval: DefaultHealthCheckInterval,
+	}
+}
+
+type endpoint struct {
+	url     string
+	client  blockstore.Client
+	healthy int32 // accessed atomically, 1 if healthy
+	latency int64 // accessed atomically, moving average in nanoseconds
+}
+
+func (e *endpoint) isHealthy() bool {
+	return atomic.LoadInt32(&e.healthy) == 1
+}
+
+func (e *endpoint) setHealthy(healthy bool) bool {
+	var value int32
+	if healthy {
+		value = 1
+	}
+	return atomic.SwapInt32(&e.healthy, value) != value
+}
+
+func (e *endpoint) getLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&e.latency))
+}
+
+func (e *endpoint) addLatency(sample time.Duration) {
+	for {
+		old := atomic.LoadInt64(&e.latency)
+		avg := int64(sample)
+		if old != 0 {
+			avg = old + (int64(sample)-old)/latencyWeight
+		}
+		if atomic.CompareAndSwapInt64(&e.latency, old, avg) {
+			return
+		}
+	}
+}
+
+// MultiBeeClient is a blockstore.Client spread over several bee nodes.
+// Reads go to the healthy nodes round robin or to the fastest one, writes go
+// to the first healthy node in the given order and fail over to the next one.
+type MultiBeeClient struct {
+	endpoints []*endpoint
+	cache     *cache.Cache
+	strategy  string
+	next      uint32
+	quit      chan struct{}
+	closeOnce sync.Once
+	logger    logging.Logger
+}
+
+func NewMultiBeeClient(urls []string, opts MultiOptions, logger logging.Logger) (*MultiBeeClient, error) {
+	// the nodes hold the same data, so they share one cache
+	if opts.Cache == nil {
+		opts.Cache = cache.New(cache.DefaultSize)
+	}
+	var clients []blockstore.Client
+	for _, u := range urls {
+		client, err := NewBeeClientFromURL(u, opts.Options, logger)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return newMultiBeeClient(urls, clients, opts, logger)
+}
+
+func newMultiBeeClient(urls []string, clients []blockstore.Client, opts MultiOptions, logger logging.Logger) (*MultiBeeClient, error) {
+	if len(clients) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	switch opts.ReadStrategy {
+	case "":
+		opts.ReadStrategy = RoundRobinReads
+	case RoundRobinReads, LatencyReads:
+	default:
+		return nil, fmt.Errorf("unknown read strategy %s", opts.ReadStrategy)
+	}
+
+	if opts.Cache == nil {
+		opts.Cache = cache.New(cache.DefaultSize)
+	}
+	m := &MultiBeeClient{
+		cache:    opts.Cache,
+		strategy: opts.ReadStrategy,
+		quit:     make(chan struct{}),
+		logger:   logger,
+	}
+	for i, client := range clients {
+		m.endpoints = append(m.endpoints, &endpoint{url: urls[i], client: client})
+	}
+	m.checkEndpoints()
+	if opts.HealthCheckInterval > 0 {
+		go m.healthCheckLoop(opts.HealthCheckInterval)
+	}
+	return m, nil
+}
+
+// Close stops the health checks.
+func (m *MultiBeeClient) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.quit)
+	})
+	return nil
+}
+
+// Cache returns the in memory cache shared by the endpoints.
+func (m *MultiBeeClient) Cache() *cache.Cache {
+	return m.cache
+}
+
+// CheckConnection checks all the endpoints and returns true if any of them is healthy.
+func (m *MultiBeeClient) CheckConnection() bool {
+	return m.checkEndpoints() > 0
+}
+
+// HealthyEndpoints returns the urls of the endpoints which passed the last health check.
+func (m *MultiBeeClient) HealthyEndpoints() []string {
+	var urls []string
+	for _, e := range m.endpoints {
+		if e.isHealthy() {
+			urls = append(urls, e.url)
+		}
+	}
+	return urls
+}
+
+func (m *MultiBeeClient) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	err = m.write(ctx, func(e *endpoint) error {
+		address, err = e.client.UploadChunk(ctx, ch, pin)
+		return err
+	})
+	return address, err
+}
+
+func (m *MultiBeeClient) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error) {
+	err = m.write(ctx, func(e *endpoint) error {
+		address, err = e.client.UploadBlob(ctx, data, pin, encrypt)
+		return err
+	})
+	return address, err
+}
+
+func (m *MultiBeeClient) DownloadChunk(ctx context.Context, address []byte) (data []byte, err error) {
+	err = m.read(ctx, func(e *endpoint) (bool, error) {
+		data, err = e.client.DownloadChunk(ctx, address)
+		return errors.Is(err, blockstore.ErrNotFound), err
+	})
+	return data, err
+}
+
+func (m *MultiBeeClient) DownloadBlob(ctx context.Context, address []byte) (data []byte, respCode int, err error) {
+	err = m.read(ctx, func(e *endpoint) (bool, error) {
+		data, respCode, err = e.client.DownloadBlob(ctx, address)
+		return isBlobNotFound(respCode, err), err
+	})
+	return data, respCode, err
+}
+
+// UploadBlobStream fails over to the next node only if r is an io.Seeker,
+// since otherwise the data already sent to the failed node is gone.
+func (m *MultiBeeClient) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		e := m.writeOrder()[0]
+		address, err = e.client.UploadBlobStream(ctx, r, pin, encrypt)
+		if err != nil && ctx.Err() == nil {
+			m.markUnhealthy(e, err)
+		}
+		return address, err
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	err = m.write(ctx, func(e *endpoint) error {
+		_, err = seeker.Seek(start, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		address, err = e.client.UploadBlobStream(ctx, r, pin, encrypt)
+		return err
+	})
+	return address, err
+}
+
+func (m *MultiBeeClient) DownloadBlobStream(ctx context.Context, address []byte) (data io.ReadCloser, respCode int, err error) {
+	err = m.read(ctx, func(e *endpoint) (bool, error) {
+		data, respCode, err = e.client.DownloadBlobStream(ctx, address)
+		return isBlobNotFound(respCode, err), err
+	})
+	return data, respCode, err
+}
+
+// isBlobNotFound tells a missing blob apart from a failing node. The bee client
+// reports network errors as not found too.
+func isBlobNotFound(respCode int, err error) bool {
+	var netErr net.Error
+	return err != nil && respCode >= http.StatusBadRequest && respCode < http.StatusInternalServerError &&
+		!errors.As(err, &netErr)
+}
+
+// isNodeFailure tells if err is of the node or the network to it failing
+// rather than of the request.
+func isNodeFailure(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// PinBlob pins in every node which has the blob.
+func (m *MultiBeeClient) PinBlob(ctx context.Context, ref utils.Reference) error {
+	return m.broadcast(ctx, func(e *endpoint) error {
+		return e.client.PinBlob(ctx, ref)
+	})
+}
+
+// UnpinChunk unpins in every node, as the chunk may have been pinned in any of them after a fail over.
+func (m *MultiBeeClient) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	return m.broadcast(ctx, func(e *endpoint) error {
+		return e.client.UnpinChunk(ctx, ref)
+	})
+}
+
+// UnpinBlob unpins in every node, as the blob may have been pinned in any of them after a fail over.
+func (m *MultiBeeClient) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	return m.broadcast(ctx, func(e *endpoint) error {
+		return e.client.UnpinBlob(ctx, ref)
+	})
+}
+
+// write tries the endpoints in the given order, healthy ones first, till one succeeds.
+func (m *MultiBeeClient) write(ctx context.Context, op func(e *endpoint) error) error {
+	var err error
+	for _, e := range m.writeOrder() {
+		err = op(e)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		m.markUnhealthy(e, err)
+	}
+	return err
+}
+
+// read tries the endpoints in the order of the read strategy till one succeeds.
+// op returns true if the error is of the data not being in the node. The nodes
+// need not be in sync, so that is not final till every healthy node misses it,
+// and only then the not found error is returned.
+func (m *MultiBeeClient) read(ctx context.Context, op func(e *endpoint) (bool, error)) error {
+	var err, notFoundErr error
+	for _, e := range m.readOrder() {
+		// a node which is down is not asked once another one missed the data
+		if notFoundErr != nil && !e.isHealthy() {
+			break
+		}
+		to := time.Now()
+		notFound, opErr := op(e)
+		if opErr == nil {
+			e.addLatency(time.Since(to))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return opErr
+		}
+		if notFound {
+			notFoundErr = opErr
+			continue
+		}
+		err = opErr
+		m.markUnhealthy(e, err)
+	}
+	if err != nil {
+		return err
+	}
+	return notFoundErr
+}
+
+// broadcast runs op in all the healthy endpoints and fails only if none of them succeed.
+func (m *MultiBeeClient) broadcast(ctx context.Context, op func(e *endpoint) error) error {
+	var err error
+	succeeded := false
+	for _, e := range m.writeOrder() {
+		if !e.isHealthy() && succeeded {
+			continue
+		}
+		opErr := op(e)
+		if opErr == nil {
+			succeeded = true
+			continue
+		}
+		err = opErr
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	if succeeded {
+		return nil
+	}
+	return err
+}
+
+// writeOrder returns the healthy endpoints in the configured order followed by the unhealthy ones.
+func (m *MultiBeeClient) writeOrder() []*endpoint {
+	healthy, unhealthy := m.partition()
+	return append(healthy, unhealthy...)
+}
+
+// readOrder returns the healthy endpoints ordered by the read strategy followed by the unhealthy ones.
+func (m *MultiBeeClient) readOrder() []*endpoint {
+	healthy, unhealthy := m.partition()
+	switch m.strategy {
+	case LatencyReads:
+		sort.SliceStable(healthy, func(i, j int) bool {
+			return healthy[i].getLatency() < healthy[j].getLatency()
+		})
+	default:
+		if len(healthy) > 1 {
+			start := int(atomic.AddUint32(&m.next, 1)-1) % len(healthy)
+			healthy = append(healthy[start:], healthy[:start]...)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (m *MultiBeeClient) partition() ([]*endpoint, []*endpoint) {
+	var healthy, unhealthy []*endpoint
+	for _, e := range m.endpoints {
+		if e.isHealthy() {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	return healthy, unhealthy
+}
+
+// markUnhealthy marks e down if err is of the node failing, a network error or
+// a 5xx response. A request the node turned down is not a sign of it failing.
+func (m *MultiBeeClient) markUnhealthy(e *endpoint, err error) {
+	if !isNodeFailure(err) {
+		return
+	}
+	if e.setHealthy(false) {
+		fields := logrus.Fields{
+			"endpoint": e.url,
+			"error":    err,
+		}
+		m.logger.WithFields(fields).Log(logrus.WarnLevel, "bee endpoint down: ")
+	}
+}
+
+// checkEndpoints runs CheckConnection on all the endpoints in parallel and
+// returns the number of healthy ones.
+func (m *MultiBeeClient) checkEndpoints() int {
+	var wg sync.WaitGroup
+	var healthyCount int32
+	for _, e := range m.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			to := time.Now()
+			healthy := e.client.CheckConnection()
+			if healthy {
+				e.addLatency(time.Since(to))
+				atomic.AddInt32(&healthyCount, 1)
+			}
+			if e.setHealthy(healthy) {
+				fields := logrus.Fields{
+					"endpoint": e.url,
+					"healthy":  healthy,
+				}
+				m.logger.WithFields(fields).Log(logrus.InfoLevel, "bee endpoint status changed: ")
+			}
+		}(e)
+	}
+	wg.Wait()
+	return int(healthyCount)
+}
+
+func (m *MultiBeeClient) healthCheckLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.checkEndpoints()
+		case <-m.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bee_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+// testNode is a bee emulator which counts the requests it serves and can be
+// taken down or made to fail the requests with a status.
+type testNode struct {
+	server   *httptest.Server
+	requests int32
+	status   int32
+	delay    time.Duration
+}
+
+func newTestNode(t *testing.T, store *mock.MockBeeClient, delay time.Duration) *testNode {
+	t.Helper()
+	n := &testNode{delay: delay}
+	beeServer := mock.NewBeeServer(store)
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status := atomic.LoadInt32(&n.status); status != 0 {
+			w.WriteHeader(int(status))
+			return
+		}
+		if r.URL.Path != "/" {
+			atomic.AddInt32(&n.requests, 1)
+		}
+		time.Sleep(n.delay)
+		beeServer.ServeHTTP(w, r)
+	}))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *testNode) count() int32 {
+	return atomic.LoadInt32(&n.requests)
+}
+
+func (n *testNode) setDown(down bool) {
+	var status int
+	if down {
+		status = http.StatusServiceUnavailable
+	}
+	n.setStatus(status)
+}
+
+// setStatus makes the node fail every request with status, 0 serves them again.
+func (n *testNode) setStatus(status int) {
+	atomic.StoreInt32(&n.status, int32(status))
+}
+
+func newTestMultiClient(t *testing.T, opts bee.MultiOptions, nodes ...*testNode) *bee.MultiBeeClient {
+	t.Helper()
+	var urls []string
+	for _, n := range nodes {
+		urls = append(urls, n.server.URL)
+	}
+	client, err := bee.NewMultiBeeClient(urls, opts, logging.New(ioutil.Discard, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func testMultiOptions() bee.MultiOptions {
+	opts := bee.DefaultMultiOptions()
+	opts.MaxRetries = 0
+	opts.HealthCheckInterval = 0
+	return opts
+}
+
+func TestMultiBeeClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("round-robin-reads", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store, 0)
+		node2 := newTestNode(t, store, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		for i := 0; i < 10; i++ {
+			addr, err := store.UploadBlob(ctx, []byte{byte(i)}, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = client.DownloadBlob(ctx, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if node1.count() != 5 || node2.count() != 5 {
+			t.Fatalf("reads not balanced: %d, %d", node1.count(), node2.count())
+		}
+	})
+
+	t.Run("latency-reads", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		slow := newTestNode(t, store, 20*time.Millisecond)
+		fast := newTestNode(t, store, 0)
+		opts := testMultiOptions()
+		opts.ReadStrategy = bee.LatencyReads
+		client := newTestMultiClient(t, opts, slow, fast)
+
+		for i := 0; i < 5; i++ {
+			addr, err := store.UploadBlob(ctx, []byte{byte(i)}, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = client.DownloadBlob(ctx, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if slow.count() != 0 || fast.count() != 5 {
+			t.Fatalf("reads not sent to the fastest node: slow %d, fast %d", slow.count(), fast.count())
+		}
+	})
+
+	t.Run("write-failover", func(t *testing.T) {
+		store1 := mock.NewMockBeeClient()
+		store2 := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store1, 0)
+		node2 := newTestNode(t, store2, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		node1.setDown(true)
+		data := []byte("written to the second node")
+		addr, err := client.UploadBlob(ctx, data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !store2.IsBlobPinned(addr) {
+			t.Fatalf("blob not written to the second node")
+		}
+		healthy := client.HealthyEndpoints()
+		if len(healthy) != 1 || healthy[0] != node2.server.URL {
+			t.Fatalf("first node should be marked down, healthy: %v", healthy)
+		}
+	})
+
+	t.Run("read-failover", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store, 0)
+		node2 := newTestNode(t, store, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		data := []byte("read from any node")
+		addr, err := client.UploadBlob(ctx, data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node1.server.Close()
+		for i := 0; i < 4; i++ {
+			rcvdData, _, err := client.DownloadBlob(ctx, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, rcvdData) {
+				t.Fatalf("data mismatch")
+			}
+		}
+	})
+
+	t.Run("not-found-after-every-node-misses", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store, 0)
+		node2 := newTestNode(t, store, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		_, err := client.DownloadChunk(ctx, make([]byte, swarm.HashSize))
+		if !errors.Is(err, blockstore.ErrNotFound) {
+			t.Fatalf("expected not found error, got %v", err)
+		}
+		if node1.count() != 1 || node2.count() != 1 {
+			t.Fatalf("missing chunk looked up %d, %d times", node1.count(), node2.count())
+		}
+		if len(client.HealthyEndpoints()) != 2 {
+			t.Fatalf("a missing chunk should not mark a node down")
+		}
+	})
+
+	t.Run("chunk-in-one-node-only", func(t *testing.T) {
+		store1 := mock.NewMockBeeClient()
+		store2 := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store1, 0)
+		node2 := newTestNode(t, store2, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		// every read starts at a different node, so one of them misses first
+		for i := 0; i < 4; i++ {
+			addr := make([]byte, swarm.HashSize)
+			_, err := rand.Read(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = store2.UploadChunk(ctx, swarm.NewChunk(swarm.NewAddress(addr), []byte{byte(i)}), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.DownloadChunk(ctx, addr)
+			if err != nil {
+				t.Fatalf("chunk written in the second node not read: %v", err)
+			}
+		}
+	})
+
+	t.Run("request-errors-keep-node-up", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store, 0)
+		node2 := newTestNode(t, store, 0)
+		client := newTestMultiClient(t, testMultiOptions(), node1, node2)
+
+		node1.setStatus(http.StatusBadRequest)
+		node2.setStatus(http.StatusBadRequest)
+		_, err := client.UploadBlob(ctx, []byte("turned down"), false, false)
+		if err == nil {
+			t.Fatalf("expected upload to fail")
+		}
+		if len(client.HealthyEndpoints()) != 2 {
+			t.Fatalf("a 4xx response should not mark a node down, healthy: %v", client.HealthyEndpoints())
+		}
+
+		node1.setStatus(http.StatusInternalServerError)
+		node2.setStatus(0)
+		_, err = client.UploadBlob(ctx, []byte("failed over"), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		healthy := client.HealthyEndpoints()
+		if len(healthy) != 1 || healthy[0] != node2.server.URL {
+			t.Fatalf("a 5xx response should mark a node down, healthy: %v", healthy)
+		}
+	})
+
+	t.Run("health-check-recovery", func(t *testing.T) {
+		store := mock.NewMockBeeClient()
+		node1 := newTestNode(t, store, 0)
+		node2 := newTestNode(t, store, 0)
+		opts := testMultiOptions()
+		opts.HealthCheckInterval = 5 * time.Millisecond
+		client := newTestMultiClient(t, opts, node1, node2)
+
+		node1.setDown(true)
+		waitForHealthy(t, client, 1)
+		node1.setDown(false)
+		waitForHealthy(t, client, 2)
+
+		node1.setDown(true)
+		node2.setDown(true)
+		if client.CheckConnection() {
+			t.Fatalf("connection reported with all nodes down")
+		}
+	})
+}
+
+func waitForHealthy(t *testing.T, client *bee.MultiBeeClient, count int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(client.HealthyEndpoints()) != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d healthy endpoints, got %d", count, len(client.HealthyEndpoints()))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -18,18 +18,27 @@ package blockstore
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 )
 
+// ErrNotFound is returned by the clients when the chunk or blob asked for is
+// not in the store.
+var ErrNotFound = errors.New("error downloading data")
+
 type Client interface {
 	CheckConnection() bool
-	UploadChunk(ch swarm.Chunk, pin bool) (address []byte, err error)
-	UploadBlob(data []byte, pin bool, encrypt bool) (address []byte, err error)
+	UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error)
+	UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error)
 	DownloadChunk(ctx context.Context, address []byte) (data []byte, err error)
-	DownloadBlob(address []byte) (data []byte, respCode int, err error)
-	UnpinChunk(ref utils.Reference) error
-	UnpinBlob(ref utils.Reference) error
+	DownloadBlob(ctx context.Context, address []byte) (data []byte, respCode int, err error)
+	UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error)
+	DownloadBlobStream(ctx context.Context, address []byte) (data io.ReadCloser, respCode int, err error)
+	PinBlob(ctx context.Context, ref utils.Reference) error
+	UnpinChunk(ctx context.Context, ref utils.Reference) error
+	UnpinBlob(ctx context.Context, ref utils.Reference) error
 }
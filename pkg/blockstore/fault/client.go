@@ -0,0 +1,301 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fault provides a blockstore.Client which injects faults in to the
+// calls made to another client, to test how the layers above behave when the
+// block store fails half way through an operation.
+package fault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// ErrInjected is returned by the calls which are made to fail.
+var ErrInjected = errors.New("fault: injected error")
+
+// Op is a kind of call to the block store.
+type Op string
+
+const (
+	UploadChunk   Op = "UploadChunk"
+	DownloadChunk Op = "DownloadChunk"
+	UploadBlob    Op = "UploadBlob"   // UploadBlob and UploadBlobStream
+	DownloadBlob  Op = "DownloadBlob" // DownloadBlob and DownloadBlobStream
+	Pin           Op = "Pin"          // PinBlob
+	Unpin         Op = "Unpin"        // UnpinChunk and UnpinBlob
+)
+
+// Client wraps a blockstore.Client and injects latency, errors and partial
+// responses in to its calls. The faults can be changed at any time, a new
+// Client injects none.
+type Client struct {
+	blockstore.Client
+	rand        *rand.Rand
+	latency     time.Duration
+	errorRate   map[Op]float64
+	failAfter   map[Op]int
+	partialRate float64
+	addresses   map[string]bool
+	calls       map[Op]int
+	mu          sync.Mutex
+}
+
+// New returns a Client over client. The seed makes the random faults
+// repeatable.
+func New(client blockstore.Client, seed int64) *Client {
+	c := &Client{
+		Client: client,
+		rand:   rand.New(rand.NewSource(seed)),
+		calls:  make(map[Op]int),
+	}
+	c.Reset()
+	return c
+}
+
+// Reset removes all the faults.
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latency = 0
+	c.errorRate = make(map[Op]float64)
+	c.failAfter = make(map[Op]int)
+	c.partialRate = 0
+	c.addresses = make(map[string]bool)
+}
+
+// SetLatency delays every call by d.
+func (c *Client) SetLatency(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.latency = d
+}
+
+// SetErrorRate makes the given ops fail with ErrInjected at the given rate,
+// from 0 for never to 1 for always. All the ops are set if none is given.
+func (c *Client) SetErrorRate(rate float64, ops ...Op) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(ops) == 0 {
+		ops = []Op{UploadChunk, DownloadChunk, UploadBlob, DownloadBlob, Pin, Unpin}
+	}
+	for _, op := range ops {
+		c.errorRate[op] = rate
+	}
+}
+
+// FailAfter lets the next n calls of op succeed and makes the ones after
+// them fail.
+func (c *Client) FailAfter(op Op, n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failAfter[op] = c.calls[op] + n
+}
+
+// SetPartialRate makes downloads return only the first half of the data, as
+// if the response was cut short, at the given rate.
+func (c *Client) SetPartialRate(rate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.partialRate = rate
+}
+
+// FailAddress makes every call on the address fail. Uploads of blobs fail
+// only after the blob is stored, as their address is not known before.
+func (c *Client) FailAddress(address []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addresses[swarm.NewAddress(address).String()] = true
+}
+
+// Calls returns the number of calls made of op, failed ones included.
+func (c *Client) Calls(op Op) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[op]
+}
+
+// Cache returns the cache of the wrapped client, if it has one, so that the
+// layers above keep sharing it.
+func (c *Client) Cache() *cache.Cache {
+	if provider, ok := c.Client.(cache.Provider); ok {
+		return provider.Cache()
+	}
+	return nil
+}
+
+func (c *Client) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	err := c.inject(ctx, UploadChunk, ch.Address().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return c.Client.UploadChunk(ctx, ch, pin)
+}
+
+func (c *Client) DownloadChunk(ctx context.Context, address []byte) ([]byte, error) {
+	err := c.inject(ctx, DownloadChunk, address)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Client.DownloadChunk(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return c.partial(data), nil
+}
+
+func (c *Client) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) ([]byte, error) {
+	err := c.inject(ctx, UploadBlob, nil)
+	if err != nil {
+		return nil, err
+	}
+	address, err := c.Client.UploadBlob(ctx, data, pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	return address, c.checkAddress(address)
+}
+
+func (c *Client) DownloadBlob(ctx context.Context, address []byte) ([]byte, int, error) {
+	err := c.inject(ctx, DownloadBlob, address)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	data, respCode, err := c.Client.DownloadBlob(ctx, address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return c.partial(data), respCode, nil
+}
+
+func (c *Client) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) ([]byte, error) {
+	err := c.inject(ctx, UploadBlob, nil)
+	if err != nil {
+		return nil, err
+	}
+	address, err := c.Client.UploadBlobStream(ctx, r, pin, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	return address, c.checkAddress(address)
+}
+
+func (c *Client) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	err := c.inject(ctx, DownloadBlob, address)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	body, respCode, err := c.Client.DownloadBlobStream(ctx, address)
+	if err != nil {
+		return nil, respCode, err
+	}
+	if !c.isPartial() {
+		return body, respCode, nil
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data[:len(data)/2])), respCode, nil
+}
+
+func (c *Client) PinBlob(ctx context.Context, ref utils.Reference) error {
+	err := c.inject(ctx, Pin, ref.Bytes())
+	if err != nil {
+		return err
+	}
+	return c.Client.PinBlob(ctx, ref)
+}
+
+func (c *Client) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	err := c.inject(ctx, Unpin, ref.Bytes())
+	if err != nil {
+		return err
+	}
+	return c.Client.UnpinChunk(ctx, ref)
+}
+
+func (c *Client) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	err := c.inject(ctx, Unpin, ref.Bytes())
+	if err != nil {
+		return err
+	}
+	return c.Client.UnpinBlob(ctx, ref)
+}
+
+// inject counts the call, waits for the latency and returns ErrInjected if
+// the call is to fail.
+func (c *Client) inject(ctx context.Context, op Op, address []byte) error {
+	c.mu.Lock()
+	c.calls[op]++
+	latency := c.latency
+	fail := c.rand.Float64() < c.errorRate[op]
+	if n, ok := c.failAfter[op]; ok && c.calls[op] > n {
+		fail = true
+	}
+	if address != nil && c.addresses[swarm.NewAddress(address).String()] {
+		fail = true
+	}
+	c.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
+
+func (c *Client) checkAddress(address []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.addresses[swarm.NewAddress(address).String()] {
+		return ErrInjected
+	}
+	return nil
+}
+
+func (c *Client) isPartial() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rand.Float64() < c.partialRate
+}
+
+func (c *Client) partial(data []byte) []byte {
+	if !c.isPartial() {
+		return data
+	}
+	return data[:len(data)/2]
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fault_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestFaultClient(t *testing.T) {
+	store := mock.NewMockBeeClient()
+	client := fault.New(store, 1)
+	data := []byte("some data to fail on")
+
+	t.Run("no-faults", func(t *testing.T) {
+		addr, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("data mismatch")
+		}
+	})
+
+	t.Run("error-rate", func(t *testing.T) {
+		defer client.Reset()
+		client.SetErrorRate(1, fault.UploadBlob)
+		_, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+		_, err = client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+
+		// other ops are not affected
+		addr, err := store.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.SetErrorRate(0.5, fault.DownloadBlob)
+		failed := 0
+		for i := 0; i < 100; i++ {
+			_, _, err = client.DownloadBlob(context.Background(), addr)
+			if err != nil {
+				failed++
+			}
+		}
+		if failed == 0 || failed == 100 {
+			t.Fatalf("%d of 100 downloads failed", failed)
+		}
+	})
+
+	t.Run("fail-after", func(t *testing.T) {
+		defer client.Reset()
+		client.FailAfter(fault.UploadBlob, 2)
+		for i := 0; i < 2; i++ {
+			_, err := client.UploadBlob(context.Background(), data, false, false)
+			if err != nil {
+				t.Fatalf("upload %d failed: %v", i, err)
+			}
+		}
+		_, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+	})
+
+	t.Run("fail-address", func(t *testing.T) {
+		defer client.Reset()
+		addr, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.FailAddress(addr)
+		_, _, err = client.DownloadBlob(context.Background(), addr)
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+
+		// the blob gets stored, but its upload is reported as failed
+		_, err = client.UploadBlob(context.Background(), data, false, false)
+		if err != fault.ErrInjected {
+			t.Fatalf("expected injected error, got %v", err)
+		}
+	})
+
+	t.Run("partial-response", func(t *testing.T) {
+		defer client.Reset()
+		addr, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SetPartialRate(1)
+		got, _, err := client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data[:len(data)/2]) {
+			t.Fatalf("expected half of the data, got %d bytes", len(got))
+		}
+		body, _, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data[:len(data)/2]) {
+			t.Fatalf("expected half of the data, got %d bytes", len(got))
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		defer client.Reset()
+		client.SetLatency(50 * time.Millisecond)
+		start := time.Now()
+		_, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) < 50*time.Millisecond {
+			t.Fatalf("call not delayed")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = client.UploadBlob(ctx, data, false, false)
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		before := client.Calls(fault.DownloadChunk)
+		_, _ = client.DownloadChunk(context.Background(), make([]byte, 32))
+		if client.Calls(fault.DownloadChunk) != before+1 {
+			t.Fatalf("call not counted")
+		}
+	})
+}
@@ -0,0 +1,373 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	chunksDir = "chunks"
+	pinsDir   = "pins"
+	dirPerm   = 0700
+	filePerm  = 0600
+)
+
+var (
+	ErrNotFound  = blockstore.ErrNotFound
+	ErrNotPinned = errors.New("reference not pinned")
+)
+
+// LocalClient is a blockstore.Client which stores chunks and blobs content
+// addressed in the local file system. It lets dfs run without a Bee node.
+type LocalClient struct {
+	dataDir     string
+	splitter    *blockstore.Splitter
+	pinMu       sync.Mutex
+	encryptOnce sync.Once
+	logger      logging.Logger
+}
+
+func NewLocalClient(dataDir string, logger logging.Logger) (*LocalClient, error) {
+	for _, dir := range []string{chunksDir, pinsDir} {
+		err := os.MkdirAll(filepath.Join(dataDir, dir), dirPerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &LocalClient{
+		dataDir:  dataDir,
+		splitter: blockstore.NewSplitter(),
+		logger:   logger,
+	}, nil
+}
+
+func (s *LocalClient) CheckConnection() bool {
+	info, err := os.Stat(filepath.Join(s.dataDir, chunksDir))
+	if err != nil {
+		return false
+	}
+	return info.IsDir()
+}
+
+// upload a chunk in the local store
+func (s *LocalClient) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	err = s.putChunk(ch.Address(), ch.Data())
+	if err != nil {
+		return nil, err
+	}
+	if pin {
+		err = s.changePin(ch.Address(), 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fields := logrus.Fields{
+		"reference": ch.Address().String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload chunk: ")
+	return ch.Address().Bytes(), nil
+}
+
+// download a chunk from the local store
+func (s *LocalClient) DownloadChunk(ctx context.Context, address []byte) (data []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	addr := swarm.NewAddress(address)
+	data, err = s.getChunk(addr)
+	if err != nil {
+		return nil, err
+	}
+	fields := logrus.Fields{
+		"reference": addr.String(),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download chunk: ")
+	return data, nil
+}
+
+// upload a blob in the local store. the blob is split in to swarm chunks
+// so that the returned reference is the same as the one Bee would return.
+// The local store does not encrypt, the data is kept unencrypted even if
+// encrypt is set, which is warned about once.
+func (s *LocalClient) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) (address []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if encrypt {
+		s.encryptOnce.Do(func() {
+			s.logger.Warningf("local store: encryption is not supported, blobs are stored unencrypted in %s", s.dataDir)
+		})
+	}
+	to := time.Now()
+	root, chunks, err := s.splitter.Split(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range chunks {
+		err = s.putChunk(ch.Address(), ch.Data())
+		if err != nil {
+			return nil, err
+		}
+		if pin {
+			err = s.changePin(ch.Address(), 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	fields := logrus.Fields{
+		"reference": root.String(),
+		"size":      len(data),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "upload blob: ")
+	return root.Bytes(), nil
+}
+
+func (s *LocalClient) DownloadBlob(ctx context.Context, address []byte) ([]byte, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	to := time.Now()
+	data, err := blockstore.Join(address, s.getVerifiedChunk)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	fields := logrus.Fields{
+		"reference": swarm.NewAddress(address).String(),
+		"size":      len(data),
+		"duration":  time.Since(to).String(),
+	}
+	s.logger.WithFields(fields).Log(logrus.DebugLevel, "download blob: ")
+	return data, http.StatusOK, nil
+}
+
+// UploadBlobStream reads the blob from r and uploads it like UploadBlob.
+func (s *LocalClient) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) (address []byte, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.UploadBlob(ctx, data, pin, encrypt)
+}
+
+// DownloadBlobStream returns a reader which joins the chunks of the blob as
+// they are read, so the whole blob is never held in memory.
+func (s *LocalClient) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, http.StatusRequestTimeout, err
+	}
+	_, err := s.getVerifiedChunk(address)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, http.StatusNotFound, err
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := blockstore.JoinTo(address, func(address []byte) ([]byte, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return s.getVerifiedChunk(address)
+		}, pw)
+		_ = pw.CloseWithError(err)
+	}()
+	return pr, http.StatusOK, nil
+}
+
+// PinBlob pins all the chunks of the blob tree rooted at the given reference.
+func (s *LocalClient) PinBlob(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var addresses []swarm.Address
+	_, err := blockstore.Join(ref.Bytes(), func(address []byte) ([]byte, error) {
+		addresses = append(addresses, swarm.NewAddress(address))
+		return s.getVerifiedChunk(address)
+	})
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		err = s.changePin(addr, 1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalClient) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.changePin(swarm.NewAddress(ref.Bytes()), -1)
+}
+
+// UnpinBlob unpins all the chunks of the blob tree rooted at the given reference.
+func (s *LocalClient) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var addresses []swarm.Address
+	_, err := blockstore.Join(ref.Bytes(), func(address []byte) ([]byte, error) {
+		addresses = append(addresses, swarm.NewAddress(address))
+		return s.getVerifiedChunk(address)
+	})
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		err = s.changePin(addr, -1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PinCount returns the number of times a chunk has been pinned.
+func (s *LocalClient) PinCount(address []byte) (uint64, error) {
+	s.pinMu.Lock()
+	defer s.pinMu.Unlock()
+	return s.readPin(swarm.NewAddress(address))
+}
+
+func (s *LocalClient) chunkPath(addr swarm.Address) string {
+	addrString := addr.String()
+	return filepath.Join(s.dataDir, chunksDir, addrString[:2], addrString)
+}
+
+func (s *LocalClient) pinPath(addr swarm.Address) string {
+	return filepath.Join(s.dataDir, pinsDir, addr.String())
+}
+
+func (s *LocalClient) putChunk(addr swarm.Address, data []byte) error {
+	if addr.IsZero() {
+		return errors.New("invalid chunk address")
+	}
+	path := s.chunkPath(addr)
+
+	// nothing to do if the same chunk is already present. A single owner
+	// chunk, like a feed update, has an address which does not depend on its
+	// data, so a different one at the address overwrites it, as in Bee.
+	if stored, err := ioutil.ReadFile(path); err == nil && bytes.Equal(stored, data) {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Dir(path), dirPerm)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, data, filePerm)
+}
+
+func (s *LocalClient) getChunk(addr swarm.Address) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.chunkPath(addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// getVerifiedChunk loads a content addressed chunk and checks its data
+// against the address before handing it out.
+func (s *LocalClient) getVerifiedChunk(address []byte) ([]byte, error) {
+	addr := swarm.NewAddress(address)
+	data, err := s.getChunk(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < swarm.SpanSize {
+		return nil, blockstore.ErrInvalidChunk
+	}
+	span := binary.LittleEndian.Uint64(data[:swarm.SpanSize])
+	computed, err := s.splitter.ChunkAddress(span, data[swarm.SpanSize:])
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(computed.Bytes(), address) {
+		return nil, blockstore.ErrInvalidChunk
+	}
+	return data, nil
+}
+
+func (s *LocalClient) changePin(addr swarm.Address, delta int) error {
+	s.pinMu.Lock()
+	defer s.pinMu.Unlock()
+	count, err := s.readPin(addr)
+	if err != nil {
+		return err
+	}
+	if delta < 0 {
+		if count == 0 {
+			return ErrNotPinned
+		}
+		count--
+		if count == 0 {
+			return os.Remove(s.pinPath(addr))
+		}
+	} else {
+		count++
+	}
+	return utils.WriteFileAtomic(s.pinPath(addr), []byte(strconv.FormatUint(count, 10)), filePerm)
+}
+
+func (s *LocalClient) readPin(addr swarm.Address) (uint64, error) {
+	data, err := ioutil.ReadFile(s.pinPath(addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,259 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestLocalClient(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	dataDir, err := ioutil.TempDir("", "local-blockstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+
+	client, err := NewLocalClient(dataDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.CheckConnection() {
+		t.Fatalf("local store not ready")
+	}
+
+	t.Run("upload-download-blob", func(t *testing.T) {
+		for _, size := range []int{0, 10, swarm.ChunkSize, swarm.ChunkSize*swarm.Branches + 1} {
+			data := make([]byte, size)
+			_, err := rand.Read(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr, err := client.UploadBlob(context.Background(), data, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdData, respCode, err := client.DownloadBlob(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respCode != http.StatusOK {
+				t.Fatalf("invalid response code %d", respCode)
+			}
+			if !bytes.Equal(data, rcvdData) {
+				t.Fatalf("data mismatch for size %d", size)
+			}
+		}
+	})
+
+	t.Run("upload-download-blob-stream", func(t *testing.T) {
+		data := make([]byte, swarm.ChunkSize*swarm.Branches+1)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, respCode, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+		if respCode != http.StatusOK {
+			t.Fatalf("invalid response code %d", respCode)
+		}
+		rcvdData, err := ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("data mismatch")
+		}
+	})
+
+	t.Run("same-data-same-address", func(t *testing.T) {
+		data := []byte("content addressed")
+		addr1, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr2, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr1, addr2) {
+			t.Fatalf("same data uploaded to different addresses")
+		}
+	})
+
+	t.Run("download-missing-blob", func(t *testing.T) {
+		addr := make([]byte, swarm.HashSize)
+		_, respCode, err := client.DownloadBlob(context.Background(), addr)
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		if respCode != http.StatusNotFound {
+			t.Fatalf("invalid response code %d", respCode)
+		}
+	})
+
+	t.Run("corrupted-chunk", func(t *testing.T) {
+		addr, err := client.UploadBlob(context.Background(), []byte("will be corrupted"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(client.chunkPath(swarm.NewAddress(addr)), []byte("garbage data"), filePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(context.Background(), addr)
+		if err == nil {
+			t.Fatalf("corrupted chunk not detected")
+		}
+
+		// unpinning walks the blob the same verified way as pinning
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err == nil || err == ErrNotPinned {
+			t.Fatalf("corrupted chunk not detected on unpin: %v", err)
+		}
+		count, err := client.PinCount(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("expected pin count 1, got %d", count)
+		}
+	})
+
+	t.Run("upload-download-chunk", func(t *testing.T) {
+		data := []byte{0, 1, 2, 3}
+		addr := make([]byte, swarm.HashSize)
+		_, err := rand.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.UploadChunk(context.Background(), swarm.NewChunk(swarm.NewAddress(addr), data), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcvdData, err := client.DownloadChunk(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("chunk data mismatch")
+		}
+	})
+
+	t.Run("overwrite-single-owner-chunk", func(t *testing.T) {
+		// the address of a single owner chunk does not depend on its data
+		addr := make([]byte, swarm.HashSize)
+		_, err := rand.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range [][]byte{[]byte("first update"), []byte("second update")} {
+			_, err = client.UploadChunk(context.Background(), swarm.NewChunk(swarm.NewAddress(addr), data), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdData, err := client.DownloadChunk(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, rcvdData) {
+				t.Fatalf("expected %q at the address, got %q", data, rcvdData)
+			}
+		}
+	})
+
+	t.Run("pin-unpin", func(t *testing.T) {
+		data := make([]byte, swarm.ChunkSize*2)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := client.PinCount(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("expected pin count 1, got %d", count)
+		}
+
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err = client.PinCount(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatalf("expected pin count 0, got %d", count)
+		}
+
+		err = client.UnpinBlob(context.Background(), utils.NewReference(addr))
+		if err != ErrNotPinned {
+			t.Fatalf("expected not pinned error, got %v", err)
+		}
+	})
+
+	t.Run("persist-across-restart", func(t *testing.T) {
+		data := []byte("survives a restart")
+		addr, err := client.UploadBlob(context.Background(), data, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client2, err := NewLocalClient(dataDir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcvdData, _, err := client2.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("data mismatch after restart")
+		}
+		count, err := client2.PinCount(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("pin lost after restart")
+		}
+	})
+}
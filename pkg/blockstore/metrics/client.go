@@ -0,0 +1,400 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics provides a blockstore.Client which measures the calls made
+// to another client and exports them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	CheckConnection    = "CheckConnection"
+	UploadChunk        = "UploadChunk"
+	DownloadChunk      = "DownloadChunk"
+	UploadBlob         = "UploadBlob"
+	DownloadBlob       = "DownloadBlob"
+	UploadBlobStream   = "UploadBlobStream"
+	DownloadBlobStream = "DownloadBlobStream"
+	PinBlob            = "PinBlob"
+	UnpinChunk         = "UnpinChunk"
+	UnpinBlob          = "UnpinBlob"
+
+	DefaultSlowThreshold = 5 * time.Second
+)
+
+// Buckets are the upper bounds in seconds of the latency histograms.
+var Buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+var ops = []string{
+	CheckConnection, UploadChunk, DownloadChunk, UploadBlob, DownloadBlob,
+	UploadBlobStream, DownloadBlobStream, PinBlob, UnpinChunk, UnpinBlob,
+}
+
+// Client is a blockstore.Client which counts the calls made to the client it
+// wraps, their errors, the ones for data not in the store, the bytes they
+// moved and the time they took, for every operation. Every call is traced in the log, the ones slower than the slow
+// threshold as warnings.
+type Client struct {
+	blockstore.Client
+	ops           map[string]*opMetrics
+	slowThreshold time.Duration
+	logger        logging.Logger
+}
+
+// Stats are the measurements of an operation. Buckets holds the number of
+// calls which took at most the matching Buckets bound.
+type Stats struct {
+	Requests uint64        `json:"requests"`
+	Errors   uint64        `json:"errors"`
+	NotFound uint64        `json:"not_found"`
+	Bytes    uint64        `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	Buckets  []uint64      `json:"buckets"`
+}
+
+type opMetrics struct {
+	stats Stats
+	mu    sync.Mutex
+}
+
+// New wraps client. Calls slower than slowThreshold are logged as warnings,
+// a zero threshold turns that off.
+func New(client blockstore.Client, slowThreshold time.Duration, logger logging.Logger) *Client {
+	c := &Client{
+		Client:        client,
+		ops:           make(map[string]*opMetrics),
+		slowThreshold: slowThreshold,
+		logger:        logger,
+	}
+	for _, op := range ops {
+		c.ops[op] = &opMetrics{stats: Stats{Buckets: make([]uint64, len(Buckets))}}
+	}
+	return c
+}
+
+// Cache returns the cache of the wrapped client, if it has one, so that the
+// layers above keep sharing it.
+func (c *Client) Cache() *cache.Cache {
+	if provider, ok := c.Client.(cache.Provider); ok {
+		return provider.Cache()
+	}
+	return nil
+}
+
+func (c *Client) CheckConnection() bool {
+	start := time.Now()
+	ok := c.Client.CheckConnection()
+	var err error
+	if !ok {
+		err = fmt.Errorf("no connection")
+	}
+	c.observe(CheckConnection, nil, 0, start, err)
+	return ok
+}
+
+func (c *Client) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	start := time.Now()
+	address, err := c.Client.UploadChunk(ctx, ch, pin)
+	c.observe(UploadChunk, ch.Address().Bytes(), len(ch.Data()), start, err)
+	return address, err
+}
+
+func (c *Client) DownloadChunk(ctx context.Context, address []byte) ([]byte, error) {
+	start := time.Now()
+	data, err := c.Client.DownloadChunk(ctx, address)
+	c.observe(DownloadChunk, address, len(data), start, err)
+	return data, err
+}
+
+func (c *Client) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) ([]byte, error) {
+	start := time.Now()
+	address, err := c.Client.UploadBlob(ctx, data, pin, encrypt)
+	c.observe(UploadBlob, address, len(data), start, err)
+	return address, err
+}
+
+func (c *Client) DownloadBlob(ctx context.Context, address []byte) ([]byte, int, error) {
+	start := time.Now()
+	data, respCode, err := c.Client.DownloadBlob(ctx, address)
+	if err == nil && respCode != http.StatusOK {
+		err = fmt.Errorf("response code %d", respCode)
+	}
+	c.observe(DownloadBlob, address, len(data), start, err)
+	return data, respCode, err
+}
+
+// UploadBlobStream counts the bytes read from r, so the latency includes the
+// time taken to produce them. An r which is an io.Seeker stays one, so that
+// the wrapped client can still retry the upload.
+func (c *Client) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) ([]byte, error) {
+	start := time.Now()
+	cr := &countingReader{r: r}
+	body := io.Reader(cr)
+	if seeker, ok := r.(io.Seeker); ok {
+		body = &countingReadSeeker{countingReader: cr, s: seeker}
+	}
+	address, err := c.Client.UploadBlobStream(ctx, body, pin, encrypt)
+	c.observe(UploadBlobStream, address, int(cr.n), start, err)
+	return address, err
+}
+
+// DownloadBlobStream measures the latency till the body is available, its
+// bytes are counted as they are read.
+func (c *Client) DownloadBlobStream(ctx context.Context, address []byte) (io.ReadCloser, int, error) {
+	start := time.Now()
+	body, respCode, err := c.Client.DownloadBlobStream(ctx, address)
+	c.observe(DownloadBlobStream, address, 0, start, err)
+	if err != nil {
+		return nil, respCode, err
+	}
+	return &countingReadCloser{ReadCloser: body, m: c.ops[DownloadBlobStream]}, respCode, nil
+}
+
+func (c *Client) PinBlob(ctx context.Context, ref utils.Reference) error {
+	start := time.Now()
+	err := c.Client.PinBlob(ctx, ref)
+	c.observe(PinBlob, ref.Bytes(), 0, start, err)
+	return err
+}
+
+func (c *Client) UnpinChunk(ctx context.Context, ref utils.Reference) error {
+	start := time.Now()
+	err := c.Client.UnpinChunk(ctx, ref)
+	c.observe(UnpinChunk, ref.Bytes(), 0, start, err)
+	return err
+}
+
+func (c *Client) UnpinBlob(ctx context.Context, ref utils.Reference) error {
+	start := time.Now()
+	err := c.Client.UnpinBlob(ctx, ref)
+	c.observe(UnpinBlob, ref.Bytes(), 0, start, err)
+	return err
+}
+
+// Stats returns the measurements of every operation.
+func (c *Client) Stats() map[string]Stats {
+	stats := make(map[string]Stats, len(c.ops))
+	for op, m := range c.ops {
+		m.mu.Lock()
+		s := m.stats
+		s.Buckets = append([]uint64(nil), m.stats.Buckets...)
+		m.mu.Unlock()
+		stats[op] = s
+	}
+	return stats
+}
+
+// Handler serves the measurements, and the counters of the cache of the
+// wrapped client if it has one, in the Prometheus text format.
+func (c *Client) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = c.Write(w)
+	})
+}
+
+// Write writes the measurements in the Prometheus text format.
+func (c *Client) Write(w io.Writer) error {
+	stats := c.Stats()
+	names := make([]string, 0, len(stats))
+	for op := range stats {
+		names = append(names, op)
+	}
+	sort.Strings(names)
+
+	pw := &promWriter{w: w}
+	pw.header("dfs_blockstore_requests_total", "Calls made to the block store.", "counter")
+	for _, op := range names {
+		pw.sample("dfs_blockstore_requests_total", fmt.Sprintf("op=%q", op), stats[op].Requests)
+	}
+	pw.header("dfs_blockstore_errors_total", "Calls to the block store which failed.", "counter")
+	for _, op := range names {
+		pw.sample("dfs_blockstore_errors_total", fmt.Sprintf("op=%q", op), stats[op].Errors)
+	}
+	pw.header("dfs_blockstore_not_found_total", "Calls to the block store for data which is not in it.", "counter")
+	for _, op := range names {
+		pw.sample("dfs_blockstore_not_found_total", fmt.Sprintf("op=%q", op), stats[op].NotFound)
+	}
+	pw.header("dfs_blockstore_bytes_total", "Bytes sent to or received from the block store.", "counter")
+	for _, op := range names {
+		pw.sample("dfs_blockstore_bytes_total", fmt.Sprintf("op=%q", op), stats[op].Bytes)
+	}
+	pw.header("dfs_blockstore_request_duration_seconds", "Time taken by the calls to the block store.", "histogram")
+	for _, op := range names {
+		s := stats[op]
+		for i, bound := range Buckets {
+			pw.sample("dfs_blockstore_request_duration_seconds_bucket", fmt.Sprintf("op=%q,le=\"%g\"", op, bound), s.Buckets[i])
+		}
+		pw.sample("dfs_blockstore_request_duration_seconds_bucket", fmt.Sprintf("op=%q,le=\"+Inf\"", op), s.Requests)
+		pw.sample("dfs_blockstore_request_duration_seconds_sum", fmt.Sprintf("op=%q", op), s.Duration.Seconds())
+		pw.sample("dfs_blockstore_request_duration_seconds_count", fmt.Sprintf("op=%q", op), s.Requests)
+	}
+
+	if ch := c.Cache(); ch != nil {
+		segments := ch.Stats()
+		names = names[:0]
+		for name := range segments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pw.header("dfs_cache_max_size_bytes", "Size budget of the cache.", "gauge")
+		pw.sample("dfs_cache_max_size_bytes", "", ch.MaxSize())
+		pw.header("dfs_cache_size_bytes", "Bytes held in the cache.", "gauge")
+		for _, name := range names {
+			pw.sample("dfs_cache_size_bytes", fmt.Sprintf("segment=%q", name), segments[name].Size)
+		}
+		pw.header("dfs_cache_entries", "Entries held in the cache.", "gauge")
+		for _, name := range names {
+			pw.sample("dfs_cache_entries", fmt.Sprintf("segment=%q", name), segments[name].Entries)
+		}
+		pw.header("dfs_cache_hits_total", "Lookups found in the cache.", "counter")
+		for _, name := range names {
+			pw.sample("dfs_cache_hits_total", fmt.Sprintf("segment=%q", name), segments[name].Hits)
+		}
+		pw.header("dfs_cache_misses_total", "Lookups not found in the cache.", "counter")
+		for _, name := range names {
+			pw.sample("dfs_cache_misses_total", fmt.Sprintf("segment=%q", name), segments[name].Misses)
+		}
+		pw.header("dfs_cache_evictions_total", "Entries evicted from the cache to stay in its budget.", "counter")
+		for _, name := range names {
+			pw.sample("dfs_cache_evictions_total", fmt.Sprintf("segment=%q", name), segments[name].Evictions)
+		}
+	}
+	return pw.err
+}
+
+// observe records a call and traces it in the log. Data which is not in the
+// store is counted apart from the errors, as the feed lookups look for
+// updates which are not there yet on every read.
+func (c *Client) observe(op string, address []byte, size int, start time.Time, err error) {
+	elapsed := time.Since(start)
+	m := c.ops[op]
+	m.mu.Lock()
+	m.stats.Requests++
+	if errors.Is(err, blockstore.ErrNotFound) {
+		m.stats.NotFound++
+	} else if err != nil {
+		m.stats.Errors++
+	}
+	m.stats.Bytes += uint64(size)
+	m.stats.Duration += elapsed
+	for i, bound := range Buckets {
+		if elapsed.Seconds() <= bound {
+			m.stats.Buckets[i]++
+		}
+	}
+	m.mu.Unlock()
+
+	fields := logrus.Fields{
+		"op":       op,
+		"size":     size,
+		"duration": elapsed.String(),
+	}
+	if address != nil {
+		fields["address"] = swarm.NewAddress(address).String()
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	if c.slowThreshold > 0 && elapsed > c.slowThreshold {
+		c.logger.WithFields(fields).Log(logrus.WarnLevel, "slow block store call")
+		return
+	}
+	c.logger.WithFields(fields).Log(logrus.TraceLevel, "block store call")
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+// countingReadSeeker is a countingReader over an io.Seeker. An upload seeks
+// only to go back to where it started for a retry, so the count starts over.
+type countingReadSeeker struct {
+	*countingReader
+	s io.Seeker
+}
+
+func (cr *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	cr.n = 0
+	return cr.s.Seek(offset, whence)
+}
+
+// countingReadCloser adds the bytes read from a downloaded body to its
+// operation.
+type countingReadCloser struct {
+	io.ReadCloser
+	m *opMetrics
+}
+
+func (cr *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	if n > 0 {
+		cr.m.mu.Lock()
+		cr.m.stats.Bytes += uint64(n)
+		cr.m.mu.Unlock()
+	}
+	return n, err
+}
+
+// promWriter writes samples in the Prometheus text format, keeping the first
+// error.
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (pw *promWriter) header(name, help, kind string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (pw *promWriter) sample(name, labels string, value interface{}) {
+	if labels != "" {
+		pw.printf("%s{%s} %v\n", name, labels, value)
+		return
+	}
+	pw.printf("%s %v\n", name, value)
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
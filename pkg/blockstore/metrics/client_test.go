@@ -0,0 +1,191 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/metrics"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+// cachingClient is a client which owns a cache, like the bee client.
+type cachingClient struct {
+	*mock.MockBeeClient
+	cache *cache.Cache
+}
+
+func (c *cachingClient) Cache() *cache.Cache {
+	return c.cache
+}
+
+func TestMetricsClient(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	inner := fault.New(mock.NewMockBeeClient(), 1)
+	client := metrics.New(inner, 0, logger)
+	data := []byte("data to measure")
+
+	t.Run("count-calls", func(t *testing.T) {
+		addr, err := client.UploadBlob(context.Background(), data, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = client.DownloadBlob(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _, err := client.DownloadBlobStream(context.Background(), addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats := client.Stats()
+		for _, op := range []string{metrics.UploadBlob, metrics.DownloadBlob, metrics.DownloadBlobStream} {
+			if stats[op].Requests != 1 || stats[op].Errors != 0 {
+				t.Fatalf("%s: unexpected stats %+v", op, stats[op])
+			}
+			if stats[op].Bytes != uint64(len(data)) {
+				t.Fatalf("%s: counted %d bytes", op, stats[op].Bytes)
+			}
+			// every call is faster than the largest bucket
+			if stats[op].Buckets[len(metrics.Buckets)-1] != 1 {
+				t.Fatalf("%s: call not in the histogram", op)
+			}
+		}
+	})
+
+	t.Run("count-errors", func(t *testing.T) {
+		defer inner.Reset()
+		inner.SetErrorRate(1, fault.UploadBlob)
+		_, err := client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err == nil {
+			t.Fatalf("upload did not fail")
+		}
+		_, err = client.DownloadChunk(context.Background(), make([]byte, 32))
+		if err == nil {
+			t.Fatalf("download of a missing chunk did not fail")
+		}
+
+		stats := client.Stats()
+		if stats[metrics.UploadBlobStream].Errors != 1 {
+			t.Fatalf("upload error not counted")
+		}
+		if stats[metrics.DownloadChunk].Errors != 0 || stats[metrics.DownloadChunk].NotFound != 1 {
+			t.Fatalf("missing chunk not counted as not found: %+v", stats[metrics.DownloadChunk])
+		}
+	})
+
+	t.Run("retry-seekable-upload", func(t *testing.T) {
+		var posts int32
+		beeServer := mock.NewBeeServer(mock.NewMockBeeClient())
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the first upload fails half way through
+			if r.Method == http.MethodPost && atomic.AddInt32(&posts, 1) == 1 {
+				_, _ = ioutil.ReadAll(r.Body)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			beeServer.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+		opts := bee.DefaultOptions()
+		opts.MaxRetries = 1
+		opts.RetryBackoff = time.Millisecond
+		beeClient, err := bee.NewBeeClientFromURL(server.URL, opts, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := metrics.New(beeClient, 0, logger)
+
+		_, err = client.UploadBlobStream(context.Background(), bytes.NewReader(data), false, false)
+		if err != nil {
+			t.Fatalf("upload through metrics not retried: %v", err)
+		}
+		if atomic.LoadInt32(&posts) != 2 {
+			t.Fatalf("expected 2 uploads, got %d", posts)
+		}
+		if stats := client.Stats()[metrics.UploadBlobStream]; stats.Bytes != uint64(len(data)) {
+			t.Fatalf("counted %d bytes of a retried upload", stats.Bytes)
+		}
+	})
+
+	t.Run("serve-metrics", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		client.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		body := rec.Body.String()
+		for _, line := range []string{
+			"# TYPE dfs_blockstore_requests_total counter",
+			`dfs_blockstore_requests_total{op="UploadBlob"} 1`,
+			`dfs_blockstore_errors_total{op="UploadBlobStream"} 1`,
+			`dfs_blockstore_not_found_total{op="DownloadChunk"} 1`,
+			`dfs_blockstore_bytes_total{op="DownloadBlob"} 15`,
+			`dfs_blockstore_request_duration_seconds_bucket{op="UploadBlob",le="+Inf"} 1`,
+			`dfs_blockstore_request_duration_seconds_count{op="UploadBlob"} 1`,
+		} {
+			if !strings.Contains(body, line+"\n") {
+				t.Fatalf("missing %q in\n%s", line, body)
+			}
+		}
+		if strings.Contains(body, "dfs_cache") {
+			t.Fatalf("cache metrics of a client without a cache")
+		}
+	})
+
+	t.Run("cache-metrics", func(t *testing.T) {
+		c := cache.New(1024)
+		segment := c.Segment("test")
+		segment.AddBytes("key", []byte("value"))
+		segment.GetBytes("key")
+		segment.GetBytes("missing")
+
+		client := metrics.New(&cachingClient{MockBeeClient: mock.NewMockBeeClient(), cache: c}, 0, logger)
+		if client.Cache() != c {
+			t.Fatalf("cache of the wrapped client not shared")
+		}
+		var buf bytes.Buffer
+		err := client.Write(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{
+			"dfs_cache_max_size_bytes 1024",
+			`dfs_cache_hits_total{segment="test"} 1`,
+			`dfs_cache_misses_total{segment="test"} 1`,
+			`dfs_cache_entries{segment="test"} 1`,
+		} {
+			if !strings.Contains(buf.String(), line+"\n") {
+				t.Fatalf("missing %q in\n%s", line, buf.String())
+			}
+		}
+	})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package blockstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	bmtlegacy "github.com/ethersphere/bmt/legacy"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrInvalidChunk = errors.New("invalid chunk data")
+)
+
+// Splitter builds the swarm chunk tree of a blob the same way Bee does for
+// its /bytes endpoint, so that the root address matches what Bee returns for
+// unencrypted uploads.
+type Splitter struct {
+	pool *bmtlegacy.TreePool
+}
+
+type levelRef struct {
+	span uint64
+	addr []byte
+}
+
+func hashFunc() hash.Hash {
+	return sha3.NewLegacyKeccak256()
+}
+
+func NewSplitter() *Splitter {
+	return &Splitter{
+		pool: bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize),
+	}
+}
+
+// ChunkAddress returns the BMT address of the given payload with the given span.
+func (s *Splitter) ChunkAddress(span uint64, payload []byte) (swarm.Address, error) {
+	hasher := bmtlegacy.New(s.pool)
+	hasher.Reset()
+	spanBytes := make([]byte, swarm.SpanSize)
+	binary.LittleEndian.PutUint64(spanBytes, span)
+	err := hasher.SetSpanBytes(spanBytes)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	_, err = hasher.Write(payload)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	return swarm.NewAddress(hasher.Sum(nil)), nil
+}
+
+// Split breaks the data in to content addressed chunks and returns the root
+// address of the chunk tree along with all the chunks (leaves and intermediates).
+func (s *Splitter) Split(data []byte) (swarm.Address, []swarm.Chunk, error) {
+	var chunks []swarm.Chunk
+	var level []levelRef
+
+	// leaf chunks
+	for cursor := 0; cursor < len(data) || cursor == 0; cursor += swarm.ChunkSize {
+		end := cursor + swarm.ChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		ch, err := s.newChunk(uint64(end-cursor), data[cursor:end])
+		if err != nil {
+			return swarm.ZeroAddress, nil, err
+		}
+		chunks = append(chunks, ch)
+		level = append(level, levelRef{span: uint64(end - cursor), addr: ch.Address().Bytes()})
+		if end == len(data) {
+			break
+		}
+	}
+
+	// intermediate chunks, till a single root remains
+	for len(level) > 1 {
+		var nextLevel []levelRef
+		for i := 0; i < len(level); i += swarm.Branches {
+			end := i + swarm.Branches
+			if end > len(level) {
+				end = len(level)
+			}
+
+			// a lonely reference at the end of a level is hoisted up as it is
+			if end-i == 1 {
+				nextLevel = append(nextLevel, level[i])
+				continue
+			}
+
+			var span uint64
+			payload := make([]byte, 0, (end-i)*swarm.HashSize)
+			for _, ref := range level[i:end] {
+				span += ref.span
+				payload = append(payload, ref.addr...)
+			}
+			ch, err := s.newChunk(span, payload)
+			if err != nil {
+				return swarm.ZeroAddress, nil, err
+			}
+			chunks = append(chunks, ch)
+			nextLevel = append(nextLevel, levelRef{span: span, addr: ch.Address().Bytes()})
+		}
+		level = nextLevel
+	}
+	return swarm.NewAddress(level[0].addr), chunks, nil
+}
+
+func (s *Splitter) newChunk(span uint64, payload []byte) (swarm.Chunk, error) {
+	addr, err := s.ChunkAddress(span, payload)
+	if err != nil {
+		return nil, err
+	}
+	chunkData := make([]byte, swarm.SpanSize+len(payload))
+	binary.LittleEndian.PutUint64(chunkData[:swarm.SpanSize], span)
+	copy(chunkData[swarm.SpanSize:], payload)
+	return swarm.NewChunk(addr, chunkData), nil
+}
+
+// Join reassembles the blob whose chunk tree is rooted at the given address.
+// getChunk is used to fetch the span prefixed data of every chunk in the tree.
+func Join(root []byte, getChunk func(address []byte) ([]byte, error)) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	_, err := JoinTo(root, getChunk, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// JoinTo is like Join, but writes the blob to w as the chunks are fetched
+// instead of holding all of it in memory.
+func JoinTo(root []byte, getChunk func(address []byte) ([]byte, error), w io.Writer) (int64, error) {
+	chunkData, err := getChunk(root)
+	if err != nil {
+		return 0, err
+	}
+	if len(chunkData) < swarm.SpanSize {
+		return 0, ErrInvalidChunk
+	}
+	span := binary.LittleEndian.Uint64(chunkData[:swarm.SpanSize])
+	payload := chunkData[swarm.SpanSize:]
+
+	// leaf chunk
+	if span <= swarm.ChunkSize {
+		if uint64(len(payload)) < span {
+			return 0, ErrInvalidChunk
+		}
+		n, err := w.Write(payload[:span])
+		return int64(n), err
+	}
+
+	// intermediate chunk
+	if len(payload)%swarm.HashSize != 0 {
+		return 0, ErrInvalidChunk
+	}
+	var written int64
+	for cursor := 0; cursor < len(payload); cursor += swarm.HashSize {
+		n, err := JoinTo(payload[cursor:cursor+swarm.HashSize], getChunk, w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if uint64(written) > span {
+			return written, ErrInvalidChunk
+		}
+	}
+	if uint64(written) != span {
+		return written, ErrInvalidChunk
+	}
+	return written, nil
+}
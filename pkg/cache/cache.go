@@ -0,0 +1,230 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+const (
+	DefaultSize = 256 * 1024 * 1024
+)
+
+// Provider is implemented by the blockstore clients which own a Cache, so
+// that the layers above them can cache in the same budget.
+type Provider interface {
+	Cache() *Cache
+}
+
+// Cache is an in memory LRU cache whose limit is the total size in bytes of
+// what it holds rather than the number of entries. A Cache is split in to
+// named segments, one for every kind of data cached, so that many users can
+// share the same budget while keeping their keys and counters apart.
+type Cache struct {
+	maxSize  int64
+	size     int64
+	ll       *list.List // front is the most recently used
+	items    map[string]*list.Element
+	segments map[string]*Segment
+	mu       sync.Mutex
+}
+
+// Segment is a named part of a Cache with its own keys and counters.
+type Segment struct {
+	name      string
+	cache     *Cache
+	entries   int
+	size      int64
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// Stats are the counters of a segment.
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Size      int64  `json:"size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type entry struct {
+	key     string
+	value   interface{}
+	size    int64
+	segment *Segment
+}
+
+// New creates a cache which holds at most maxSize bytes.
+func New(maxSize int64) *Cache {
+	return &Cache{
+		maxSize:  maxSize,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		segments: make(map[string]*Segment),
+	}
+}
+
+// Segment returns the segment with the given name, creating it if needed.
+func (c *Cache) Segment(name string) *Segment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.segments[name]
+	if !ok {
+		s = &Segment{name: name, cache: c}
+		c.segments[name] = s
+	}
+	return s
+}
+
+// MaxSize returns the budget of the cache in bytes.
+func (c *Cache) MaxSize() int64 {
+	return c.maxSize
+}
+
+// Size returns the number of bytes held in the cache.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Stats returns the counters of all the segments, keyed by segment name.
+func (c *Cache) Stats() map[string]Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make(map[string]Stats, len(c.segments))
+	for name, s := range c.segments {
+		stats[name] = s.stats()
+	}
+	return stats
+}
+
+// Name returns the name of the segment.
+func (s *Segment) Name() string {
+	return s.name
+}
+
+// Add stores the value under the key, replacing any earlier value. size is
+// the number of bytes the value is accounted for. Values larger than the
+// whole cache are not stored and false is returned.
+func (s *Segment) Add(key string, value interface{}, size int64) bool {
+	c := s.cache
+	size += int64(len(key))
+	if size > c.maxSize {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	k := s.key(key)
+	if elem, ok := c.items[k]; ok {
+		c.remove(elem)
+	}
+	c.items[k] = c.ll.PushFront(&entry{key: k, value: value, size: size, segment: s})
+	c.size += size
+	s.entries++
+	s.size += size
+	for c.size > c.maxSize {
+		elem := c.ll.Back()
+		c.remove(elem)
+		elem.Value.(*entry).segment.evictions++
+	}
+	return true
+}
+
+// Get returns the value stored under the key and marks it as recently used.
+func (s *Segment) Get(key string) (interface{}, bool) {
+	c := s.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[s.key(key)]
+	if !ok {
+		s.misses++
+		return nil, false
+	}
+	s.hits++
+	c.ll.MoveToFront(elem)
+	return elem.Value.(*entry).value, true
+}
+
+// GetBytes is Get for segments which hold byte slices.
+func (s *Segment) GetBytes(key string) ([]byte, bool) {
+	value, ok := s.Get(key)
+	if !ok {
+		return nil, false
+	}
+	data, ok := value.([]byte)
+	return data, ok
+}
+
+// AddBytes is Add for segments which hold byte slices.
+func (s *Segment) AddBytes(key string, value []byte) bool {
+	return s.Add(key, value, int64(len(value)))
+}
+
+// Contains reports if the key is in the segment, without touching the
+// counters or the order of use.
+func (s *Segment) Contains(key string) bool {
+	c := s.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[s.key(key)]
+	return ok
+}
+
+// Remove drops the key from the segment.
+func (s *Segment) Remove(key string) {
+	c := s.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[s.key(key)]; ok {
+		c.remove(elem)
+	}
+}
+
+// Stats returns the counters of the segment.
+func (s *Segment) Stats() Stats {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+	return s.stats()
+}
+
+func (s *Segment) stats() Stats {
+	return Stats{
+		Entries:   s.entries,
+		Size:      s.size,
+		Hits:      s.hits,
+		Misses:    s.misses,
+		Evictions: s.evictions,
+	}
+}
+
+func (s *Segment) key(key string) string {
+	return s.name + "/" + key
+}
+
+// remove drops an entry. The caller must hold the lock.
+func (c *Cache) remove(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.ll.Remove(elem)
+	delete(c.items, e.key)
+	c.size -= e.size
+	e.segment.entries--
+	e.segment.size -= e.size
+}
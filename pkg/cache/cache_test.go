@@ -0,0 +1,117 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("byte-budget", func(t *testing.T) {
+		c := cache.New(100)
+		s := c.Segment("blocks")
+		for _, key := range []string{"a", "b", "c"} {
+			if !s.AddBytes(key, make([]byte, 29)) {
+				t.Fatalf("could not add %s", key)
+			}
+		}
+		if c.Size() != 90 {
+			t.Fatalf("expected size 90, got %d", c.Size())
+		}
+
+		// use a, so b is the least recently used
+		if _, ok := s.GetBytes("a"); !ok {
+			t.Fatalf("a not found")
+		}
+		s.AddBytes("d", make([]byte, 29))
+		if c.Size() > c.MaxSize() {
+			t.Fatalf("cache over budget %d", c.Size())
+		}
+		if s.Contains("b") {
+			t.Fatalf("least recently used entry not evicted")
+		}
+		for _, key := range []string{"a", "c", "d"} {
+			if !s.Contains(key) {
+				t.Fatalf("%s evicted", key)
+			}
+		}
+		stats := s.Stats()
+		if stats.Evictions != 1 || stats.Entries != 3 || stats.Size != c.Size() {
+			t.Fatalf("invalid stats %+v", stats)
+		}
+	})
+
+	t.Run("too-large", func(t *testing.T) {
+		s := cache.New(10).Segment("blocks")
+		if s.AddBytes("big", make([]byte, 10)) {
+			t.Fatalf("value larger than the cache added")
+		}
+		if s.Stats().Entries != 0 {
+			t.Fatalf("value larger than the cache stored")
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		c := cache.New(100)
+		s := c.Segment("blocks")
+		s.AddBytes("a", []byte("first"))
+		s.AddBytes("a", []byte("second value"))
+		data, ok := s.GetBytes("a")
+		if !ok || !bytes.Equal(data, []byte("second value")) {
+			t.Fatalf("value not replaced")
+		}
+		if c.Size() != int64(len("a")+len("second value")) {
+			t.Fatalf("invalid size %d", c.Size())
+		}
+	})
+
+	t.Run("hits-and-misses", func(t *testing.T) {
+		s := cache.New(100).Segment("blocks")
+		s.AddBytes("a", []byte("data"))
+		s.GetBytes("a")
+		s.GetBytes("a")
+		s.GetBytes("b")
+		stats := s.Stats()
+		if stats.Hits != 2 || stats.Misses != 1 {
+			t.Fatalf("invalid stats %+v", stats)
+		}
+	})
+
+	t.Run("shared-budget", func(t *testing.T) {
+		c := cache.New(100)
+		blocks := c.Segment("blocks")
+		feeds := c.Segment("feeds")
+		if c.Segment("blocks") != blocks {
+			t.Fatalf("segment not reused")
+		}
+		blocks.AddBytes("a", make([]byte, 59))
+		if feeds.Contains("a") {
+			t.Fatalf("key visible in another segment")
+		}
+		feeds.AddBytes("a", make([]byte, 59))
+		if blocks.Contains("a") {
+			t.Fatalf("older entry of another segment not evicted")
+		}
+		stats := c.Stats()
+		if stats["blocks"].Evictions != 1 || stats["feeds"].Entries != 1 {
+			t.Fatalf("invalid stats %+v", stats)
+		}
+	})
+}
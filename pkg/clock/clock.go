@@ -0,0 +1,72 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. The feeds and the metadata of the pods, directories
+// and files take their timestamps from the one they are given, so that the
+// time can be set in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the system.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Mock is a clock that only moves when it is set or advanced.
+type Mock struct {
+	now time.Time
+	mu  sync.Mutex
+}
+
+// NewMock creates a clock stopped at now.
+func NewMock(now time.Time) *Mock {
+	return &Mock{
+		now: now,
+	}
+}
+
+// Now returns the time the clock is at.
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set moves the clock to t.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = t
+}
+
+// Add advances the clock by d.
+func (m *Mock) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
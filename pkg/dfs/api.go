@@ -17,36 +17,53 @@ limitations under the License.
 package dfs
 
 import (
+	"context"
+	"encoding/hex"
 	"io"
 	"net/http"
+	"path/filepath"
+	"time"
 
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
-	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee"
 	"github.com/jmozah/intOS-dfs/pkg/dir"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/file"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
 	"github.com/jmozah/intOS-dfs/pkg/pod"
 	"github.com/jmozah/intOS-dfs/pkg/user"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
+const (
+	uploadsDirectoryName = "uploads"
+)
+
 type DfsAPI struct {
 	dataDir string
 	client  blockstore.Client
 	users   *user.Users
+	uploads *file.Uploads
 	logger  logging.Logger
 }
 
-func NewDfsAPI(dataDir, host, port string, logger logging.Logger) (*DfsAPI, error) {
-	c := bee.NewBeeClient(host, port, logger)
+// NewDfsAPI creates the API of the dfs over the block store c. The sequence
+// feeds of the users, like the ones of their directories, are written in
+// feedFormat.
+func NewDfsAPI(dataDir string, c blockstore.Client, feedFormat feed.Format, logger logging.Logger) (*DfsAPI, error) {
 	if !c.CheckConnection() {
 		return nil, ErrBeeClient
 	}
-	users := user.NewUsers(dataDir, c, logger)
+	users := user.NewUsers(dataDir, c, feedFormat, logger)
+	uploads, err := file.NewUploads(filepath.Join(dataDir, uploadsDirectoryName), logger)
+	if err != nil {
+		return nil, err
+	}
 	return &DfsAPI{
 		dataDir: dataDir,
 		client:  c,
 		users:   users,
+		uploads: uploads,
 		logger:  logger,
 	}, nil
 }
@@ -54,34 +71,34 @@ func NewDfsAPI(dataDir, host, port string, logger logging.Logger) (*DfsAPI, erro
 //
 //  User related APIs
 //
-func (d *DfsAPI) CreateUser(userName, passPhrase, mnemonic string, response http.ResponseWriter, sessionId string) (string, string, error) {
+func (d *DfsAPI) CreateUser(ctx context.Context, userName, passPhrase, mnemonic string, response http.ResponseWriter, sessionId string) (string, string, error) {
 	if !d.client.CheckConnection() {
 		return "", "", ErrBeeClient
 	}
 
-	reference, rcvdMnemonic, userInfo, err := d.users.CreateNewUser(userName, passPhrase, mnemonic, d.dataDir, d.client, response, sessionId)
+	reference, rcvdMnemonic, userInfo, err := d.users.CreateNewUser(ctx, userName, passPhrase, mnemonic, d.dataDir, d.client, response, sessionId)
 	if err != nil {
 		return reference, rcvdMnemonic, err
 	}
 
-	err = d.users.CreateRootFeeds(userInfo)
+	err = d.users.CreateRootFeeds(ctx, userInfo)
 	if err != nil {
 		return reference, rcvdMnemonic, err
 	}
 	return reference, rcvdMnemonic, nil
 }
 
-func (d *DfsAPI) ImportUserUsingMnemonic(userName, passPhrase, mnemonic string, response http.ResponseWriter, sessionId string) (string, error) {
-	reference, _, err := d.CreateUser(userName, passPhrase, mnemonic, response, sessionId)
+func (d *DfsAPI) ImportUserUsingMnemonic(ctx context.Context, userName, passPhrase, mnemonic string, response http.ResponseWriter, sessionId string) (string, error) {
+	reference, _, err := d.CreateUser(ctx, userName, passPhrase, mnemonic, response, sessionId)
 	return reference, err
 }
 
-func (d *DfsAPI) ImportUserUsingAddress(userName, passPhrase, address string, response http.ResponseWriter, sessionId string) error {
-	return d.users.ImportUsingAddress(userName, passPhrase, address, d.dataDir, d.client, response, sessionId)
+func (d *DfsAPI) ImportUserUsingAddress(ctx context.Context, userName, passPhrase, address string, response http.ResponseWriter, sessionId string) error {
+	return d.users.ImportUsingAddress(ctx, userName, passPhrase, address, d.dataDir, d.client, response, sessionId)
 }
 
-func (d *DfsAPI) LoginUser(userName, passPhrase string, response http.ResponseWriter, sessionId string) error {
-	return d.users.LoginUser(userName, passPhrase, d.dataDir, d.client, response, sessionId)
+func (d *DfsAPI) LoginUser(ctx context.Context, userName, passPhrase string, response http.ResponseWriter, sessionId string) error {
+	return d.users.LoginUser(ctx, userName, passPhrase, d.dataDir, d.client, response, sessionId)
 }
 
 func (d *DfsAPI) LogoutUser(sessionId string, response http.ResponseWriter) error {
@@ -94,14 +111,14 @@ func (d *DfsAPI) LogoutUser(sessionId string, response http.ResponseWriter) erro
 	return d.users.LogoutUser(ui.GetUserName(), d.dataDir, sessionId, response)
 }
 
-func (d *DfsAPI) DeleteUser(passPhrase, sessionId string, response http.ResponseWriter) error {
+func (d *DfsAPI) DeleteUser(ctx context.Context, passPhrase, sessionId string, response http.ResponseWriter) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return ErrUserNotLoggedIn
 	}
 
-	return d.users.DeleteUser(ui.GetUserName(), d.dataDir, passPhrase, sessionId, response, ui)
+	return d.users.DeleteUser(ctx, ui.GetUserName(), d.dataDir, passPhrase, sessionId, response, ui)
 }
 
 func (d *DfsAPI) IsUserNameAvailable(userName string) bool {
@@ -117,60 +134,60 @@ func (d *DfsAPI) ListAllUsers() []string {
 	return d.users.ListAllUsers(d.dataDir)
 }
 
-func (d *DfsAPI) SaveAvatar(sessionId string, data []byte) error {
+func (d *DfsAPI) SaveAvatar(ctx context.Context, sessionId string, data []byte) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return ErrUserNotLoggedIn
 	}
 
-	return d.users.SaveAvatar(data, ui)
+	return d.users.SaveAvatar(ctx, data, ui)
 }
 
-func (d *DfsAPI) GetAvatar(sessionId string) ([]byte, error) {
+func (d *DfsAPI) GetAvatar(ctx context.Context, sessionId string) ([]byte, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return nil, ErrUserNotLoggedIn
 	}
 
-	return d.users.GetAvatar(ui)
+	return d.users.GetAvatar(ctx, ui)
 }
 
-func (d *DfsAPI) SaveName(firstName, lastName, middleName, surname, sessionId string) error {
+func (d *DfsAPI) SaveName(ctx context.Context, firstName, lastName, middleName, surname, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return ErrUserNotLoggedIn
 	}
-	return d.users.SaveName(firstName, lastName, middleName, surname, ui)
+	return d.users.SaveName(ctx, firstName, lastName, middleName, surname, ui)
 }
 
-func (d *DfsAPI) GetName(sessionId string) (*user.Name, error) {
+func (d *DfsAPI) GetName(ctx context.Context, sessionId string) (*user.Name, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return nil, ErrUserNotLoggedIn
 	}
-	return d.users.GetName(ui)
+	return d.users.GetName(ctx, ui)
 }
 
-func (d *DfsAPI) SaveContact(phone, mobile string, address *user.Address, sessionId string) error {
+func (d *DfsAPI) SaveContact(ctx context.Context, phone, mobile string, address *user.Address, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return ErrUserNotLoggedIn
 	}
-	return d.users.SaveContacts(phone, mobile, address, ui)
+	return d.users.SaveContacts(ctx, phone, mobile, address, ui)
 }
 
-func (d *DfsAPI) GetContact(sessionId string) (*user.Contacts, error) {
+func (d *DfsAPI) GetContact(ctx context.Context, sessionId string) (*user.Contacts, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return nil, ErrUserNotLoggedIn
 	}
-	return d.users.GetContacts(ui)
+	return d.users.GetContacts(ctx, ui)
 }
 
 func (d *DfsAPI) GetUserStat(sessionId string) (*user.Stat, error) {
@@ -182,22 +199,22 @@ func (d *DfsAPI) GetUserStat(sessionId string) (*user.Stat, error) {
 	return d.users.GetUserStat(ui)
 }
 
-func (d *DfsAPI) GetUserSharingInbox(sessionId string) (*user.Inbox, error) {
+func (d *DfsAPI) GetUserSharingInbox(ctx context.Context, sessionId string) (*user.Inbox, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return nil, ErrUserNotLoggedIn
 	}
-	return d.users.GetSharingInbox(ui)
+	return d.users.GetSharingInbox(ctx, ui)
 }
 
-func (d *DfsAPI) GetUserSharingOutbox(sessionId string) (*user.Outbox, error) {
+func (d *DfsAPI) GetUserSharingOutbox(ctx context.Context, sessionId string) (*user.Outbox, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
 		return nil, ErrUserNotLoggedIn
 	}
-	return d.users.GetSharingOutbox(ui)
+	return d.users.GetSharingOutbox(ctx, ui)
 }
 
 func (d *DfsAPI) ExportUser(sessionId string) (string, string, error) {
@@ -212,7 +229,7 @@ func (d *DfsAPI) ExportUser(sessionId string) (string, string, error) {
 //
 //  Pods related APIs
 //
-func (d *DfsAPI) CreatePod(podName, passPhrase, sessionId string) (*pod.Info, error) {
+func (d *DfsAPI) CreatePod(ctx context.Context, podName, passPhrase, sessionId string) (*pod.Info, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -220,13 +237,13 @@ func (d *DfsAPI) CreatePod(podName, passPhrase, sessionId string) (*pod.Info, er
 	}
 
 	// create the pod
-	pi, err := ui.GetPod().CreatePod(podName, passPhrase)
+	pi, err := ui.GetPod().CreatePod(ctx, podName, passPhrase)
 	if err != nil {
 		return nil, err
 	}
 
 	// open the pod
-	_, err = ui.GetPod().OpenPod(podName, passPhrase)
+	_, err = ui.GetPod().OpenPod(ctx, podName, passPhrase)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +253,7 @@ func (d *DfsAPI) CreatePod(podName, passPhrase, sessionId string) (*pod.Info, er
 	return pi, nil
 }
 
-func (d *DfsAPI) DeletePod(podName, sessionId string) error {
+func (d *DfsAPI) DeletePod(ctx context.Context, podName, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -244,7 +261,7 @@ func (d *DfsAPI) DeletePod(podName, sessionId string) error {
 	}
 
 	// delete the pod
-	err := ui.GetPod().DeletePod(podName)
+	err := ui.GetPod().DeletePod(ctx, podName)
 	if err != nil {
 		return err
 	}
@@ -264,7 +281,7 @@ func (d *DfsAPI) DeletePod(podName, sessionId string) error {
 	return nil
 }
 
-func (d *DfsAPI) OpenPod(podName, passPhrase, sessionId string) (*pod.Info, error) {
+func (d *DfsAPI) OpenPod(ctx context.Context, podName, passPhrase, sessionId string) (*pod.Info, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -280,7 +297,7 @@ func (d *DfsAPI) OpenPod(podName, passPhrase, sessionId string) (*pod.Info, erro
 	}
 
 	// open the pod
-	po, err := ui.GetPod().OpenPod(podName, passPhrase)
+	po, err := ui.GetPod().OpenPod(ctx, podName, passPhrase)
 	if err != nil {
 		return nil, err
 	}
@@ -328,7 +345,7 @@ func (d *DfsAPI) PodStat(podName, sessionId string) (*pod.PodStat, error) {
 	return podStat, nil
 }
 
-func (d *DfsAPI) SyncPod(sessionId string) error {
+func (d *DfsAPI) SyncPod(ctx context.Context, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -341,14 +358,46 @@ func (d *DfsAPI) SyncPod(sessionId string) error {
 	}
 
 	// sync the pod
-	err := ui.GetPod().SyncPod(ui.GetPodName())
+	err := ui.GetPod().SyncPod(ctx, ui.GetPodName())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) ListPods(sessionId string) ([]string, error) {
+func (d *DfsAPI) CollectPodGarbage(ctx context.Context, sessionId string) (*pod.GCStats, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	// unpin what is not reachable from the pod
+	return ui.GetPod().CollectGarbage(ctx, ui.GetPodName())
+}
+
+func (d *DfsAPI) MigratePodToBeeFeeds(ctx context.Context, sessionId string) (int, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return 0, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return 0, ErrPodNotOpen
+	}
+
+	// rewrite the directory feeds of the pod in the format of bee
+	return ui.GetPod().MigrateToBeeFeeds(ctx, ui.GetPodName())
+}
+
+func (d *DfsAPI) ListPods(ctx context.Context, sessionId string) ([]string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -356,7 +405,7 @@ func (d *DfsAPI) ListPods(sessionId string) ([]string, error) {
 	}
 
 	// list pods of a user
-	pods, err := ui.GetPod().ListPods()
+	pods, err := ui.GetPod().ListPods(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -367,7 +416,7 @@ func (d *DfsAPI) ListPods(sessionId string) ([]string, error) {
 //  Directory related APIs
 //
 
-func (d *DfsAPI) Mkdir(directoryName, sessionId string) error {
+func (d *DfsAPI) Mkdir(ctx context.Context, directoryName, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -380,14 +429,14 @@ func (d *DfsAPI) Mkdir(directoryName, sessionId string) error {
 	}
 
 	// make dir
-	err := ui.GetPod().MakeDir(ui.GetPodName(), directoryName)
+	err := ui.GetPod().MakeDir(ctx, ui.GetPodName(), directoryName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) RmDir(directoryName, sessionId string) error {
+func (d *DfsAPI) RmDir(ctx context.Context, directoryName, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -399,14 +448,14 @@ func (d *DfsAPI) RmDir(directoryName, sessionId string) error {
 		return ErrPodNotOpen
 	}
 
-	err := ui.GetPod().RemoveDir(ui.GetPodName(), directoryName)
+	err := ui.GetPod().RemoveDir(ctx, ui.GetPodName(), directoryName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) ListDir(currentDir, sessionId string) ([]dir.DirOrFileEntry, error) {
+func (d *DfsAPI) ListDir(ctx context.Context, currentDir, sessionId string) ([]dir.DirOrFileEntry, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -418,14 +467,14 @@ func (d *DfsAPI) ListDir(currentDir, sessionId string) ([]dir.DirOrFileEntry, er
 		return nil, ErrPodNotOpen
 	}
 
-	entries, err := ui.GetPod().ListEntiesInDir(ui.GetPodName(), currentDir)
+	entries, err := ui.GetPod().ListEntiesInDir(ctx, ui.GetPodName(), currentDir)
 	if err != nil {
 		return nil, err
 	}
 	return entries, nil
 }
 
-func (d *DfsAPI) DirectoryStat(directoryName, sessionId string, printNames bool) (*dir.DirStats, error) {
+func (d *DfsAPI) DirectoryStat(ctx context.Context, directoryName, sessionId string, printNames bool) (*dir.DirStats, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -437,14 +486,14 @@ func (d *DfsAPI) DirectoryStat(directoryName, sessionId string, printNames bool)
 		return nil, ErrPodNotOpen
 	}
 
-	ds, err := ui.GetPod().DirectoryStat(ui.GetPodName(), directoryName, printNames)
+	ds, err := ui.GetPod().DirectoryStat(ctx, ui.GetPodName(), directoryName, printNames)
 	if err != nil {
 		return nil, err
 	}
 	return ds, nil
 }
 
-func (d *DfsAPI) ChangeDirectory(directoryName, sessionId string) (*pod.Info, error) {
+func (d *DfsAPI) ChangeDirectory(ctx context.Context, directoryName, sessionId string) (*pod.Info, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -456,7 +505,7 @@ func (d *DfsAPI) ChangeDirectory(directoryName, sessionId string) (*pod.Info, er
 		return nil, ErrPodNotOpen
 	}
 
-	podInfo, err := ui.GetPod().ChangeDir(ui.GetPodName(), directoryName)
+	podInfo, err := ui.GetPod().ChangeDir(ctx, ui.GetPodName(), directoryName)
 	if err != nil {
 		return nil, err
 	}
@@ -466,7 +515,7 @@ func (d *DfsAPI) ChangeDirectory(directoryName, sessionId string) (*pod.Info, er
 //
 // File related API's
 //
-func (d *DfsAPI) CopyToLocal(localDir, podFile, sessionId string) error {
+func (d *DfsAPI) CopyToLocal(ctx context.Context, localDir, podFile, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -478,14 +527,14 @@ func (d *DfsAPI) CopyToLocal(localDir, podFile, sessionId string) error {
 		return ErrPodNotOpen
 	}
 
-	err := ui.GetPod().CopyToLocal(ui.GetPodName(), localDir, podFile)
+	err := ui.GetPod().CopyToLocal(ctx, ui.GetPodName(), localDir, podFile)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) Cat(fileName, sessionId string) error {
+func (d *DfsAPI) Cat(ctx context.Context, fileName, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -497,14 +546,14 @@ func (d *DfsAPI) Cat(fileName, sessionId string) error {
 		return ErrPodNotOpen
 	}
 
-	err := ui.GetPod().Cat(ui.GetPodName(), fileName)
+	err := ui.GetPod().Cat(ctx, ui.GetPodName(), fileName)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) DeleteFile(podFile, sessionId string) error {
+func (d *DfsAPI) DeleteFile(ctx context.Context, podFile, sessionId string) error {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -516,14 +565,119 @@ func (d *DfsAPI) DeleteFile(podFile, sessionId string) error {
 		return ErrPodNotOpen
 	}
 
-	err := ui.GetPod().RemoveFile(ui.GetPodName(), podFile)
+	err := ui.GetPod().RemoveFile(ctx, ui.GetPodName(), podFile)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *DfsAPI) FileStat(fileName, sessionId string) (*file.FileStats, error) {
+func (d *DfsAPI) WriteFile(ctx context.Context, podFile string, data []byte, offset uint64, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().WriteFile(ctx, ui.GetPodName(), podFile, data, offset)
+}
+
+func (d *DfsAPI) AppendFile(ctx context.Context, podFile string, data []byte, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().AppendFile(ctx, ui.GetPodName(), podFile, data)
+}
+
+func (d *DfsAPI) TruncateFile(ctx context.Context, podFile string, size uint64, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().TruncateFile(ctx, ui.GetPodName(), podFile, size)
+}
+
+func (d *DfsAPI) ListFileVersions(podFile, sessionId string) ([]m.FileVersion, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	return ui.GetPod().ListFileVersions(ui.GetPodName(), podFile)
+}
+
+func (d *DfsAPI) DownloadFileVersion(ctx context.Context, podFile, version, sessionId string) (*file.Reader, string, string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, "", "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, "", "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().DownloadFileVersion(ctx, ui.GetPodName(), podFile, version)
+}
+
+func (d *DfsAPI) RestoreFileVersion(ctx context.Context, podFile, version, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().RestoreFileVersion(ctx, ui.GetPodName(), podFile, version)
+}
+
+func (d *DfsAPI) PruneFileVersions(ctx context.Context, podFile string, maxAge time.Duration, maxCount int, sessionId string) (string, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return "", ErrPodNotOpen
+	}
+
+	return ui.GetPod().PruneFileVersions(ctx, ui.GetPodName(), podFile, maxAge, maxCount)
+}
+
+func (d *DfsAPI) FileStat(ctx context.Context, fileName, sessionId string) (*file.FileStats, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -535,14 +689,14 @@ func (d *DfsAPI) FileStat(fileName, sessionId string) (*file.FileStats, error) {
 		return nil, ErrPodNotOpen
 	}
 
-	ds, err := ui.GetPod().FileStat(ui.GetPodName(), fileName)
+	ds, err := ui.GetPod().FileStat(ctx, ui.GetPodName(), fileName)
 	if err != nil {
 		return nil, err
 	}
 	return ds, nil
 }
 
-func (d *DfsAPI) UploadFile(fileName, sessionId string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
+func (d *DfsAPI) UploadFile(ctx context.Context, fileName, sessionId string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -554,14 +708,14 @@ func (d *DfsAPI) UploadFile(fileName, sessionId string, fileSize int64, fd io.Re
 		return "", ErrPodNotOpen
 	}
 
-	ref, err := ui.GetPod().UploadFile(ui.GetPodName(), fileName, fileSize, fd, podDir, blockSize, compression)
+	ref, err := ui.GetPod().UploadFile(ctx, ui.GetPodName(), fileName, fileSize, fd, podDir, blockSize, compression)
 	if err != nil {
 		return "", err
 	}
 	return ref, nil
 }
 
-func (d *DfsAPI) DownloadFile(podFile, sessionId string) (io.ReadCloser, string, string, error) {
+func (d *DfsAPI) DownloadFile(ctx context.Context, podFile, sessionId string) (*file.Reader, string, string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -573,14 +727,14 @@ func (d *DfsAPI) DownloadFile(podFile, sessionId string) (io.ReadCloser, string,
 		return nil, "", "", ErrPodNotOpen
 	}
 
-	reader, ref, size, err := ui.GetPod().DownloadFile(ui.GetPodName(), podFile)
+	reader, ref, size, err := ui.GetPod().DownloadFile(ctx, ui.GetPodName(), podFile)
 	if err != nil {
 		return nil, "", "", err
 	}
 	return reader, ref, size, nil
 }
 
-func (d *DfsAPI) ShareFile(podFile, destinationUser, sessionId string) (string, error) {
+func (d *DfsAPI) ShareFile(ctx context.Context, podFile, destinationUser, sessionId string) (string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -592,14 +746,14 @@ func (d *DfsAPI) ShareFile(podFile, destinationUser, sessionId string) (string,
 		return "", ErrPodNotOpen
 	}
 
-	sharingRef, err := d.users.ShareFileWithUser(ui.GetPodName(), podFile, destinationUser, ui, ui.GetPod())
+	sharingRef, err := d.users.ShareFileWithUser(ctx, ui.GetPodName(), podFile, destinationUser, ui, ui.GetPod())
 	if err != nil {
 		return "", err
 	}
 	return sharingRef, nil
 }
 
-func (d *DfsAPI) ReceiveFile(sessionId string, sharingRef utils.SharingReference, dir string) (string, string, error) {
+func (d *DfsAPI) ReceiveFile(ctx context.Context, sessionId string, sharingRef utils.SharingReference, dir string) (string, string, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -611,10 +765,10 @@ func (d *DfsAPI) ReceiveFile(sessionId string, sharingRef utils.SharingReference
 		return "", "", ErrPodNotOpen
 	}
 
-	return d.users.ReceiveFileFromUser(ui.GetPodName(), sharingRef, ui, ui.GetPod(), dir)
+	return d.users.ReceiveFileFromUser(ctx, ui.GetPodName(), sharingRef, ui, ui.GetPod(), dir)
 }
 
-func (d *DfsAPI) ReceiveInfo(sessionId string, sharingRef utils.SharingReference) (*user.ReceiveFileInfo, error) {
+func (d *DfsAPI) ReceiveInfo(ctx context.Context, sessionId string, sharingRef utils.SharingReference) (*user.ReceiveFileInfo, error) {
 	// get the logged in user information
 	ui := d.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -626,5 +780,169 @@ func (d *DfsAPI) ReceiveInfo(sessionId string, sharingRef utils.SharingReference
 		return nil, ErrPodNotOpen
 	}
 
-	return d.users.ReceiveFileInfo(ui.GetPodName(), sharingRef, ui, ui.GetPod())
+	return d.users.ReceiveFileInfo(ctx, ui.GetPodName(), sharingRef, ui, ui.GetPod())
+}
+
+//
+//  Resumable upload related APIs
+//
+func (d *DfsAPI) OpenUpload(ctx context.Context, sessionId, fileName string, fileSize int64, podDir, blockSize, compression string) (*file.UploadSession, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	return ui.GetPod().OpenUpload(ctx, d.uploads, ui.GetUserName(), ui.GetPodName(), fileName, fileSize, podDir, blockSize, compression)
+}
+
+func (d *DfsAPI) UploadBlocks(ctx context.Context, sessionId, uploadId string, first int, r io.Reader) (int, error) {
+	ui, err := d.getUploadUser(sessionId, uploadId)
+	if err != nil {
+		return 0, err
+	}
+	return ui.GetPod().UploadBlocks(ctx, d.uploads, ui.GetPodName(), uploadId, first, r)
+}
+
+func (d *DfsAPI) UploadStatus(sessionId, uploadId string) (*file.UploadSession, error) {
+	ui, err := d.getUploadUser(sessionId, uploadId)
+	if err != nil {
+		return nil, err
+	}
+	return ui.GetPod().GetUpload(d.uploads, ui.GetPodName(), uploadId)
+}
+
+func (d *DfsAPI) CommitUpload(ctx context.Context, sessionId, uploadId string) (string, error) {
+	ui, err := d.getUploadUser(sessionId, uploadId)
+	if err != nil {
+		return "", err
+	}
+	return ui.GetPod().CommitUpload(ctx, d.uploads, ui.GetPodName(), uploadId)
+}
+
+func (d *DfsAPI) AbortUpload(ctx context.Context, sessionId, uploadId string) error {
+	ui, err := d.getUploadUser(sessionId, uploadId)
+	if err != nil {
+		return err
+	}
+	return ui.GetPod().AbortUpload(ctx, d.uploads, ui.GetPodName(), uploadId)
+}
+
+// getUploadUser returns the logged in user of the session, if the upload is
+// one of theirs to the pod they have open.
+func (d *DfsAPI) getUploadUser(sessionId, uploadId string) (*user.Info, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" {
+		return nil, ErrPodNotOpen
+	}
+
+	upload, err := ui.GetPod().GetUpload(d.uploads, ui.GetPodName(), uploadId)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserName != ui.GetUserName() {
+		return nil, file.ErrUploadNotFound
+	}
+	return ui, nil
+}
+
+//
+//  Watch related APIs
+//
+
+// The kinds of watch events
+const (
+	WatchInbox = "inbox" // the sharing inbox of the user changed
+	WatchPods  = "pods"  // the list of pods of the user changed
+	WatchDir   = "dir"   // a directory of the open pod changed
+)
+
+// WatchEvent tells a logged in user what changed in what they watch.
+type WatchEvent struct {
+	Kind      string `json:"kind"`
+	Path      string `json:"path,omitempty"`
+	Reference string `json:"reference"`
+}
+
+// Watch sends an event on the returned channel whenever the sharing inbox of
+// the user, the list of pods or one of the given directories of the open pod
+// changes, every interval at most, until ctx is done. The root of the open pod
+// is watched if no directories are given, nothing in a pod if none is open.
+func (d *DfsAPI) Watch(ctx context.Context, sessionId string, dirs []string, interval time.Duration) (<-chan *WatchEvent, error) {
+	// get the logged in user information
+	ui := d.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	// check if pod open
+	if ui.GetPodName() == "" && len(dirs) > 0 {
+		return nil, ErrPodNotOpen
+	}
+	if ui.GetPodName() != "" && len(dirs) == 0 {
+		dirs = []string{utils.PathSeperator}
+	}
+
+	// the feeds are only read, so the feeds of the pod can be read through
+	// the API of the user too
+	watcher := ui.GetFeed().NewWatcher(interval)
+	watched := make(map[string]WatchEvent) // topic -> what it is
+	watch := func(kind, path string, topic []byte, user utils.Address) error {
+		watched[hex.EncodeToString(topic)] = WatchEvent{Kind: kind, Path: path}
+		return watcher.Watch(ctx, topic, user)
+	}
+	topic, user := d.users.InboxFeed(ui)
+	err := watch(WatchInbox, "", topic, user)
+	if err == nil {
+		topic, user = ui.GetPod().PodsFeed()
+		err = watch(WatchPods, "", topic, user)
+	}
+	for _, dir := range dirs {
+		if err != nil {
+			break
+		}
+		topic, user, err = ui.GetPod().DirectoryFeed(ui.GetPodName(), dir)
+		if err == nil {
+			err = watch(WatchDir, dir, topic, user)
+		}
+	}
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	events := make(chan *WatchEvent)
+	go func() {
+		defer close(events)
+		defer watcher.Close()
+		for {
+			select {
+			case fe, ok := <-watcher.Events():
+				if !ok {
+					return
+				}
+				event := watched[hex.EncodeToString(fe.Topic)]
+				event.Reference = hex.EncodeToString(fe.Address)
+				select {
+				case events <- &event:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
 }
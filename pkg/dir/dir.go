@@ -31,6 +31,10 @@ import (
 
 const (
 	DirectoryNameLength = 25
+
+	// updateAttempts is how many times a directory is read and updated
+	// before giving up on other writers updating it at the same time
+	updateAttempts = 5
 )
 
 type Directory struct {
@@ -116,3 +120,20 @@ func (d *Directory) GetPrefixPodFromPathMap(prefix string) *DirInode {
 	}
 	return nil
 }
+
+// ListDirsUnder returns the paths in the directory map of the given directory
+// and all the directories under it.
+func (d *Directory) ListDirsUnder(path string) []string {
+	d.dirMu.Lock()
+	defer d.dirMu.Unlock()
+	if !strings.HasPrefix(path, "/") {
+		path = utils.PathSeperator + path
+	}
+	var paths []string
+	for k := range d.dirMap {
+		if k == path || strings.HasPrefix(k, path+utils.PathSeperator) {
+			paths = append(paths, k)
+		}
+	}
+	return paths
+}
@@ -17,17 +17,17 @@ limitations under the License.
 package dir
 
 import (
+	"context"
 	"encoding/json"
-	"time"
 
 	m "github.com/jmozah/intOS-dfs/pkg/meta"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (d *Directory) CreateDirINode(podName string, dirName string, parent *DirInode) (*DirInode, []byte, error) {
+func (d *Directory) CreateDirINode(ctx context.Context, podName string, dirName string, parent *DirInode) (*DirInode, []byte, error) {
 	// create the meta data
 	parentPath := getPath(podName, parent)
-	now := time.Now().Unix()
+	now := d.fd.Clock().Now().Unix()
 	meta := m.DirectoryMetaData{
 		Version:          m.DirMetaVersion,
 		Path:             parentPath,
@@ -44,10 +44,11 @@ func (d *Directory) CreateDirINode(podName string, dirName string, parent *DirIn
 		return nil, nil, err
 	}
 
-	// create a feed for the directory and add data to it
+	// create a feed for the directory and add data to it. directories are
+	// updated with every change under them, so their feeds are sequence feeds
 	totalPath := parentPath + utils.PathSeperator + dirName
 	topic := utils.HashString(totalPath)
-	_, err = d.fd.CreateFeed(topic, d.acc.GetAddress(), data)
+	_, err = d.fd.CreateSequenceFeed(ctx, topic, d.acc.GetAddress(), data)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -56,11 +57,11 @@ func (d *Directory) CreateDirINode(podName string, dirName string, parent *DirIn
 	return dirInode, topic, nil
 }
 
-func (d *Directory) IsDirINodePresent(podName string, dirName string, parent *DirInode) bool {
+func (d *Directory) IsDirINodePresent(ctx context.Context, podName string, dirName string, parent *DirInode) bool {
 	parentPath := getPath(podName, parent)
 	totalPath := parentPath + utils.PathSeperator + dirName
 	topic := utils.HashString(totalPath)
-	_, _, err := d.fd.GetFeedData(topic, d.getAccount().GetAddress())
+	_, _, err := d.fd.GetFeedData(ctx, topic, d.getAccount().GetAddress())
 	return err == nil
 }
 
@@ -74,9 +75,9 @@ func getPath(podName string, parent *DirInode) string {
 	return path
 }
 
-func (d *Directory) CreatePodINode(podName string) (*DirInode, []byte, error) {
+func (d *Directory) CreatePodINode(ctx context.Context, podName string) (*DirInode, []byte, error) {
 	// create the metadata
-	now := time.Now().Unix()
+	now := d.fd.Clock().Now().Unix()
 	meta := m.DirectoryMetaData{
 		Version:          m.DirMetaVersion,
 		Path:             "/",
@@ -96,7 +97,7 @@ func (d *Directory) CreatePodINode(podName string) (*DirInode, []byte, error) {
 	// create a feed and store the metadata of the pod
 	totalPath := utils.PathSeperator + podName
 	topic := utils.HashString(totalPath)
-	_, err = d.fd.CreateFeed(topic, d.acc.GetAddress(), data)
+	_, err = d.fd.CreateSequenceFeed(ctx, topic, d.acc.GetAddress(), data)
 	if err != nil {
 		return nil, nil, err
 	}
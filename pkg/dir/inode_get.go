@@ -17,6 +17,7 @@ limitations under the License.
 package dir
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -24,9 +25,9 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (d *Directory) GetDirNode(name string, fd *feed.API, accountInfo *account.AccountInfo) ([]byte, *DirInode, error) {
+func (d *Directory) GetDirNode(ctx context.Context, name string, fd *feed.API, accountInfo *account.AccountInfo) ([]byte, *DirInode, error) {
 	topic := utils.HashString(name)
-	addr, data, err := fd.GetFeedData(topic, accountInfo.GetAddress())
+	addr, data, err := fd.GetFeedData(ctx, topic, accountInfo.GetAddress())
 	if err != nil {
 		return nil, nil, err
 	}
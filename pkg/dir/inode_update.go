@@ -17,34 +17,42 @@ limitations under the License.
 package dir
 
 import (
+	"context"
 	"encoding/json"
-	"time"
 
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (d *Directory) UpdateDirectory(dirInode *DirInode) ([]byte, error) {
-	dirName := dirInode.Meta.Name
-	path := dirInode.Meta.Path
-	meta := dirInode.Meta
-	meta.ModificationTime = time.Now().Unix()
-	dirInode.Meta = meta
-
-	data, err := json.Marshal(dirInode)
-	if err != nil {
-		return nil, err
-	}
-
-	curDir := path + utils.PathSeperator + dirName
-	if path == utils.PathSeperator {
-		curDir = path + dirName
+// ModifyDirectory applies change to the latest inode of the directory at path
+// and stores it, unless the directory was updated by someone else in the
+// meantime, like the same user on another device. Then the inode is read
+// again and change applied to it afresh, so change must only depend on the
+// inode it is given. An error returned by change aborts the update.
+func (d *Directory) ModifyDirectory(ctx context.Context, path string, change func(dirInode *DirInode) error) (*DirInode, []byte, error) {
+	topic := utils.HashString(path)
+	for attempt := 1; ; attempt++ {
+		addr, dirInode, err := d.GetDirNode(ctx, path, d.getFeed(), d.getAccount())
+		if err != nil {
+			return nil, nil, err
+		}
+		err = change(dirInode)
+		if err != nil {
+			return nil, nil, err
+		}
+		dirInode.Meta.ModificationTime = d.getFeed().Clock().Now().Unix()
+		data, err := json.Marshal(dirInode)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = d.getFeed().UpdateFeedIf(ctx, topic, d.getAccount().GetAddress(), data, addr)
+		if err == nil {
+			d.AddToDirectoryMap(path, dirInode)
+			return dirInode, topic, nil
+		}
+		if !feed.IsConflict(err) || attempt == updateAttempts {
+			return nil, nil, err
+		}
+		d.logger.Debugf("directory %s was updated meanwhile, updating it again: %v", path, err)
 	}
-	topic := utils.HashString(curDir)
-	_, err = d.getFeed().UpdateFeed(topic, d.getAccount().GetAddress(), data)
-	if err != nil {
-		return nil, err
-	}
-
-	d.AddToDirectoryMap(curDir, dirInode)
-	return topic, nil
 }
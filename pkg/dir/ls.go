@@ -17,11 +17,14 @@ limitations under the License.
 package dir
 
 import (
+	"context"
 	"encoding/json"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	m "github.com/jmozah/intOS-dfs/pkg/meta"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
@@ -40,59 +43,88 @@ type DirOrFileEntry struct {
 	AccessTime       string `json:"access_time"`
 }
 
-func (d *Directory) ListDir(podName, path string, printNames bool) []DirOrFileEntry {
-	_, dirInode, err := d.GetDirNode(path, d.getFeed(), d.getAccount())
+func (d *Directory) ListDir(ctx context.Context, podName, path string, printNames bool) []DirOrFileEntry {
+	_, dirInode, err := d.GetDirNode(ctx, path, d.getFeed(), d.getAccount())
 	if err != nil {
 		return nil
 	}
 
-	var listEntries []DirOrFileEntry
-	for _, ref := range dirInode.Hashes {
+	// the entries which are directories are all looked up at once, and the
+	// metadata of the files is then downloaded in parallel too
+	workers := feed.NewWorkers()
+	results := d.getFeed().GetFeedDataBatch(ctx, dirInode.Hashes, d.getAccount().GetAddress(), workers)
+	entries := make([]*DirOrFileEntry, len(dirInode.Hashes))
+	var wg sync.WaitGroup
+	for i, ref := range dirInode.Hashes {
 		// check if this is a directory
-		_, data, err := d.getFeed().GetFeedData(ref, d.getAccount().GetAddress())
-		if err != nil {
-			// if it is not a dir, then treat this reference as a file
-			data, _, err := d.getClient().DownloadBlob(ref)
-			if err != nil {
-				continue
-			}
-			var meta *m.FileMetaData
-			err = json.Unmarshal(data, &meta)
-			if err != nil {
+		if results[i].Err != nil {
+			// a feed which could not be read is neither
+			if !feed.IsNotFound(results[i].Err) {
+				d.logger.Warningf("ls: could not read entry %x: %v", ref, results[i].Err)
 				continue
 			}
 
-			entry := DirOrFileEntry{
-				Name:             meta.Name,
-				ContentType:      meta.ContentType,
-				Size:             strconv.FormatUint(meta.FileSize, 10),
-				BlockSize:        strconv.FormatInt(int64(uint64(meta.BlockSize)), 10),
-				CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
-				AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
-				ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
-			}
-			listEntries = append(listEntries, entry)
+			// if it is not a dir, then treat this reference as a file
+			wg.Add(1)
+			workers.Acquire()
+			go func(i int, ref []byte) {
+				defer func() {
+					workers.Release()
+					wg.Done()
+				}()
+				entries[i] = d.fileEntry(ctx, ref)
+			}(i, ref)
 			continue
 		}
 
 		var dirInode *DirInode
-		err = json.Unmarshal(data, &dirInode)
+		err = json.Unmarshal(results[i].Data, &dirInode)
 		if err != nil {
 			continue
 		}
-		entry := DirOrFileEntry{
+		entries[i] = &DirOrFileEntry{
 			Name:             dirInode.Meta.Name,
 			ContentType:      MineTypeDirectory, // per RFC2425
 			CreationTime:     strconv.FormatInt(dirInode.Meta.CreationTime, 10),
 			AccessTime:       strconv.FormatInt(dirInode.Meta.AccessTime, 10),
 			ModificationTime: strconv.FormatInt(dirInode.Meta.ModificationTime, 10),
 		}
-		listEntries = append(listEntries, entry)
+	}
+	wg.Wait()
+
+	var listEntries []DirOrFileEntry
+	for _, entry := range entries {
+		if entry != nil {
+			listEntries = append(listEntries, *entry)
+		}
 	}
 	return listEntries
 
 }
 
+// fileEntry returns the entry of the file whose metadata is at ref, nil if
+// there is no file there.
+func (d *Directory) fileEntry(ctx context.Context, ref []byte) *DirOrFileEntry {
+	data, _, err := d.getClient().DownloadBlob(ctx, ref)
+	if err != nil {
+		return nil
+	}
+	var meta *m.FileMetaData
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil
+	}
+	return &DirOrFileEntry{
+		Name:             meta.Name,
+		ContentType:      meta.ContentType,
+		Size:             strconv.FormatUint(meta.FileSize, 10),
+		BlockSize:        strconv.FormatInt(int64(uint64(meta.BlockSize)), 10),
+		CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
+		AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
+		ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
+	}
+}
+
 func (d *Directory) ListDirOnlyNames(podName, path string, printNames bool) ([]string, []string) {
 	d.dirMu.Lock()
 	defer d.dirMu.Unlock()
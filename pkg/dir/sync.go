@@ -17,47 +17,82 @@ limitations under the License.
 package dir
 
 import (
+	"context"
 	"encoding/json"
-	"net/http"
+	"sync"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (d *Directory) LoadDirMeta(podName string, curDirInode *DirInode, fd *feed.API, accountInfo *account.AccountInfo) error {
-	for _, ref := range curDirInode.Hashes {
-		_, data, err := fd.GetFeedData(ref, accountInfo.GetAddress())
-		if err != nil {
-			respCode, err := d.file.LoadFileMeta(podName, ref)
-			if err != nil {
-				return err
-			}
-			if respCode == http.StatusOK {
-				continue
+// LoadDirMeta loads the directories and files under a directory in to the
+// maps. As many of them are read at the same time as workers allow, which
+// are shared with the directories under it. A nil workers is a bound of its
+// own of feed.BatchWorkers reads.
+func (d *Directory) LoadDirMeta(ctx context.Context, podName string, curDirInode *DirInode, fd *feed.API, accountInfo *account.AccountInfo, workers feed.Workers) error {
+	if workers == nil {
+		workers = feed.NewWorkers()
+	}
+
+	// the entries which are directories are all read at once, and the
+	// metadata of the files is then loaded in parallel too
+	results := fd.GetFeedDataBatch(ctx, curDirInode.Hashes, accountInfo.GetAddress(), workers)
+	errC := make(chan error, 1) // the first error of the workers
+	var wg sync.WaitGroup
+	var dirInodes []*DirInode
+	for i, ref := range curDirInode.Hashes {
+		if results[i].Err != nil {
+			// only an entry which is not of a feed is a file, a feed which
+			// could not be read is an error
+			if !feed.IsNotFound(results[i].Err) {
+				wg.Wait()
+				return results[i].Err
 			}
-			return err
+			wg.Add(1)
+			workers.Acquire()
+			go func(ref []byte) {
+				defer func() {
+					workers.Release()
+					wg.Done()
+				}()
+				_, err := d.file.LoadFileMeta(ctx, podName, ref)
+				if err != nil {
+					select {
+					case errC <- err:
+					default:
+					}
+				}
+			}(ref)
+			continue
 		}
 
 		var dirInode *DirInode
-		err = json.Unmarshal(data, &dirInode)
+		err := json.Unmarshal(results[i].Data, &dirInode)
 		if err != nil {
+			wg.Wait()
 			return err
 		}
+		dirInodes = append(dirInodes, dirInode)
+	}
+	wg.Wait()
+	select {
+	case err := <-errC:
+		return err
+	default:
+	}
 
+	// the entry of a directory is the topic of its feed, so what was read is
+	// its latest inode already
+	for _, dirInode := range dirInodes {
 		path := dirInode.Meta.Path + utils.PathSeperator + dirInode.Meta.Name
 		d.AddToDirectoryMap(path, dirInode)
 		d.logger.Infof(path)
 
-		_, newDirInode, err := d.GetDirNode(path, fd, accountInfo)
+		err := d.LoadDirMeta(ctx, podName, dirInode, fd, accountInfo, workers)
 		if err != nil {
 			return err
 		}
-		err = d.LoadDirMeta(podName, newDirInode, fd, accountInfo)
-		if err != nil {
-			return err
-		}
-
 	}
 	return nil
 }
@@ -17,9 +17,9 @@ limitations under the License.
 package feed
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/ethersphere/bee/pkg/content"
 	"github.com/ethersphere/bee/pkg/crypto"
@@ -28,6 +28,7 @@ import (
 	bmtlegacy "github.com/ethersphere/bmt/legacy"
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
 	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
@@ -41,53 +42,96 @@ const (
 var (
 	// ErrInvalidTopicSize is returned when a topic is not equal to TopicLength
 	ErrInvalidTopicSize = fmt.Errorf("Topic is not equal to %d", TopicLength)
-
-	// ErrInvalidPayloadSize is returned when the payload is greater than the chunk size
-	ErrInvalidPayloadSize = fmt.Errorf("payload is greater than %d", utils.MaxChunkLength)
 )
 
 type API struct {
 	handler     *Handler
 	accountInfo *account.AccountInfo
+	format      Format
 	logger      logging.Logger
 }
 
+// Options tells how a feed API reads and writes its feeds.
+type Options struct {
+	// Hints keeps where the latest updates of the feeds were found, so that
+	// the lookups start from there even after a restart. None are kept if nil.
+	Hints *Hints
+
+	// Format is the format of the sequence feeds created.
+	Format Format
+
+	// Clock is the time source of the updates. The system clock is used if
+	// nil.
+	Clock clock.Clock
+}
+
 type Request struct {
 	ID
 	//User   utils.Address
 	idAddr swarm.Address // cached chunk address for the update (not serialized, for internal use)
 
-	data       []byte     // actual data payload
-	Signature  *Signature // Signature of the payload
-	binaryData []byte     // cached serialized data (does not get serialized again!, for efficiency/internal use)
+	data       []byte        // actual data payload
+	prev       *lookup.Epoch // epoch of the update before, nil if there is none (not serialized)
+	kind       Kind          // kind of the feed (not serialized)
+	format     Format        // format of the payload (not serialized)
+	index      uint64        // index of the update in a sequence feed (not serialized)
+	Signature  *Signature    // Signature of the payload
+	binaryData []byte        // cached serialized data (does not get serialized again!, for efficiency/internal use)
 }
 
 func New(accountInfo *account.AccountInfo, client blockstore.Client, logger logging.Logger) *API {
+	return NewWithOptions(accountInfo, client, Options{}, logger)
+}
+
+// NewWithOptions creates a feed API with the given options.
+func NewWithOptions(accountInfo *account.AccountInfo, client blockstore.Client, opts Options, logger logging.Logger) *API {
 	bmtPool := bmtlegacy.NewTreePool(hashFunc, swarm.Branches, bmtlegacy.PoolSize)
+	handler := NewHandler(accountInfo, client, bmtPool)
+	handler.hints = opts.Hints
+	if opts.Clock != nil {
+		handler.clock = opts.Clock
+	}
 	return &API{
-		handler:     NewHandler(accountInfo, client, bmtPool),
+		handler:     handler,
 		accountInfo: accountInfo,
+		format:      opts.Format,
 		logger:      logger,
 	}
 }
 
+// Options returns the options of the API, to create others like it.
+func (a *API) Options() Options {
+	return Options{
+		Hints:  a.handler.hints,
+		Format: a.format,
+		Clock:  a.handler.clock,
+	}
+}
+
+// Clock returns the clock the updates of the API are timed with. Metadata
+// kept in its feeds takes its timestamps from it too.
+func (a *API) Clock() clock.Clock {
+	return a.handler.clock
+}
+
 // create feed
-func (a *API) CreateFeed(topic []byte, user utils.Address, data []byte) ([]byte, error) {
+func (a *API) CreateFeed(ctx context.Context, topic []byte, user utils.Address, data []byte) ([]byte, error) {
 	var req Request
 
 	if len(topic) != TopicLength {
 		return nil, ErrInvalidTopicSize
 	}
 
-	if len(data) > utils.MaxChunkLength {
-		return nil, ErrInvalidPayloadSize
-	}
-
 	// fill Feed and Epoc related details
 	copy(req.ID.Topic[:], topic)
 	req.ID.User = user
 	req.Epoch.Level = 31
-	req.Epoch.Time = uint64(time.Now().Unix())
+	req.Epoch.Time = a.handler.now()
+
+	data, err := a.toPayload(ctx, &updateHeader{time: req.Time}, data)
+	if err != nil {
+		return nil, err
+	}
 
 	// Add initial feed data
 	req.data = data
@@ -98,15 +142,21 @@ func (a *API) CreateFeed(topic []byte, user utils.Address, data []byte) ([]byte,
 		return nil, err
 	}
 
+	// sign and send the soc chunk to bee
+	return a.signAndUpdate(ctx, &req, id)
+}
+
+// signAndUpdate signs the update with the given id and sends its chunk.
+func (a *API) signAndUpdate(ctx context.Context, req *Request, id []byte) ([]byte, error) {
 	// get the payload id BMT(span, payload)
-	payloadId, err := a.handler.getPayloadId(data)
+	payloadId, err := a.handler.getPayloadId(req.data)
 	if err != nil {
 		return nil, err
 	}
 
 	// create the signer and the content addressed chunk
 	signer := crypto.NewDefaultSigner(a.accountInfo.GetPrivateKey())
-	ch, err := content.NewChunk(data)
+	ch, err := content.NewChunk(req.data)
 	if err != nil {
 		return nil, err
 	}
@@ -120,102 +170,132 @@ func (a *API) CreateFeed(topic []byte, user utils.Address, data []byte) ([]byte,
 	req.binaryData = sch.Data()
 
 	// set signature and binary data fields
-	_, err = a.handler.toChunkContent(&req, id, payloadId)
+	_, err = a.handler.toChunkContent(req, id, payloadId)
 	if err != nil {
 		return nil, err
 	}
 
 	// send the updated soc chunk to bee
-	address, err := a.handler.update(&req)
+	address, err := a.handler.update(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	return address, nil
 }
 
-func (a *API) GetFeedData(topic []byte, user utils.Address) ([]byte, []byte, error) {
-	if len(topic) != TopicLength {
-		return nil, nil, ErrInvalidTopicSize
-	}
-
-	ctx := context.Background()
-	f := new(Feed)
-	f.User = user
-	copy(f.Topic[:], topic)
-
-	// create the query from values
-	q := &Query{Feed: *f}
-	q.TimeLimit = 0
-	q.Hint = lookup.NoClue
-	_, err := a.handler.Lookup(ctx, q)
+func (a *API) GetFeedData(ctx context.Context, topic []byte, user utils.Address) ([]byte, []byte, error) {
+	entry, err := a.getLatest(ctx, topic, user)
 	if err != nil {
 		return nil, nil, err
 	}
-	var data []byte
-	addr, data, err := a.handler.GetContent(&q.Feed)
+	data, err := a.fromPayload(ctx, entry.data)
 	if err != nil {
 		return nil, nil, err
 	}
-	return addr.Bytes(), data, nil
+	return entry.lastKey, data, nil
 
 }
 
-func (a *API) UpdateFeed(topic []byte, user utils.Address, data []byte) ([]byte, error) {
+// GetFeedPayloadReference returns the reference of the blob holding the data
+// of the latest update of a feed, or nil if the data is in the update itself.
+func (a *API) GetFeedPayloadReference(ctx context.Context, topic []byte, user utils.Address) ([]byte, error) {
+	entry, err := a.getLatest(ctx, topic, user)
+	if err != nil {
+		return nil, err
+	}
+	return payloadReference(entry.data), nil
+}
+
+func (a *API) getLatest(ctx context.Context, topic []byte, user utils.Address) (*CacheEntry, error) {
 	if len(topic) != TopicLength {
 		return nil, ErrInvalidTopicSize
 	}
 
-	if len(data) > utils.MaxChunkLength {
-		return nil, ErrInvalidPayloadSize
+	f := new(Feed)
+	f.User = user
+	copy(f.Topic[:], topic)
+
+	// use the entry found rather than reading it back from the cache, which
+	// may have evicted it by now
+	return a.handler.LookupLatest(ctx, f)
+}
+
+// UpdateFeed adds an update to a feed, of whichever kind the feed is.
+func (a *API) UpdateFeed(ctx context.Context, topic []byte, user utils.Address, data []byte) ([]byte, error) {
+	return a.updateFeed(ctx, topic, user, data, nil)
+}
+
+// UpdateFeedIf adds an update to a feed only if its latest update is still the
+// one at the expected address, as returned when it was read or updated. A nil
+// expected address means the feed must have no updates. Otherwise it fails
+// with an ErrConflict error, and the caller should read the feed again and
+// redo its update on top of what it finds.
+//
+// The check and the update are not atomic in the block store: it narrows the
+// window in which two writers can overwrite each other to the time it takes
+// to write one chunk, it does not close it.
+func (a *API) UpdateFeedIf(ctx context.Context, topic []byte, user utils.Address, data, expected []byte) ([]byte, error) {
+	return a.updateFeed(ctx, topic, user, data, func(latest *CacheEntry) error {
+		var latestAddr []byte
+		if latest != nil {
+			latestAddr = latest.lastKey
+		}
+		if !bytes.Equal(latestAddr, expected) {
+			return NewErrorf(ErrConflict, "feed updated since %x, the latest update is %x", expected, latestAddr)
+		}
+		return nil
+	})
+}
+
+// updateFeed adds an update to a feed if check, when given, approves of the
+// latest update of the feed, which is nil if there is none.
+func (a *API) updateFeed(ctx context.Context, topic []byte, user utils.Address, data []byte, check func(*CacheEntry) error) ([]byte, error) {
+	if len(topic) != TopicLength {
+		return nil, ErrInvalidTopicSize
 	}
 
-	ctx := context.Background()
 	f := new(Feed)
 	f.User = user
 	copy(f.Topic[:], topic)
 
-	// get the existing request from DB
-	req, err := a.handler.NewRequest(ctx, f)
+	latest, err := a.handler.LookupLatest(ctx, f)
 	if err != nil {
-		return nil, err
+		if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+			return nil, err
+		}
+		latest = nil
 	}
-	req.Time = uint64(time.Now().Unix())
-	req.data = data
-
-	// create the id, hash(topic, epoc)
-	id, err := a.handler.getId(req.Topic, req.Time, req.Level)
-	if err != nil {
-		return nil, err
+	if check != nil {
+		err = check(latest)
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	// get the payload id BMT(span, payload)
-	payloadId, err := a.handler.getPayloadId(data)
-	if err != nil {
-		return nil, err
+	if latest != nil && latest.kind == SequenceFeed {
+		return a.updateSequence(ctx, f, latest.index+1, data, latest.format)
 	}
 
-	// create the signer and the content addressed chunk
-	signer := crypto.NewDefaultSigner(a.accountInfo.GetPrivateKey())
-	ch, err := content.NewChunk(data)
+	// get the existing request from DB
+	req := a.handler.newRequest(f, latest)
+	data, err = a.toPayload(ctx, &updateHeader{time: req.Time, prev: req.prev}, data)
 	if err != nil {
 		return nil, err
 	}
-	sch, err := soc.NewChunk(id, ch, signer)
+	req.data = data
+
+	// create the id, hash(topic, epoc)
+	id, err := a.handler.getId(req.Topic, req.Time, req.Level)
 	if err != nil {
 		return nil, err
 	}
-
-	// set the address and the data for the soc chunk
-	req.idAddr = sch.Address()
-	req.binaryData = sch.Data()
-
-	// set signature and binary data fields
-	_, err = a.handler.toChunkContent(req, id, payloadId)
+	address, err := a.signAndUpdate(ctx, req, id)
 	if err != nil {
 		return nil, err
 	}
 
-	address, err := a.handler.update(req)
+	// the update can be ahead of the clock when it did not fit in its second,
+	// the lookups must start from it to find it
+	_, err = a.handler.updateCache(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+var (
+	// BatchWorkers is how many feeds of a batch are read at the same time at
+	// most.
+	BatchWorkers = 16
+)
+
+// Workers bounds how many reads are made at the same time. The batches of a
+// walk down a tree of feeds, like the directories of a pod, share one so that
+// the bound holds for the walk as a whole however deep it goes. A worker is
+// only held for a read of its own, never while waiting on other reads, so the
+// walk cannot run out of them.
+type Workers chan bool
+
+// NewWorkers returns a bound of BatchWorkers reads at the same time.
+func NewWorkers() Workers {
+	return make(Workers, BatchWorkers)
+}
+
+// Acquire waits for a worker to be free and takes it.
+func (w Workers) Acquire() {
+	w <- true
+}
+
+// Release frees a worker taken by Acquire.
+func (w Workers) Release() {
+	<-w
+}
+
+// FeedData is the latest update of a feed read in a batch, or the error which
+// kept it from being read.
+type FeedData struct {
+	Address []byte
+	Data    []byte
+	Err     error
+}
+
+// GetFeedDataBatch reads the latest updates of many feeds of a user, as many
+// of them at the same time as workers allow, so that reading them takes a few
+// round trips to the block store instead of one per feed. A nil workers is a
+// bound of its own of BatchWorkers reads. The results are in the order of the
+// topics, each with the error of its own feed, like a topic which is not of a
+// feed. A topic given more than once is read once. The lookups share the
+// cache of the API with all its other reads.
+func (a *API) GetFeedDataBatch(ctx context.Context, topics [][]byte, user utils.Address, workers Workers) []*FeedData {
+	if workers == nil {
+		workers = NewWorkers()
+	}
+	results := make([]*FeedData, len(topics))
+	first := make(map[string]int) // topic -> index of its first occurrence
+	var wg sync.WaitGroup
+	for i, topic := range topics {
+		key := hex.EncodeToString(topic)
+		if _, ok := first[key]; ok {
+			continue
+		}
+		first[key] = i
+
+		wg.Add(1)
+		workers.Acquire()
+		go func(i int, topic []byte) {
+			defer func() {
+				workers.Release()
+				wg.Done()
+			}()
+			addr, data, err := a.GetFeedData(ctx, topic, user)
+			results[i] = &FeedData{
+				Address: addr,
+				Data:    data,
+				Err:     err,
+			}
+		}(i, topic)
+	}
+	wg.Wait()
+
+	for i, topic := range topics {
+		if j := first[hex.EncodeToString(topic)]; j != i {
+			results[i] = results[j]
+		}
+	}
+	return results
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestFeedDataBatch(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	client := fault.New(mock.NewMockBeeClient(), 0)
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+
+	writer := New(accountInfo, client, logger)
+	var topics [][]byte
+	var addrs [][]byte
+	for i := 0; i < 64; i++ {
+		topic := hashString(fmt.Sprintf("batch%d", i))
+		addr, err := writer.CreateSequenceFeed(context.Background(), topic, user, []byte(fmt.Sprintf("data %d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		topics = append(topics, topic)
+		addrs = append(addrs, addr)
+	}
+	epochTopic := hashString("batch-epoch")
+	epochAddr, err := writer.CreateFeed(context.Background(), epochTopic, user, []byte("epoch"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("results-in-order", func(t *testing.T) {
+		missing := hashString("batch-missing")
+		batch := append([][]byte{missing, epochTopic}, topics...)
+		batch = append(batch, topics[0])
+		results := New(accountInfo, client, logger).GetFeedDataBatch(context.Background(), batch, user, nil)
+		if len(results) != len(batch) {
+			t.Fatalf("expected %d results, got %d", len(batch), len(results))
+		}
+		if ferr, ok := results[0].Err.(*Error); !ok || ferr.Code() != ErrNotFound {
+			t.Fatalf("expected not found for a topic without a feed, got %v", results[0].Err)
+		}
+		if results[1].Err != nil || !bytes.Equal(results[1].Address, epochAddr) || string(results[1].Data) != "epoch" {
+			t.Fatalf("unexpected result of the epoch feed %+v", results[1])
+		}
+		for i := range topics {
+			result := results[i+2]
+			data := fmt.Sprintf("data %d", i)
+			if result.Err != nil || !bytes.Equal(result.Address, addrs[i]) || string(result.Data) != data {
+				t.Fatalf("expected %q at %x, got %+v", data, addrs[i], result)
+			}
+		}
+		if last := results[len(results)-1]; last.Err != nil || string(last.Data) != "data 0" {
+			t.Fatalf("unexpected result of a topic given twice %+v", last)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		latency := 20 * time.Millisecond
+		client.SetLatency(latency)
+		defer client.SetLatency(0)
+
+		client.Reset()
+		start := time.Now()
+		results := New(accountInfo, client, logger).GetFeedDataBatch(context.Background(), topics, user, nil)
+		elapsed := time.Since(start)
+		for _, result := range results {
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+		}
+
+		// one after the other the reads would take this long
+		sequential := time.Duration(client.Calls(fault.DownloadChunk)) * latency
+		if elapsed > sequential/4 {
+			t.Fatalf("batch of %d reads took %v, %v one after the other", client.Calls(fault.DownloadChunk), elapsed, sequential)
+		}
+	})
+}
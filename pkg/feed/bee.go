@@ -0,0 +1,180 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// Format tells how the payload of an update is stored.
+type Format uint8
+
+const (
+	// DfsFormat stores a header which links the update to the one before,
+	// then the data itself or the reference of the blob holding it.
+	DfsFormat Format = iota
+
+	// BeeFormat stores the time of the update and the reference of the blob
+	// holding the data, as the sequence feeds of bee do. Such feeds resolve
+	// through the /feeds endpoint of bee and so through any Swarm gateway.
+	BeeFormat
+)
+
+const (
+	dfsFormatName = "dfs"
+	beeFormatName = "bee"
+)
+
+// ParseFormat returns the format of the given name, dfs or bee.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case dfsFormatName:
+		return DfsFormat, nil
+	case beeFormatName:
+		return BeeFormat, nil
+	default:
+		return DfsFormat, fmt.Errorf("unknown feed format %s", name)
+	}
+}
+
+func (f Format) String() string {
+	if f == BeeFormat {
+		return beeFormatName
+	}
+	return dfsFormatName
+}
+
+// beeTimeLength is the length of the time which starts the payload of an
+// update of a bee feed, before the reference of its data.
+const beeTimeLength = 8
+
+// toBeePayload stores the data in a blob and returns the payload of an update
+// of a bee feed which refers to it.
+func (a *API) toBeePayload(ctx context.Context, time uint64, data []byte) ([]byte, error) {
+	ref, err := a.handler.client.UploadBlob(ctx, data, true, false)
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, beeTimeLength, beeTimeLength+len(ref))
+	binary.BigEndian.PutUint64(payload, time)
+	return append(payload, ref...), nil
+}
+
+// fromBeePayload returns the payload of an update of a bee feed the way it
+// would be stored in the dfs format, so that the rest of the package reads
+// both the same. It returns false if the payload is not of a bee feed, which
+// holds a time and either a plain or an encrypted reference, and nothing else.
+// Only the updates of sequence feeds are told apart this way: they always
+// start with a prefix of the dfs format, while the old updates of epoch feeds
+// hold any data as it is. The prefix is what tells them apart, the length of
+// a payload in the dfs format can be that of a bee one.
+func fromBeePayload(payload []byte) ([]byte, bool) {
+	if bytes.HasPrefix(payload, dfsPayloadPrefix) {
+		return nil, false
+	}
+	refLength := len(payload) - beeTimeLength
+	if refLength != utils.ReferenceLength && refLength != 2*utils.ReferenceLength {
+		return nil, false
+	}
+	header := &updateHeader{time: binary.BigEndian.Uint64(payload[:beeTimeLength])}
+	converted := append(header.marshal(), blobPayloadPrefix...)
+	return append(converted, payload[beeTimeLength:]...), true
+}
+
+// MigrateToBeeFormat rewrites the latest update of a feed as the next update
+// of a sequence feed in the bee format, and returns its address. The updates
+// after it keep to that format, so the feed resolves through bee from then
+// on. The old updates stay where they are. An epoch feed becomes a sequence
+// feed, whose history starts with the migrated update, and gets a last update
+// which sends the readers that know it as an epoch feed to the sequence feed.
+// A feed in the bee format already is left as it is.
+func (a *API) MigrateToBeeFormat(ctx context.Context, topic []byte, user utils.Address) ([]byte, error) {
+	if len(topic) != TopicLength {
+		return nil, ErrInvalidTopicSize
+	}
+
+	f := new(Feed)
+	f.User = user
+	copy(f.Topic[:], topic)
+
+	latest, err := a.handler.LookupLatest(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if latest.kind == SequenceFeed && latest.format == BeeFormat {
+		// a migration which failed half way may not have ended the epoch
+		// feed yet
+		if latest.index == 0 {
+			err = a.endEpochFeed(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return latest.lastKey, nil
+	}
+	data, err := a.fromPayload(ctx, latest.data)
+	if err != nil {
+		return nil, err
+	}
+	var index uint64
+	if latest.kind == SequenceFeed {
+		index = latest.index + 1
+	}
+	address, err := a.updateSequence(ctx, f, index, data, BeeFormat)
+	if err != nil {
+		return nil, err
+	}
+	if latest.kind == EpochFeed {
+		err = a.endEpochFeed(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return address, nil
+}
+
+// endEpochFeed adds the update which tells that an epoch feed moved to a
+// sequence feed, unless the feed has no epoch updates or has it already. The
+// cache is left alone, it holds the sequence feed.
+func (a *API) endEpochFeed(ctx context.Context, f *Feed) error {
+	// a lookup in the past is not cached
+	latest, err := a.handler.Lookup(ctx, NewQuery(f, a.handler.now(), lookup.NoClue))
+	if err != nil {
+		if ferr, ok := err.(*Error); ok && ferr.code == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if isMovedPayload(latest.data) {
+		return nil
+	}
+
+	req := a.handler.newRequest(f, latest)
+	req.data = append((&updateHeader{time: req.Time, prev: req.prev}).marshal(), movedPayloadPrefix...)
+	id, err := a.handler.getId(req.Topic, req.Time, req.Level)
+	if err != nil {
+		return err
+	}
+	_, err = a.signAndUpdate(ctx, req, id)
+	return err
+}
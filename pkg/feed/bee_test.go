@@ -0,0 +1,274 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/soc"
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestBeeFormat(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	client := mock.NewMockBeeClient()
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+
+	// readBeeUpdate resolves the update at index of a sequence feed the way
+	// bee does, and returns the data it refers to
+	readBeeUpdate := func(t *testing.T, topic []byte, index uint64) []byte {
+		t.Helper()
+		var tp Topic
+		copy(tp[:], topic)
+		id, err := getSequenceId(tp, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := toSignDigest(id, user[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := client.DownloadChunk(context.Background(), addr)
+		if err != nil {
+			t.Fatalf("no update at index %d: %v", index, err)
+		}
+		payload := data[idLength+signatureLength+utils.SpanLength:]
+		if len(payload) != beeTimeLength+utils.ReferenceLength {
+			t.Fatalf("update at index %d is not in the bee format: %d bytes", index, len(payload))
+		}
+		at := binary.BigEndian.Uint64(payload[:beeTimeLength])
+		if now := uint64(time.Now().Unix()); at > now || at+60 < now {
+			t.Fatalf("update at index %d has time %d", index, at)
+		}
+		blob, respCode, err := client.DownloadBlob(context.Background(), payload[beeTimeLength:])
+		if err != nil || respCode != http.StatusOK {
+			t.Fatalf("could not read the data of the update at index %d: %v", index, err)
+		}
+		return blob
+	}
+
+	t.Run("create-and-update", func(t *testing.T) {
+		fd := NewWithOptions(accountInfo, client, Options{Format: BeeFormat}, logger)
+		topic := hashString("bee1")
+		addr, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readBeeUpdate(t, topic, 0); string(got) != "first" {
+			t.Fatalf("expected %q, got %q", "first", got)
+		}
+
+		// whoever updates it next keeps to its format
+		other := New(accountInfo, client, logger)
+		rcvdAddr, data, err := other.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, rcvdAddr) || string(data) != "first" {
+			t.Fatalf("expected %q at %x, got %q at %x", "first", addr, data, rcvdAddr)
+		}
+		addr, err = other.UpdateFeed(context.Background(), topic, user, []byte("second"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readBeeUpdate(t, topic, 1); string(got) != "second" {
+			t.Fatalf("expected %q, got %q", "second", got)
+		}
+		rcvdAddr, data, err = New(accountInfo, client, logger).GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, rcvdAddr) || string(data) != "second" {
+			t.Fatalf("expected %q at %x, got %q at %x", "second", addr, data, rcvdAddr)
+		}
+
+		updates, err := New(accountInfo, client, logger).GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != 2 || string(updates[0].Data) != "second" || string(updates[1].Data) != "first" {
+			t.Fatalf("unexpected history %v", updates)
+		}
+	})
+
+	t.Run("written-by-bee", func(t *testing.T) {
+		// an update made the way bee makes them, without this package
+		topic := hashString("bee2")
+		ref, err := client.UploadBlob(context.Background(), []byte("from bee"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload := make([]byte, beeTimeLength)
+		binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+		ch, err := content.NewChunk(append(payload, ref...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tp Topic
+		copy(tp[:], topic)
+		id, err := getSequenceId(tp, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sch, err := soc.NewChunk(id, ch, crypto.NewDefaultSigner(accountInfo.GetPrivateKey()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.UploadChunk(context.Background(), sch, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fd := New(accountInfo, client, logger)
+		addr, data, err := fd.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(addr, sch.Address().Bytes()) || string(data) != "from bee" {
+			t.Fatalf("expected %q at %s, got %q at %x", "from bee", sch.Address(), data, addr)
+		}
+	})
+
+	t.Run("migrate", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		epochTopic := hashString("bee3")
+		_, err := fd.CreateFeed(context.Background(), epochTopic, user, []byte("epoch"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sequenceTopic := hashString("bee4")
+		_, err = fd.CreateSequenceFeed(context.Background(), sequenceTopic, user, []byte("sequence 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.UpdateFeed(context.Background(), sequenceTopic, user, []byte("sequence 1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a reader which knows the epoch feed as one before the migration
+		known := New(accountInfo, client, logger)
+		_, _, err = known.GetFeedData(context.Background(), epochTopic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		migrations := []struct {
+			topic []byte
+			index uint64
+			data  string
+		}{
+			{epochTopic, 0, "epoch"},
+			{sequenceTopic, 2, "sequence 1"},
+		}
+		for _, m := range migrations {
+			addr, err := fd.MigrateToBeeFormat(context.Background(), m.topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readBeeUpdate(t, m.topic, m.index); string(got) != m.data {
+				t.Fatalf("expected %q, got %q", m.data, got)
+			}
+
+			// migrating again changes nothing
+			again, err := fd.MigrateToBeeFormat(context.Background(), m.topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(addr, again) {
+				t.Fatalf("migrated again to %x from %x", again, addr)
+			}
+
+			rcvdAddr, data, err := New(accountInfo, client, logger).GetFeedData(context.Background(), m.topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(addr, rcvdAddr) || string(data) != m.data {
+				t.Fatalf("expected %q at %x, got %q at %x", m.data, addr, data, rcvdAddr)
+			}
+		}
+
+		// finds the updates made after it
+		_, err = fd.UpdateFeed(context.Background(), epochTopic, user, []byte("after migration"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readBeeUpdate(t, epochTopic, 1); string(got) != "after migration" {
+			t.Fatalf("expected %q, got %q", "after migration", got)
+		}
+		_, data, err := known.GetFeedData(context.Background(), epochTopic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "after migration" {
+			t.Fatalf("migrated feed read as an epoch feed: %q", data)
+		}
+	})
+
+	t.Run("parse-format", func(t *testing.T) {
+		for _, format := range []Format{DfsFormat, BeeFormat} {
+			parsed, err := ParseFormat(format.String())
+			if err != nil || parsed != format {
+				t.Fatalf("parsed %s as %s: %v", format, parsed, err)
+			}
+		}
+		if _, err := ParseFormat("swarm"); err == nil {
+			t.Fatal("parsed an unknown format")
+		}
+	})
+
+	t.Run("tell-payloads-apart", func(t *testing.T) {
+		header := (&updateHeader{time: 1600000000}).marshal()
+		for _, refLength := range []int{utils.ReferenceLength, 2 * utils.ReferenceLength} {
+			bee := make([]byte, beeTimeLength+refLength)
+			binary.BigEndian.PutUint64(bee, 1600000000)
+			if _, ok := fromBeePayload(bee); !ok {
+				t.Fatalf("bee payload with a %d byte reference not taken for one", refLength)
+			}
+
+			// a dfs payload of the same length
+			dfs := append(append([]byte(nil), header...), make([]byte, len(bee)-len(header))...)
+			if _, ok := fromBeePayload(dfs); ok {
+				t.Fatalf("dfs payload of %d bytes taken for a bee one", len(dfs))
+			}
+			blob := append(append([]byte(nil), blobPayloadPrefix...), make([]byte, len(bee)-len(blobPayloadPrefix))...)
+			if _, ok := fromBeePayload(blob); ok {
+				t.Fatalf("dfs blob payload of %d bytes taken for a bee one", len(blob))
+			}
+		}
+		if _, ok := fromBeePayload(make([]byte, beeTimeLength+utils.ReferenceLength+1)); ok {
+			t.Fatal("payload of another length taken for a bee one")
+		}
+	})
+}
@@ -19,12 +19,14 @@ package feed
 import (
 	"bytes"
 	"context"
-	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
 const (
-	hasherCount            = 8
-	defaultRetrieveTimeout = 100 * time.Millisecond
+	hasherCount = 8
+
+	CacheSegment = "feed"
 )
 
 // CacheEntry caches the last known update of a specific Swarm feed.
@@ -32,6 +34,9 @@ type CacheEntry struct {
 	Update
 	*bytes.Reader
 	lastKey []byte
+	kind    Kind   // kind of the feed
+	format  Format // format of the payload of the update
+	index   uint64 // index of the update in a sequence feed
 }
 
 // implements storage.LazySectionReader
@@ -39,6 +44,11 @@ func (r *CacheEntry) Size(ctx context.Context, _ chan bool) (int64, error) {
 	return int64(len(r.Update.data)), nil
 }
 
+// size is the number of bytes the entry is accounted for in the cache
+func (r *CacheEntry) size() int64 {
+	return int64(len(r.Update.data) + len(r.lastKey) + TopicLength + utils.AddressLength)
+}
+
 //returns the feed's topic
 func (r *CacheEntry) Topic() Topic {
 	return r.Feed.Topic
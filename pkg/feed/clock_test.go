@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestClock(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	client := mock.NewMockBeeClient()
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+	start := time.Unix(1600000000, 0)
+
+	t.Run("updates-timed-by-clock", func(t *testing.T) {
+		clk := clock.NewMock(start)
+		fd := NewWithOptions(accountInfo, client, Options{Clock: clk}, logger)
+		topic := hashString("clock1")
+		_, err := fd.CreateFeed(context.Background(), topic, user, []byte("v0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		clk.Add(time.Hour)
+		_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("v1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != 2 {
+			t.Fatalf("expected 2 updates, got %d", len(updates))
+		}
+		want := []int64{start.Add(time.Hour).Unix(), start.Unix()}
+		for i, update := range updates {
+			if update.Epoch.Time != uint64(want[i]) {
+				t.Fatalf("update %d: expected time %d, got %d", i, want[i], update.Epoch.Time)
+			}
+		}
+		if fd.Clock() != clk || fd.Options().Clock != clk {
+			t.Fatalf("clock of the feed API not kept")
+		}
+	})
+
+	t.Run("same-second-updates", func(t *testing.T) {
+		clk := clock.NewMock(start)
+		fd := NewWithOptions(accountInfo, client, Options{Clock: clk}, logger)
+		topic := hashString("clock2")
+		count := 50 // more than the levels an epoch has in a second
+		_, err := fd.CreateFeed(context.Background(), topic, user, []byte("v0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < count; i++ {
+			data := []byte(fmt.Sprintf("v%d", i))
+			_, err = fd.UpdateFeed(context.Background(), topic, user, data)
+			if err != nil {
+				t.Fatalf("update %d: %v", i, err)
+			}
+			_, got, err := fd.GetFeedData(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(data) {
+				t.Fatalf("expected %q, got %q", data, got)
+			}
+		}
+
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != count {
+			t.Fatalf("expected %d updates, got %d", count, len(updates))
+		}
+		for i, update := range updates {
+			want := fmt.Sprintf("v%d", count-1-i)
+			if string(update.Data) != want {
+				t.Fatalf("update %d: expected %q, got %q", i, want, update.Data)
+			}
+		}
+
+		// the updates that did not fit in the second are found by others
+		// once their time has come
+		other := NewWithOptions(accountInfo, client, Options{Clock: clk}, logger)
+		clk.Add(time.Minute)
+		_, got, err := other.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("v%d", count-1); string(got) != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	})
+}
@@ -32,6 +32,7 @@ const (
 	ErrInvalidSignature
 	ErrNotSynced
 	ErrPeriodDepth
+	ErrConflict
 	ErrCnt
 )
 
@@ -61,7 +62,7 @@ func NewError(code int, s string) error {
 		err: s,
 	}
 	switch code {
-	case ErrNotFound, ErrIO, ErrUnauthorized, ErrInvalidValue, ErrDataOverflow, ErrNothingToReturn, ErrInvalidSignature, ErrNotSynced, ErrPeriodDepth, ErrCorruptData:
+	case ErrNotFound, ErrIO, ErrUnauthorized, ErrInvalidValue, ErrDataOverflow, ErrNothingToReturn, ErrInvalidSignature, ErrNotSynced, ErrPeriodDepth, ErrCorruptData, ErrConflict:
 		r.code = code
 	}
 	return r
@@ -71,3 +72,17 @@ func NewError(code int, s string) error {
 func NewErrorf(code int, format string, args ...interface{}) error {
 	return NewError(code, fmt.Sprintf(format, args...))
 }
+
+// IsConflict tells if err is a conditional update failing because the feed
+// was updated by someone else since it was read.
+func IsConflict(err error) bool {
+	ferr, ok := err.(*Error)
+	return ok && ferr.code == ErrConflict
+}
+
+// IsNotFound tells if err is a read failing because the feed has no updates,
+// which is also what a topic which is not of a feed gives.
+func IsNotFound(err error) bool {
+	ferr, ok := err.(*Error)
+	return ok && ferr.code == ErrNotFound
+}
@@ -18,6 +18,8 @@ package feed
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/binary"
 	"fmt"
 	"io/ioutil"
@@ -26,6 +28,7 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
 func TestFeed(t *testing.T) {
@@ -44,13 +47,13 @@ func TestFeed(t *testing.T) {
 		fd := New(accountInfo1, client, logger)
 		topic := hashString("topic1")
 		data := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
-		addr, err := fd.CreateFeed(topic, user1, data)
+		addr, err := fd.CreateFeed(context.Background(), topic, user1, data)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// check if the data and address is present and is same as stored
-		rcvdAddr, rcvdData, err := fd.GetFeedData(topic, user1)
+		rcvdAddr, rcvdData, err := fd.GetFeedData(context.Background(), topic, user1)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -75,14 +78,14 @@ func TestFeed(t *testing.T) {
 		fd1 := New(accountInfo1, client, logger)
 		topic := hashString("topic1")
 		data := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
-		addr, err := fd1.CreateFeed(topic, user1, data)
+		addr, err := fd1.CreateFeed(context.Background(), topic, user1, data)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		// check if you can read the data from user2
 		fd2 := New(accountInfo2, client, logger)
-		rcvdAddr, rcvdData, err := fd2.GetFeedData(topic, user1)
+		rcvdAddr, rcvdData, err := fd2.GetFeedData(context.Background(), topic, user1)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -99,7 +102,7 @@ func TestFeed(t *testing.T) {
 		topic := hashString("topic2")
 
 		// check if the data and address is present and is same as stored
-		_, _, err := fd.GetFeedData(topic, user1)
+		_, _, err := fd.GetFeedData(context.Background(), topic, user1)
 		if err != nil && err.Error() != "no feed updates found" {
 			t.Fatal(err)
 		}
@@ -110,7 +113,7 @@ func TestFeed(t *testing.T) {
 		fd := New(accountInfo1, client, logger)
 		topic := hashString("topic3")
 		data := []byte{0}
-		_, err := fd.CreateFeed(topic, user1, data)
+		_, err := fd.CreateFeed(context.Background(), topic, user1, data)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -118,11 +121,11 @@ func TestFeed(t *testing.T) {
 		for i := 1; i < 256; i++ {
 			buf := make([]byte, 4)
 			binary.LittleEndian.PutUint16(buf, uint16(i))
-			_, err := fd.UpdateFeed(topic, user1, buf)
+			_, err := fd.UpdateFeed(context.Background(), topic, user1, buf)
 			if err != nil {
 				t.Fatal(err)
 			}
-			getAddr, rcvdData, err := fd.GetFeedData(topic, user1)
+			getAddr, rcvdData, err := fd.GetFeedData(context.Background(), topic, user1)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -137,4 +140,133 @@ func TestFeed(t *testing.T) {
 
 	})
 
+	t.Run("large-payload", func(t *testing.T) {
+		fd := New(accountInfo1, client, logger)
+		topic := hashString("topic4")
+		data := make([]byte, 3*utils.MaxChunkLength)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.CreateFeed(context.Background(), topic, user1, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rcvdData, err := fd.GetFeedData(context.Background(), topic, user1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("large payload mismatch")
+		}
+		ref, err := fd.GetFeedPayloadReference(context.Background(), topic, user1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref == nil || !client.IsBlobPinned(ref) {
+			t.Fatalf("large payload not in a pinned blob")
+		}
+
+		// back to a payload which fits in the update
+		small := []byte("small again")
+		_, err = fd.UpdateFeed(context.Background(), topic, user1, small)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rcvdData, err = fd.GetFeedData(context.Background(), topic, user1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(small, rcvdData) {
+			t.Fatalf("small payload mismatch")
+		}
+		ref, err = fd.GetFeedPayloadReference(context.Background(), topic, user1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref != nil {
+			t.Fatalf("small payload not inline")
+		}
+	})
+
+	t.Run("payload-like-a-reference", func(t *testing.T) {
+		fd := New(accountInfo1, client, logger)
+		topic := hashString("topic5")
+		data := append(append([]byte{}, blobPayloadPrefix...), make([]byte, 32)...)
+		_, err := fd.CreateFeed(context.Background(), topic, user1, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rcvdData, err := fd.GetFeedData(context.Background(), topic, user1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, rcvdData) {
+			t.Fatalf("payload mismatch")
+		}
+	})
+
+	t.Run("payload-like-a-marker", func(t *testing.T) {
+		fd := New(accountInfo1, client, logger)
+		markers := [][]byte{movedPayloadPrefix, updateHeaderPrefix, dfsPayloadPrefix}
+		for i, create := range []func(context.Context, []byte, utils.Address, []byte) ([]byte, error){fd.CreateFeed, fd.CreateSequenceFeed} {
+			topic := hashString(fmt.Sprintf("topic-marker-%d", i))
+			for j, marker := range markers {
+				data := append([]byte{}, marker...)
+				var err error
+				if j == 0 {
+					_, err = create(context.Background(), topic, user1, data)
+				} else {
+					_, err = fd.UpdateFeed(context.Background(), topic, user1, data)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, rcvdData, err := fd.GetFeedData(context.Background(), topic, user1)
+				if err != nil {
+					t.Fatalf("feed %d, payload %q: %v", i, marker, err)
+				}
+				if !bytes.Equal(data, rcvdData) {
+					t.Fatalf("feed %d, payload %q read back as %q", i, marker, rcvdData)
+				}
+			}
+		}
+	})
+
+	t.Run("conditional-update", func(t *testing.T) {
+		fd := New(accountInfo1, client, logger)
+		for i, create := range []func(context.Context, []byte, utils.Address, []byte) ([]byte, error){fd.CreateFeed, fd.CreateSequenceFeed} {
+			topic := hashString(fmt.Sprintf("topic-cas-%d", i))
+			first, err := create(context.Background(), topic, user1, []byte("first"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := fd.UpdateFeedIf(context.Background(), topic, user1, []byte("second"), first)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// another writer which still has the first update
+			other := New(accountInfo1, client, logger)
+			_, err = other.UpdateFeedIf(context.Background(), topic, user1, []byte("lost"), first)
+			if !IsConflict(err) {
+				t.Fatalf("expected a conflict, got %v", err)
+			}
+			_, err = other.UpdateFeedIf(context.Background(), topic, user1, []byte("lost"), nil)
+			if !IsConflict(err) {
+				t.Fatalf("expected a conflict updating as a new feed, got %v", err)
+			}
+			_, data, err := other.GetFeedData(context.Background(), topic, user1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "second" {
+				t.Fatalf("conflicting update overwrote the feed: %s", data)
+			}
+			_, err = other.UpdateFeedIf(context.Background(), topic, user1, []byte("third"), second)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
 }
@@ -24,16 +24,20 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
 	beecrypto "github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/soc"
 	"github.com/ethersphere/bee/pkg/swarm"
 	bmtlegacy "github.com/ethersphere/bmt/legacy"
 	"golang.org/x/crypto/sha3"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
 	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
@@ -43,8 +47,11 @@ type Handler struct {
 	client      blockstore.Client
 	hasherPool  *bmtlegacy.TreePool
 	HashSize    int
-	cache       map[uint64]*CacheEntry
-	cacheLock   sync.RWMutex
+	cache       *cache.Segment
+	hints       *Hints
+	clock       clock.Clock
+	kinds       map[uint64]Kind // kinds of the feeds looked up
+	kindsMu     sync.Mutex
 }
 
 // hashPool contains a pool of ready hashers
@@ -59,12 +66,23 @@ func init() {
 	}
 }
 
+// NewHandler creates a feed handler. The latest updates of the feeds are
+// cached in the cache of the client if it has one, else in a cache of its own.
 func NewHandler(accountInfo *account.AccountInfo, client blockstore.Client, hasherPool *bmtlegacy.TreePool) *Handler {
+	var c *cache.Cache
+	if provider, ok := client.(cache.Provider); ok {
+		c = provider.Cache()
+	}
+	if c == nil {
+		c = cache.New(cache.DefaultSize)
+	}
 	fh := &Handler{
 		accountInfo: accountInfo,
 		client:      client,
 		hasherPool:  hasherPool,
-		cache:       make(map[uint64]*CacheEntry),
+		cache:       c.Segment(CacheSegment),
+		clock:       clock.System,
+		kinds:       make(map[uint64]Kind),
 	}
 	for i := 0; i < hasherCount; i++ {
 		hashfunc := crypto.SHA256.New()
@@ -76,14 +94,14 @@ func NewHandler(accountInfo *account.AccountInfo, client blockstore.Client, hash
 	return fh
 }
 
-func (h *Handler) update(req *Request) ([]byte, error) {
+func (h *Handler) update(ctx context.Context, req *Request) ([]byte, error) {
 	if req.idAddr.Equal(swarm.ZeroAddress) || req.binaryData == nil {
 		return nil, fmt.Errorf("invlaid address or chunk data")
 	}
 	ch := swarm.NewChunk(req.idAddr, req.binaryData)
 
 	// send the chunk
-	addr, err := h.client.UploadChunk(ch, true)
+	addr, err := h.client.UploadChunk(ctx, ch, true)
 	if err != nil {
 		return nil, err
 	}
@@ -113,16 +131,23 @@ func (h *Handler) Lookup(ctx context.Context, query *Query) (*CacheEntry, error)
 
 	timeLimit := query.TimeLimit
 	if timeLimit == 0 { // if time limit is set to zero, the user wants to get the latest update
-		timeLimit = TimestampProvider.Now().Time
+		timeLimit = h.now()
 	}
 
-	if query.Hint == lookup.NoClue { // try to use our cache
+	if query.Hint == lookup.NoClue { // try to use our cache, then the hints kept
 		entry, err := h.get(&query.Feed)
 		if err != nil {
 			return nil, err
 		}
-		if entry != nil && entry.Epoch.Time <= timeLimit { // avoid bad hints
+		if entry != nil && entry.kind == EpochFeed && query.TimeLimit == 0 && entry.Epoch.Time > timeLimit {
+			// updates made in the same second can be ahead of the clock,
+			// see newRequest. our own must still be found
+			timeLimit = entry.Epoch.Time
+		}
+		if entry != nil && entry.kind == EpochFeed && entry.Epoch.Time <= timeLimit { // avoid bad hints
 			query.Hint = entry.Epoch
+		} else if ht, ok := h.hints.get(&query.Feed); entry == nil && ok && ht.kind == EpochFeed && ht.epoch.Time <= timeLimit {
+			query.Hint = ht.epoch
 		}
 	}
 
@@ -141,16 +166,13 @@ func (h *Handler) Lookup(ctx context.Context, query *Query) (*CacheEntry, error)
 			Feed:  query.Feed,
 			Epoch: epoch,
 		}
-		ctx, cancel := context.WithTimeout(ctx, defaultRetrieveTimeout)
-		defer cancel()
-
 		addr, err := h.getAddress(id.Topic, query.Feed.User, epoch)
 		if err != nil {
 			return nil, err
 		}
 		data, err := h.client.DownloadChunk(ctx, addr.Bytes())
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) || err.Error() == "error downloading data" { // chunk not found
+			if isChunkNotFound(err) {
 				return nil, nil
 			}
 			return nil, err
@@ -158,7 +180,7 @@ func (h *Handler) Lookup(ctx context.Context, query *Query) (*CacheEntry, error)
 		ch := swarm.NewChunk(addr, data)
 		var request Request
 		if err := h.fromChunk(ch, &request, query, &id); err != nil {
-			return nil, nil
+			return nil, err
 		}
 		if request.Time <= timeLimit {
 			return &request, nil
@@ -172,15 +194,59 @@ func (h *Handler) Lookup(ctx context.Context, query *Query) (*CacheEntry, error)
 	if request == nil {
 		return nil, NewError(ErrNotFound, "no feed updates found")
 	}
+	if query.TimeLimit != 0 {
+		// an update found in the past must not take the place of the latest
+		// one in the cache
+		return newCacheEntry(request), nil
+	}
 	return h.updateCache(request)
 }
 
-// fromChunk populates this structure from chunk data. It does not verify the signature is valid.
+// getUpdate reads the update of a feed stored at the given epoch, without
+// looking it up or caching it.
+func (h *Handler) getUpdate(ctx context.Context, feed *Feed, epoch lookup.Epoch) (*CacheEntry, error) {
+	addr, err := h.getAddress(feed.Topic, feed.User, epoch)
+	if err != nil {
+		return nil, err
+	}
+	data, err := h.client.DownloadChunk(ctx, addr.Bytes())
+	if err != nil {
+		if isChunkNotFound(err) {
+			return nil, NewError(ErrNotFound, "feed update not found")
+		}
+		return nil, err
+	}
+	var request Request
+	id := ID{
+		Feed:  *feed,
+		Epoch: epoch,
+	}
+	err = h.fromChunk(swarm.NewChunk(addr, data), &request, &Query{Feed: *feed}, &id)
+	if err != nil {
+		return nil, err
+	}
+	return newCacheEntry(&request), nil
+}
+
+// isChunkNotFound tells if a chunk download failed because there is no chunk.
+// A read which timed out or failed otherwise tells nothing of the chunk.
+func isChunkNotFound(err error) bool {
+	return errors.Is(err, blockstore.ErrNotFound)
+}
+
+// fromChunk populates this structure from chunk data. It fails with an
+// ErrInvalidSignature error unless the chunk is signed by the user of the
+// feed and is stored at the address its id and signer give, so a corrupted
+// update or one made up by someone else is never taken for the user's.
 func (h *Handler) fromChunk(chunk swarm.Chunk, r *Request, q *Query, id *ID) error {
 	chunkdata := chunk.Data()
 
 	if len(chunkdata) < idLength+signatureLength+utils.SpanLength {
-		return fmt.Errorf("invalid chunk data len")
+		return NewError(ErrInvalidSignature, "invalid chunk data len")
+	}
+	err := verifyChunk(chunk, q.User)
+	if err != nil {
+		return err
 	}
 
 	r.idAddr = swarm.NewAddress(chunk.Address().Bytes())
@@ -202,25 +268,25 @@ func (h *Handler) fromChunk(chunk swarm.Chunk, r *Request, q *Query, id *ID) err
 
 // update feed updates cache with specified content
 func (h *Handler) updateCache(request *Request) (*CacheEntry, error) {
-	updateAddr := request.idAddr.Bytes()
-	entry, err := h.get(&request.Feed)
+	// a new entry every time, so that the size accounted in the cache stays right
+	entry := newCacheEntry(request)
+	err := h.set(&request.Feed, entry)
 	if err != nil {
 		return nil, err
 	}
-	if entry == nil {
-		entry = &CacheEntry{}
-		err := h.set(&request.Feed, entry)
-		if err != nil {
-			return nil, err
-		}
-	}
+	return entry, nil
+}
 
-	// update our rsrcs entry map
-	entry.lastKey = updateAddr
+func newCacheEntry(request *Request) *CacheEntry {
+	entry := &CacheEntry{}
+	entry.lastKey = request.idAddr.Bytes()
 	entry.Update.ID = request.ID
 	entry.data = request.data
 	entry.Reader = bytes.NewReader(entry.data)
-	return entry, nil
+	entry.kind = request.kind
+	entry.format = request.format
+	entry.index = request.index
+	return entry
 }
 
 func hashFunc() hash.Hash {
@@ -271,30 +337,48 @@ func (h *Handler) NewRequest(ctx context.Context, feed *Feed) (request *Request,
 		return nil, NewError(ErrInvalidValue, "feed cannot be nil")
 	}
 
-	now := TimestampProvider.Now().Time
-	request = new(Request)
-
 	query := NewQueryLatest(feed, lookup.NoClue)
 
 	feedUpdate, err := h.Lookup(ctx, query)
 	if err != nil {
-		if err.(*Error).code != ErrNotFound {
+		if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
 			return nil, err
 		}
 		// not finding updates means that there is a network error
 		// or that the feed really does not have updates
 	}
+	return h.newRequest(feed, feedUpdate), nil
+}
 
+// newRequest prepares the request of the update after feedUpdate, which is
+// nil if the feed has no updates yet.
+func (h *Handler) newRequest(feed *Feed, feedUpdate *CacheEntry) *Request {
+	now := h.now()
+	request := new(Request)
 	request.Feed = *feed
 
 	// if we already have an update, then find next epoch
 	if feedUpdate != nil {
+		if now < feedUpdate.Epoch.Time {
+			now = feedUpdate.Epoch.Time
+		}
 		request.Epoch = lookup.GetNextEpoch(feedUpdate.Epoch, now)
+		if request.Epoch.Equals(feedUpdate.Epoch) {
+			// every level of this second is taken by the updates made in
+			// it, so this one goes in the next second
+			request.Epoch = lookup.GetNextEpoch(feedUpdate.Epoch, now+1)
+		}
+		prev := feedUpdate.Epoch
+		request.prev = &prev
 	} else {
 		request.Epoch = lookup.GetFirstEpoch(now)
 	}
+	return request
+}
 
-	return request, nil
+// now returns the time of the clock of the handler as a Unix epoch.
+func (h *Handler) now() uint64 {
+	return uint64(h.clock.Now().Unix())
 }
 
 func (h *Handler) getId(topic Topic, time uint64, level uint8) ([]byte, error) {
@@ -359,10 +443,11 @@ func (h *Handler) get(feed *Feed) (*CacheEntry, error) {
 	if err != nil {
 		return nil, err
 	}
-	h.cacheLock.RLock()
-	defer h.cacheLock.RUnlock()
-	feedUpdate := h.cache[mapKey]
-	return feedUpdate, nil
+	value, ok := h.cache.Get(strconv.FormatUint(mapKey, 10))
+	if !ok {
+		return nil, nil
+	}
+	return value.(*CacheEntry), nil
 }
 
 // Sets the feed update cache value for the given feed
@@ -371,9 +456,31 @@ func (h *Handler) set(feed *Feed, feedUpdate *CacheEntry) error {
 	if err != nil {
 		return err
 	}
-	h.cacheLock.Lock()
-	defer h.cacheLock.Unlock()
-	h.cache[mapKey] = feedUpdate
+	h.cache.Add(strconv.FormatUint(mapKey, 10), feedUpdate, feedUpdate.size())
+	h.hints.put(feed, feedUpdate)
+	return nil
+}
+
+// verifyChunk checks the signature of the chunk of an update of a feed of
+// owner, and that the chunk is at the address of its id and owner.
+func verifyChunk(chunk swarm.Chunk, owner utils.Address) error {
+	// soc.FromChunk writes into the data it is given, which may be shared
+	// with the block store or its cache
+	data := append([]byte(nil), chunk.Data()...)
+	s, err := soc.FromChunk(swarm.NewChunk(chunk.Address(), data))
+	if err != nil {
+		return NewErrorf(ErrInvalidSignature, "invalid feed update %s: %v", chunk.Address(), err)
+	}
+	if !bytes.Equal(s.OwnerAddress(), owner[:]) {
+		return NewErrorf(ErrInvalidSignature, "feed update %s signed by %x, not by the owner %x", chunk.Address(), s.OwnerAddress(), owner[:])
+	}
+	addr, err := s.Address()
+	if err != nil {
+		return NewErrorf(ErrInvalidSignature, "invalid feed update %s: %v", chunk.Address(), err)
+	}
+	if !addr.Equal(chunk.Address()) {
+		return NewErrorf(ErrInvalidSignature, "feed update %s found at %s", addr, chunk.Address())
+	}
 	return nil
 }
 
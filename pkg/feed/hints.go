@@ -0,0 +1,155 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	hintsDirPerm  = 0700
+	hintsFilePerm = 0600
+	hintLength    = 1 + lookup.EpochLength + 8
+)
+
+// Hints keeps where the latest update of every feed was last found, and so
+// the kind of the feed, so that a lookup after a restart starts from there
+// instead of from no clue. The
+// hint of a feed is a small file named by the user and the topic under the
+// directory of the hints. A hint is only a starting point: a stale one costs
+// the reads it takes to find it wrong, never a wrong answer.
+//
+// Hints are safe for concurrent use and can be shared by many handlers. A nil
+// *Hints keeps nothing.
+type Hints struct {
+	dir    string
+	hints  map[string]hint
+	mu     sync.Mutex
+	logger logging.Logger
+}
+
+type hint struct {
+	kind  Kind
+	epoch lookup.Epoch // of an epoch feed
+	index uint64       // of a sequence feed
+}
+
+// NewHints opens the hints kept in dir, creating it if needed.
+func NewHints(dir string, logger logging.Logger) (*Hints, error) {
+	err := os.MkdirAll(dir, hintsDirPerm)
+	if err != nil {
+		return nil, err
+	}
+	return &Hints{
+		dir:    dir,
+		hints:  make(map[string]hint),
+		logger: logger,
+	}, nil
+}
+
+// get returns the hint of a feed, if there is one.
+func (h *Hints) get(feed *Feed) (hint, bool) {
+	if h == nil {
+		return hint{}, false
+	}
+	path := h.path(feed)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ht, ok := h.hints[path]; ok {
+		return ht, true
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return hint{}, false
+	}
+	ht, ok := unmarshalHint(data)
+	if !ok {
+		h.logger.Warningf("dropping corrupt feed hint %s", path)
+		_ = os.Remove(path)
+		return hint{}, false
+	}
+	h.hints[path] = ht
+	return ht, true
+}
+
+// put records where the latest update of a feed was found. Failing to keep
+// it is not an error, the next lookup just starts further away.
+func (h *Hints) put(feed *Feed, entry *CacheEntry) {
+	if h == nil {
+		return
+	}
+	ht := hint{kind: entry.kind}
+	if entry.kind == SequenceFeed {
+		ht.index = entry.index
+	} else {
+		ht.epoch = entry.Epoch
+	}
+	path := h.path(feed)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if old, ok := h.hints[path]; ok && old == ht {
+		return
+	}
+	err := writeHint(path, ht)
+	if err != nil {
+		h.logger.Warningf("could not keep feed hint %s: %v", path, err)
+		return
+	}
+	h.hints[path] = ht
+}
+
+func (h *Hints) path(feed *Feed) string {
+	return filepath.Join(h.dir, hex.EncodeToString(feed.User[:]), hex.EncodeToString(feed.Topic[:]))
+}
+
+// writeHint replaces the file of a hint, so that it is never seen half written.
+func writeHint(path string, ht hint) error {
+	err := os.MkdirAll(filepath.Dir(path), hintsDirPerm)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, marshalHint(ht), hintsFilePerm)
+}
+
+func marshalHint(ht hint) []byte {
+	buf := make([]byte, hintLength)
+	buf[0] = byte(ht.kind)
+	epoch, _ := ht.epoch.MarshalBinary()
+	copy(buf[1:], epoch)
+	binary.LittleEndian.PutUint64(buf[1+lookup.EpochLength:], ht.index)
+	return buf
+}
+
+func unmarshalHint(data []byte) (hint, bool) {
+	if len(data) != hintLength || Kind(data[0]) > SequenceFeed {
+		return hint{}, false
+	}
+	ht := hint{kind: Kind(data[0])}
+	if err := ht.epoch.UnmarshalBinary(data[1 : 1+lookup.EpochLength]); err != nil {
+		return hint{}, false
+	}
+	ht.index = binary.LittleEndian.Uint64(data[1+lookup.EpochLength:])
+	return ht, true
+}
@@ -0,0 +1,170 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestHints(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+	client := fault.New(mock.NewMockBeeClient(), 0)
+
+	dir, err := ioutil.TempDir("", "hints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// restarted returns a feed API as it is after a restart, with nothing
+	// cached but the hints kept in dir
+	restarted := func(t *testing.T) *API {
+		hints, err := NewHints(dir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewWithOptions(accountInfo, client, Options{Hints: hints}, logger)
+	}
+
+	// reads returns the number of chunks read to get the latest update of
+	// the feed and checks that it is the expected one
+	reads := func(t *testing.T, fd *API, topic []byte, expected string) int {
+		before := client.Calls(fault.DownloadChunk)
+		_, data, err := fd.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Fatalf("expected %s, got %s", expected, data)
+		}
+		return client.Calls(fault.DownloadChunk) - before
+	}
+
+	t.Run("epoch-feed-after-restart", func(t *testing.T) {
+		fd := restarted(t)
+		topic := hashString("hints1")
+		_, err := fd.CreateFeed(context.Background(), topic, user, []byte("update 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < 10; i++ {
+			_, err = fd.UpdateFeed(context.Background(), topic, user, []byte(fmt.Sprintf("update %d", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		reads(t, fd, topic, "update 9")
+
+		withoutHints := reads(t, New(accountInfo, client, logger), topic, "update 9")
+		withHints := reads(t, restarted(t), topic, "update 9")
+		if withHints >= withoutHints {
+			t.Fatalf("%d reads with hints, %d without", withHints, withoutHints)
+		}
+	})
+
+	t.Run("sequence-feed-after-restart", func(t *testing.T) {
+		fd := restarted(t)
+		topic := hashString("hints2")
+		_, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("update 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < 100; i++ {
+			_, err = fd.UpdateFeed(context.Background(), topic, user, []byte(fmt.Sprintf("update %d", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if n := reads(t, restarted(t), topic, "update 99"); n != 2 {
+			t.Fatalf("%d reads to find the hinted update", n)
+		}
+	})
+
+	t.Run("stale-hints", func(t *testing.T) {
+		fd := restarted(t)
+		epochTopic := hashString("hints3")
+		sequenceTopic := hashString("hints4")
+		_, err := fd.CreateFeed(context.Background(), epochTopic, user, []byte("epoch"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.CreateSequenceFeed(context.Background(), sequenceTopic, user, []byte("sequence"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// hints of updates which were never written
+		hints, err := NewHints(dir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		epochFeed := &Feed{User: user}
+		copy(epochFeed.Topic[:], epochTopic)
+		stale := &CacheEntry{}
+		stale.Epoch = lookup.Epoch{Time: uint64(time.Now().Unix()), Level: 0}
+		hints.put(epochFeed, stale)
+		sequenceFeed := &Feed{User: user}
+		copy(sequenceFeed.Topic[:], sequenceTopic)
+		hints.put(sequenceFeed, &CacheEntry{kind: SequenceFeed, index: 1000})
+
+		reads(t, restarted(t), epochTopic, "epoch")
+		reads(t, restarted(t), sequenceTopic, "sequence")
+	})
+
+	t.Run("corrupt-hint", func(t *testing.T) {
+		fd := restarted(t)
+		topic := hashString("hints5")
+		_, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hints, err := NewHints(dir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &Feed{User: user}
+		copy(f.Topic[:], topic)
+		err = ioutil.WriteFile(hints.path(f), []byte("not a hint"), hintsFilePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reads(t, NewWithOptions(accountInfo, client, Options{Hints: hints}, logger), topic, "data")
+		if _, err := os.Stat(hints.path(f)); err != nil {
+			t.Fatalf("hint not written again after the lookup: %v", err)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// FeedUpdate is an update of a feed as found in its history.
+type FeedUpdate struct {
+	Address []byte       // address of the chunk of the update
+	Kind    Kind         // kind of the feed
+	Epoch   lookup.Epoch // epoch of the update, Time being when it was written
+	Index   uint64       // index of the update in a sequence feed
+	Data    []byte       // data of the update
+
+	// PayloadReference is the reference of the blob holding the data, nil
+	// if the data is in the update itself
+	PayloadReference []byte
+}
+
+// GetFeedDataAt returns the address and the data of the update of a feed
+// which was the latest at the given time, in seconds since the Unix epoch.
+// The updates are walked back from the latest one, so reading far in the past
+// of a busy feed takes a chunk read per update in between.
+func (a *API) GetFeedDataAt(ctx context.Context, topic []byte, user utils.Address, at uint64) ([]byte, []byte, error) {
+	entry, err := a.getLatest(ctx, topic, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		header, _ := splitPayload(entry.data)
+		if header == nil {
+			// written before update times were recorded, so all that is left
+			// is what the epochs tell
+			return a.getFeedDataAtEpoch(ctx, &entry.Feed, at)
+		}
+		if header.time <= at {
+			data, err := a.fromPayload(ctx, entry.data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return entry.lastKey, data, nil
+		}
+		entry, err = a.previousUpdate(ctx, entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		if entry == nil {
+			return nil, nil, NewError(ErrNotFound, "no feed updates found at that time")
+		}
+	}
+}
+
+// getFeedDataAtEpoch looks up the update of a feed in the epoch holding the
+// given time. Epochs are time slots, so this can return an update written
+// later in the same slot.
+func (a *API) getFeedDataAtEpoch(ctx context.Context, feed *Feed, at uint64) ([]byte, []byte, error) {
+	if at == 0 {
+		return nil, nil, NewError(ErrNotFound, "no feed updates found at that time")
+	}
+	entry, err := a.handler.Lookup(ctx, NewQuery(feed, at, lookup.NoClue))
+	if err != nil {
+		return nil, nil, err
+	}
+	if header, _ := splitPayload(entry.data); header != nil && header.time > at {
+		return nil, nil, NewError(ErrNotFound, "no feed updates found at that time")
+	}
+	data, err := a.fromPayload(ctx, entry.data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entry.lastKey, data, nil
+}
+
+// GetFeedHistory returns the updates of a feed, the latest first. Sequence
+// feeds number their updates and every update of an epoch feed records the
+// one before it, so the history is exact. The updates written
+// before that was recorded are found from their epochs, which tell only the
+// time slot they are in: their Time is the start of the slot and updates
+// overwritten in a slot are missed. The history ends early if the store no
+// longer has an update.
+func (a *API) GetFeedHistory(ctx context.Context, topic []byte, user utils.Address) ([]*FeedUpdate, error) {
+	updates, _, err := a.GetFeedHistoryAfter(ctx, topic, user, nil)
+	return updates, err
+}
+
+// GetFeedHistoryAfter is like GetFeedHistory, but stops at the update at the
+// address after, leaving it and the ones before it out, and tells if it did.
+// It is the whole history if after is nil or not an update of the feed.
+func (a *API) GetFeedHistoryAfter(ctx context.Context, topic []byte, user utils.Address, after []byte) ([]*FeedUpdate, bool, error) {
+	entry, err := a.getLatest(ctx, topic, user)
+	if err != nil {
+		return nil, false, err
+	}
+	var updates []*FeedUpdate
+	for entry != nil {
+		if after != nil && bytes.Equal(entry.lastKey, after) {
+			return updates, true, nil
+		}
+		update, err := a.toFeedUpdate(ctx, entry)
+		if err != nil {
+			return nil, false, err
+		}
+		updates = append(updates, update)
+
+		entry, err = a.previousUpdate(ctx, entry)
+		if err != nil {
+			if ferr, ok := err.(*Error); ok && ferr.code == ErrNotFound {
+				break
+			}
+			return nil, false, err
+		}
+	}
+	return updates, false, nil
+}
+
+// previousUpdate returns the update written before the given one, nil if it is
+// the first update.
+func (a *API) previousUpdate(ctx context.Context, entry *CacheEntry) (*CacheEntry, error) {
+	if entry.kind == SequenceFeed {
+		if entry.index == 0 {
+			return nil, nil
+		}
+		return a.handler.getSequenceUpdate(ctx, &entry.Feed, entry.index-1)
+	}
+	header, _ := splitPayload(entry.data)
+	if header != nil {
+		if header.prev == nil {
+			return nil, nil
+		}
+		return a.handler.getUpdate(ctx, &entry.Feed, *header.prev)
+	}
+
+	// the update before is in an earlier time slot
+	base := entry.Epoch.Base()
+	if base == 0 {
+		return nil, nil
+	}
+	prev, err := a.handler.Lookup(ctx, NewQuery(&entry.Feed, base-1, lookup.NoClue))
+	if err != nil {
+		return nil, err
+	}
+	if prev.Epoch.Base() >= base {
+		return nil, nil
+	}
+	return prev, nil
+}
+
+func (a *API) toFeedUpdate(ctx context.Context, entry *CacheEntry) (*FeedUpdate, error) {
+	data, err := a.fromPayload(ctx, entry.data)
+	if err != nil {
+		return nil, err
+	}
+	update := &FeedUpdate{
+		Address: entry.lastKey,
+		Kind:    entry.kind,
+		Index:   entry.index,
+		Epoch: lookup.Epoch{
+			Time:  entry.Epoch.Base(),
+			Level: entry.Epoch.Level,
+		},
+		Data:             data,
+		PayloadReference: payloadReference(entry.data),
+	}
+	if header, _ := splitPayload(entry.data); header != nil {
+		update.Epoch.Time = header.time
+	}
+	return update, nil
+}
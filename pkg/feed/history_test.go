@@ -0,0 +1,207 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/content"
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/soc"
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestFeedHistory(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+	client := mock.NewMockBeeClient()
+
+	t.Run("list-updates", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("history1")
+		versions := [][]byte{[]byte("v0"), []byte("v1"), make([]byte, 2*utils.MaxChunkLength)}
+		_, err := fd.CreateFeed(context.Background(), topic, user, versions[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, data := range versions[1:] {
+			_, err = fd.UpdateFeed(context.Background(), topic, user, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != len(versions) {
+			t.Fatalf("expected %d updates, got %d", len(versions), len(updates))
+		}
+		for i, update := range updates {
+			data := versions[len(versions)-1-i]
+			if !bytes.Equal(update.Data, data) {
+				t.Fatalf("update %d: data mismatch", i)
+			}
+			if (update.PayloadReference != nil) != (len(data) > utils.MaxChunkLength) {
+				t.Fatalf("update %d: unexpected payload reference", i)
+			}
+			if i > 0 && update.Epoch.Time > updates[i-1].Epoch.Time {
+				t.Fatalf("update %d: written after the update following it", i)
+			}
+		}
+		if updates[len(updates)-1].Epoch.Level != 31 {
+			t.Fatalf("first update not at the top level")
+		}
+
+		// the updates after the first two
+		after, found, err := fd.GetFeedHistoryAfter(context.Background(), topic, user, updates[1].Address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || len(after) != 1 || !bytes.Equal(after[0].Address, updates[0].Address) {
+			t.Fatalf("expected only the latest update after the second one, got %d updates", len(after))
+		}
+	})
+
+	t.Run("read-at-time", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("history2")
+		_, err := fd.CreateFeed(context.Background(), topic, user, []byte("old"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		created := updates[0].Epoch.Time
+		time.Sleep(time.Second)
+		_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("new"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, data, err := fd.GetFeedDataAt(context.Background(), topic, user, created)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "old" {
+			t.Fatalf("expected old data, got %s", data)
+		}
+		_, data, err = fd.GetFeedDataAt(context.Background(), topic, user, uint64(time.Now().Unix()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "new" {
+			t.Fatalf("expected new data, got %s", data)
+		}
+		_, _, err = fd.GetFeedDataAt(context.Background(), topic, user, created-1)
+		if err == nil {
+			t.Fatalf("found data before the feed was created")
+		}
+	})
+
+	t.Run("updates-without-header", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("history3")
+		now := uint64(time.Now().Unix())
+		epoch := lookup.Epoch{Time: now, Level: 31}
+		createUpdateWithoutHeader(t, fd, topic, user, epoch, []byte("legacy"))
+
+		_, data, err := fd.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "legacy" {
+			t.Fatalf("expected legacy data, got %s", data)
+		}
+		_, data, err = fd.GetFeedDataAt(context.Background(), topic, user, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "legacy" {
+			t.Fatalf("expected legacy data at %d, got %s", now, data)
+		}
+
+		_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("current"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != 2 || string(updates[0].Data) != "current" || string(updates[1].Data) != "legacy" {
+			t.Fatalf("unexpected history %v", updates)
+		}
+		if updates[1].Epoch.Time != epoch.Base() || updates[1].Epoch.Level != epoch.Level {
+			t.Fatalf("unexpected epoch %v of the update without header", updates[1].Epoch)
+		}
+	})
+}
+
+// createUpdateWithoutHeader stores an update the way it was before updates
+// recorded their time and the update before them.
+func createUpdateWithoutHeader(t *testing.T, fd *API, topic []byte, user utils.Address, epoch lookup.Epoch, data []byte) {
+	t.Helper()
+	var req Request
+	copy(req.ID.Topic[:], topic)
+	req.ID.User = user
+	req.Epoch = epoch
+	req.data = data
+
+	id, err := fd.handler.getId(req.Topic, req.Time, req.Level)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadId, err := fd.handler.getPayloadId(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := content.NewChunk(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch, err := soc.NewChunk(id, ch, crypto.NewDefaultSigner(fd.accountInfo.GetPrivateKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.idAddr = sch.Address()
+	_, err = fd.handler.toChunkContent(&req, id, payloadId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fd.handler.update(context.Background(), &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
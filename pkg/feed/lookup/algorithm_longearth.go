@@ -38,6 +38,7 @@ func LongEarthAlgorithm(ctx context.Context, now uint64, hint Epoch, read ReadFu
 
 	errc := make(chan struct{}) // errc will help as an error shortcut signal
 	var gerr error              // in case of error, this variable will be set
+	var errOnce sync.Once       // several reads can fail, only the first one is kept
 
 	var step stepFunc // For efficiency, the algorithm step is defined as a closure
 	step = func(ctxS context.Context, t uint64, last Epoch) interface{} {
@@ -97,8 +98,10 @@ func LongEarthAlgorithm(ctx context.Context, now uint64, hint Epoch, read ReadFu
 				//cancelA() // cancel this also for faster eject
 			}
 			if err != nil && !errors.Is(err, context.Canceled) {
-				gerr = err
-				close(errc)
+				errOnce.Do(func() {
+					gerr = err
+					close(errc)
+				})
 			}
 		}()
 
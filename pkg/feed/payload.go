@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// dfsPayloadPrefix starts every prefix the payloads written by dfs are marked
+// with. The payload of a bee feed starts with the big endian time of the
+// update instead, which would have to be more than a billion years from now
+// to start the same.
+var dfsPayloadPrefix = []byte("\x00dfs:")
+
+// updateHeaderPrefix marks the payload of an update which records when it was
+// written and the epoch of the update before it. The epoch an update is found
+// at only tells the time slot it is stored in, so this is what lets the
+// history of a feed be walked back exactly. Updates written before have no
+// header and their payload is the data itself.
+var updateHeaderPrefix = []byte("\x00dfs:update:")
+
+// updateHeaderLength is the length of the prefix, the time and the epoch id
+// of the previous update, which is all zeros for the first update.
+var updateHeaderLength = len(updateHeaderPrefix) + 8 + lookup.EpochLength
+
+// blobPayloadPrefix marks the payload of an update which does not fit in a
+// chunk. The payload itself is stored in a blob and the update holds only
+// this prefix and the reference of the blob. Everything else is inline, as
+// the feeds written before always were. A small payload which happens to
+// start with dfsPayloadPrefix goes in a blob too, so it is never confused
+// with this or any other of the markers.
+var blobPayloadPrefix = []byte("\x00dfs:blob:")
+
+// movedPayloadPrefix is all there is after the header of the last update of an
+// epoch feed which was migrated to a sequence feed. The readers which know the
+// feed as an epoch feed find it and look for the sequence feed instead.
+var movedPayloadPrefix = []byte("\x00dfs:moved:")
+
+// updateHeader is the header of the payload of an update.
+type updateHeader struct {
+	time uint64        // when the update was written
+	prev *lookup.Epoch // epoch of the update before, nil for the first one
+}
+
+func (h *updateHeader) marshal() []byte {
+	buf := make([]byte, updateHeaderLength)
+	cursor := copy(buf, updateHeaderPrefix)
+	binary.LittleEndian.PutUint64(buf[cursor:cursor+8], h.time)
+	cursor += 8
+	if h.prev != nil {
+		id := h.prev.ID()
+		copy(buf[cursor:], id[:])
+	}
+	return buf
+}
+
+// splitPayload returns the header of an update and what is stored after it.
+// The header is nil for the updates written without one.
+func splitPayload(payload []byte) (*updateHeader, []byte) {
+	if len(payload) < updateHeaderLength || !bytes.HasPrefix(payload, updateHeaderPrefix) {
+		return nil, payload
+	}
+	cursor := len(updateHeaderPrefix)
+	header := &updateHeader{
+		time: binary.LittleEndian.Uint64(payload[cursor : cursor+8]),
+	}
+	cursor += 8
+	var zero lookup.EpochID
+	if !bytes.Equal(payload[cursor:updateHeaderLength], zero[:]) {
+		prev := &lookup.Epoch{}
+		if err := prev.UnmarshalBinary(payload[cursor:updateHeaderLength]); err == nil {
+			header.prev = prev
+		}
+	}
+	return header, payload[updateHeaderLength:]
+}
+
+// toPayload returns what is stored in the chunk of an update of data.
+func (a *API) toPayload(ctx context.Context, header *updateHeader, data []byte) ([]byte, error) {
+	payload := header.marshal()
+	if len(payload)+len(data) <= utils.MaxChunkLength && !bytes.HasPrefix(data, dfsPayloadPrefix) {
+		return append(payload, data...), nil
+	}
+	ref, err := a.handler.client.UploadBlob(ctx, data, true, false)
+	if err != nil {
+		return nil, err
+	}
+	payload = append(payload, blobPayloadPrefix...)
+	return append(payload, ref...), nil
+}
+
+// fromPayload returns the data of an update from what is stored in its chunk.
+func (a *API) fromPayload(ctx context.Context, payload []byte) ([]byte, error) {
+	if isMovedPayload(payload) {
+		return nil, NewError(ErrNotFound, "feed moved to a sequence feed")
+	}
+	ref := payloadReference(payload)
+	if ref == nil {
+		_, data := splitPayload(payload)
+		return data, nil
+	}
+	data, respCode, err := a.handler.client.DownloadBlob(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if respCode != http.StatusOK {
+		return nil, fmt.Errorf("could not load feed payload %s", utils.NewReference(ref).String())
+	}
+	return data, nil
+}
+
+// isMovedPayload tells if payload is of the update which ends a migrated
+// epoch feed.
+func isMovedPayload(payload []byte) bool {
+	header, body := splitPayload(payload)
+	return header != nil && bytes.Equal(body, movedPayloadPrefix)
+}
+
+// payloadReference returns the reference of the blob holding the data of
+// an update, or nil if the data is inline.
+func payloadReference(payload []byte) []byte {
+	_, body := splitPayload(payload)
+	if !bytes.HasPrefix(body, blobPayloadPrefix) {
+		return nil
+	}
+	return body[len(blobPayloadPrefix):]
+}
@@ -0,0 +1,287 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"context"
+	"encoding/binary"
+
+	beecrypto "github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/jmozah/intOS-dfs/pkg/feed/lookup"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// Kind tells how the updates of a feed are addressed.
+type Kind uint8
+
+const (
+	// EpochFeed stores every update in the time slot it is written in, so
+	// finding the latest update takes a walk down the slots.
+	EpochFeed Kind = iota
+
+	// SequenceFeed numbers its updates from zero, so the latest update is
+	// found by probing forward from the last index known. Meant for what is
+	// updated often, like directories.
+	SequenceFeed
+)
+
+// CreateSequenceFeed creates a feed whose updates are numbered, in the format
+// the API is set to. Both kinds of feeds are read and updated the same way,
+// the kind and the format are found from the feed. Creating a sequence feed
+// which exists already adds an update to it, in the format of the feed, so
+// that the old updates never hide the new one.
+func (a *API) CreateSequenceFeed(ctx context.Context, topic []byte, user utils.Address, data []byte) ([]byte, error) {
+	if len(topic) != TopicLength {
+		return nil, ErrInvalidTopicSize
+	}
+
+	f := new(Feed)
+	f.User = user
+	copy(f.Topic[:], topic)
+
+	var index uint64
+	format := a.format
+	latest, err := a.handler.lookupSequence(ctx, f)
+	if err == nil {
+		index = latest.index + 1
+		format = latest.format
+	} else if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+		return nil, err
+	}
+	return a.updateSequence(ctx, f, index, data, format)
+}
+
+// updateSequence writes the update at index of a sequence feed, in the given
+// format.
+func (a *API) updateSequence(ctx context.Context, f *Feed, index uint64, data []byte, format Format) ([]byte, error) {
+	req := &Request{
+		kind:   SequenceFeed,
+		format: format,
+		index:  index,
+	}
+	req.Feed = *f
+	req.Epoch.Time = a.handler.now()
+
+	var err error
+	if format == BeeFormat {
+		data, err = a.toBeePayload(ctx, req.Time, data)
+	} else {
+		data, err = a.toPayload(ctx, &updateHeader{time: req.Time}, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	req.data = data
+
+	id, err := getSequenceId(f.Topic, index)
+	if err != nil {
+		return nil, err
+	}
+	address, err := a.signAndUpdate(ctx, req, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the next lookup starts from here. the cache holds the payload the way
+	// it is read back
+	if format == BeeFormat {
+		req.data, _ = fromBeePayload(req.data)
+	}
+	err = a.handler.set(f, newCacheEntry(req))
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// LookupLatest finds the latest update of a feed of either kind. A feed is
+// looked up as the kind it was last found to be, and the other kind is tried
+// only if that finds nothing. A sequence feed always has an update at index 0,
+// so telling the kind of a feed not seen before takes one read. An epoch feed
+// which was migrated ends with an update telling so, which sends the lookup to
+// the sequence feed it became.
+func (h *Handler) LookupLatest(ctx context.Context, feed *Feed) (*CacheEntry, error) {
+	entry, err := h.get(feed)
+	if err != nil {
+		return nil, err
+	}
+	kind, known := h.getKind(feed)
+	if entry != nil {
+		kind, known = entry.kind, true
+	}
+	probed := !known || kind == SequenceFeed
+	if probed {
+		latest, err := h.lookupSequence(ctx, feed)
+		if err == nil {
+			h.setKind(feed, SequenceFeed)
+			return latest, nil
+		}
+		if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+			return nil, err
+		}
+	}
+	latest, err := h.Lookup(ctx, NewQueryLatest(feed, lookup.NoClue))
+	if err == nil && !isMovedPayload(latest.data) {
+		h.setKind(feed, EpochFeed)
+		return latest, nil
+	}
+	if err != nil {
+		if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+			return nil, err
+		}
+	}
+	if probed {
+		return nil, NewError(ErrNotFound, "no feed updates found")
+	}
+
+	// the topic is of a sequence feed now
+	latest, err = h.lookupSequence(ctx, feed)
+	if err != nil {
+		return nil, err
+	}
+	h.setKind(feed, SequenceFeed)
+	return latest, nil
+}
+
+// getKind returns the kind a feed was last found to be, by this handler or,
+// before a restart, as kept in the hints.
+func (h *Handler) getKind(feed *Feed) (Kind, bool) {
+	mapKey, err := feed.mapKey()
+	if err != nil {
+		return EpochFeed, false
+	}
+	h.kindsMu.Lock()
+	kind, ok := h.kinds[mapKey]
+	h.kindsMu.Unlock()
+	if ok {
+		return kind, true
+	}
+	if ht, ok := h.hints.get(feed); ok {
+		return ht.kind, true
+	}
+	return EpochFeed, false
+}
+
+// setKind records the kind a feed was found to be. The hints keep it along
+// with the update found.
+func (h *Handler) setKind(feed *Feed, kind Kind) {
+	mapKey, err := feed.mapKey()
+	if err != nil {
+		return
+	}
+	h.kindsMu.Lock()
+	defer h.kindsMu.Unlock()
+	h.kinds[mapKey] = kind
+}
+
+// lookupSequence finds the latest update of a sequence feed. The indexes
+// after the last one known are probed at doubling distances from it until one
+// is missing, and the latest update is then searched for in between.
+func (h *Handler) lookupSequence(ctx context.Context, feed *Feed) (*CacheEntry, error) {
+	last, err := h.get(feed)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.kind != SequenceFeed {
+		last = nil
+	}
+	if ht, ok := h.hints.get(feed); last == nil && ok && ht.kind == SequenceFeed {
+		// a stale hint is no worse than no hint
+		last, _ = h.getSequenceUpdate(ctx, feed, ht.index)
+	}
+	if last == nil {
+		last, err = h.getSequenceUpdate(ctx, feed, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	base := last.index
+	lo, hi := base, uint64(0)
+	for step := uint64(1); hi == 0; step *= 2 {
+		entry, err := h.getSequenceUpdate(ctx, feed, base+step)
+		if err != nil {
+			if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+				return nil, err
+			}
+			hi = base + step
+			continue
+		}
+		last = entry
+		lo = base + step
+	}
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		entry, err := h.getSequenceUpdate(ctx, feed, mid)
+		if err != nil {
+			if ferr, ok := err.(*Error); !ok || ferr.code != ErrNotFound {
+				return nil, err
+			}
+			hi = mid
+			continue
+		}
+		last = entry
+		lo = mid
+	}
+
+	err = h.set(feed, last)
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// getSequenceUpdate reads the update at index of a sequence feed.
+func (h *Handler) getSequenceUpdate(ctx context.Context, feed *Feed, index uint64) (*CacheEntry, error) {
+	id, err := getSequenceId(feed.Topic, index)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := toSignDigest(id, feed.User[:])
+	if err != nil {
+		return nil, err
+	}
+	data, err := h.client.DownloadChunk(ctx, addr)
+	if err != nil {
+		if isChunkNotFound(err) {
+			return nil, NewError(ErrNotFound, "feed update not found")
+		}
+		return nil, err
+	}
+	request := Request{
+		kind:  SequenceFeed,
+		index: index,
+	}
+	err = h.fromChunk(swarm.NewChunk(swarm.NewAddress(addr), data), &request, &Query{Feed: *feed}, &ID{Feed: *feed})
+	if err != nil {
+		return nil, err
+	}
+	if payload, ok := fromBeePayload(request.data); ok {
+		request.format = BeeFormat
+		request.data = payload
+	}
+	return newCacheEntry(&request), nil
+}
+
+// getSequenceId returns the id of the update at index of a sequence feed,
+// hash(topic, index), the same as the sequence feeds of bee.
+func getSequenceId(topic Topic, index uint64) ([]byte, error) {
+	buf := make([]byte, TopicLength+8)
+	copy(buf, topic[:])
+	binary.BigEndian.PutUint64(buf[TopicLength:], index)
+	return beecrypto.LegacyKeccak256(buf)
+}
@@ -0,0 +1,298 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestSequenceFeed(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	accountInfo := acc.GetUserAccountInfo()
+	client := fault.New(mock.NewMockBeeClient(), 0)
+
+	t.Run("create-and-update", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("sequence1")
+		_, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("update 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i < 100; i++ {
+			data := []byte(fmt.Sprintf("update %d", i))
+			addr, err := fd.UpdateFeed(context.Background(), topic, user, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdAddr, rcvdData, err := fd.GetFeedData(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(addr, rcvdAddr) || !bytes.Equal(data, rcvdData) {
+				t.Fatalf("update %d: got %s", i, rcvdData)
+			}
+		}
+
+		// a reader which knows nothing of the feed
+		reads := client.Calls(fault.DownloadChunk)
+		_, data, err := New(accountInfo, client, logger).GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "update 99" {
+			t.Fatalf("expected the latest update, got %s", data)
+		}
+		if n := client.Calls(fault.DownloadChunk) - reads; n > 16 {
+			t.Fatalf("%d reads to find the latest of 100 updates", n)
+		}
+
+		// a reader which knows the update before the latest
+		reader := New(accountInfo, client, logger)
+		_, _, err = reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("update 100"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reads = client.Calls(fault.DownloadChunk)
+		_, data, err = reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "update 100" {
+			t.Fatalf("expected the latest update, got %s", data)
+		}
+		if n := client.Calls(fault.DownloadChunk) - reads; n != 2 {
+			t.Fatalf("%d reads to find the update after the last known", n)
+		}
+
+		updates, err := fd.GetFeedHistory(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) != 101 {
+			t.Fatalf("expected 101 updates, got %d", len(updates))
+		}
+		for i, update := range updates {
+			index := uint64(len(updates) - 1 - i)
+			if update.Kind != SequenceFeed || update.Index != index || string(update.Data) != fmt.Sprintf("update %d", index) {
+				t.Fatalf("unexpected update %d: %s", index, update.Data)
+			}
+		}
+	})
+
+	t.Run("both-kinds", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		epochTopic := hashString("sequence2")
+		sequenceTopic := hashString("sequence3")
+		_, err := fd.CreateFeed(context.Background(), epochTopic, user, []byte("epoch"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.CreateSequenceFeed(context.Background(), sequenceTopic, user, []byte("sequence"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, topic := range [][]byte{epochTopic, sequenceTopic} {
+			_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("updated"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		reader := New(accountInfo, client, logger)
+		for topic, kind := range map[string]Kind{string(epochTopic): EpochFeed, string(sequenceTopic): SequenceFeed} {
+			_, data, err := reader.GetFeedData(context.Background(), []byte(topic), user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "updated" {
+				t.Fatalf("expected updated data, got %s", data)
+			}
+			updates, err := reader.GetFeedHistory(context.Background(), []byte(topic), user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(updates) != 2 || updates[0].Kind != kind || updates[1].Kind != kind {
+				t.Fatalf("feed of kind %d changed kind", kind)
+			}
+		}
+	})
+
+	t.Run("create-again", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("sequence4")
+		_, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fd.UpdateFeed(context.Background(), topic, user, []byte("second"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(accountInfo, client, logger).CreateSequenceFeed(context.Background(), topic, user, []byte("created again"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, data, err := New(accountInfo, client, logger).GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "created again" {
+			t.Fatalf("older update hides the new one: %s", data)
+		}
+	})
+
+	t.Run("kind-kept", func(t *testing.T) {
+		client := fault.New(mock.NewMockBeeClient(), 0)
+		clk := clock.NewMock(time.Unix(1600000000, 0))
+		dir, err := ioutil.TempDir("", "kinds")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		hints, err := NewHints(dir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd := NewWithOptions(accountInfo, client, Options{Clock: clk}, logger)
+		topic := hashString("sequence5")
+		_, err = fd.CreateFeed(context.Background(), topic, user, []byte("epoch"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := &Feed{User: user}
+		copy(f.Topic[:], topic)
+		key, err := f.mapKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := getSequenceId(f.Topic, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		probe, err := toSignDigest(id, user[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		reader := NewWithOptions(accountInfo, client, Options{Clock: clk, Hints: hints}, logger)
+		_, _, err = reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// once the update is out of the cache, and however long after, the
+		// feed is still known to be an epoch feed, so index 0 of a sequence
+		// feed is not probed
+		client.FailAddress(probe)
+		clk.Add(time.Hour)
+		reader.handler.cache.Remove(strconv.FormatUint(key, 10))
+		_, data, err := reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatalf("sequence feed probed for a known epoch feed: %v", err)
+		}
+		if string(data) != "epoch" {
+			t.Fatalf("expected the latest update, got %s", data)
+		}
+
+		// the hints keep the kind across a restart
+		restarted := NewWithOptions(accountInfo, client, Options{Clock: clk, Hints: hints}, logger)
+		_, _, err = restarted.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatalf("sequence feed probed for an epoch feed kept in the hints: %v", err)
+		}
+
+		// without them the kind is not known, and a probe which fails is an
+		// error
+		_, _, err = NewWithOptions(accountInfo, client, Options{Clock: clk}, logger).GetFeedData(context.Background(), topic, user)
+		if !errors.Is(err, fault.ErrInjected) {
+			t.Fatalf("expected %v, got %v", fault.ErrInjected, err)
+		}
+	})
+
+	t.Run("kind-changed", func(t *testing.T) {
+		fd := New(accountInfo, client, logger)
+		topic := hashString("sequence7")
+		_, err := fd.CreateFeed(context.Background(), topic, user, []byte("epoch"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := New(accountInfo, client, logger)
+		_, _, err = reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a feed known to be of one kind is found when it is of the other
+		f := &Feed{User: user}
+		copy(f.Topic[:], topic)
+		reader.handler.setKind(f, SequenceFeed)
+		key, err := f.mapKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.handler.cache.Remove(strconv.FormatUint(key, 10))
+		_, data, err := reader.GetFeedData(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "epoch" {
+			t.Fatalf("expected the epoch update, got %s", data)
+		}
+	})
+
+	t.Run("timeout-is-not-not-found", func(t *testing.T) {
+		client := fault.New(mock.NewMockBeeClient(), 0)
+		fd := New(accountInfo, client, logger)
+		topic := hashString("sequence6")
+		_, err := fd.CreateSequenceFeed(context.Background(), topic, user, []byte("sequence"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.SetLatency(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, _, err = New(accountInfo, client, logger).GetFeedData(ctx, topic, user)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestVerifyFeedChunks(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	client := mock.NewMockBeeClient()
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+	other := account.New(logger)
+	_, _, err = other.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUser := other.GetAddress(account.UserAccountIndex)
+
+	kinds := []struct {
+		name   string
+		create func(fd *API, topic []byte, user utils.Address, data []byte) ([]byte, error)
+	}{
+		{"epoch", func(fd *API, topic []byte, user utils.Address, data []byte) ([]byte, error) {
+			return fd.CreateFeed(context.Background(), topic, user, data)
+		}},
+		{"sequence", func(fd *API, topic []byte, user utils.Address, data []byte) ([]byte, error) {
+			return fd.CreateSequenceFeed(context.Background(), topic, user, data)
+		}},
+	}
+	for _, kind := range kinds {
+		kind := kind
+		// tamper creates a feed of the given kind and stores at the address
+		// of its update whatever change makes of the update chunk
+		tamper := func(t *testing.T, name string, change func(addr, data []byte) []byte) []byte {
+			topic := hashString(kind.name + name)
+			addr, err := kind.create(New(acc.GetUserAccountInfo(), client, logger), topic, user, []byte("genuine"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := client.DownloadChunk(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.UploadChunk(context.Background(), swarm.NewChunk(swarm.NewAddress(addr), change(addr, data)), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return topic
+		}
+		expectInvalid := func(t *testing.T, topic []byte) {
+			// read with a new API so that nothing comes from its cache
+			fd := New(acc.GetUserAccountInfo(), client, logger)
+			_, data, err := fd.GetFeedData(context.Background(), topic, user)
+			if err == nil {
+				t.Fatalf("read %q from a tampered feed", data)
+			}
+			ferr, ok := err.(*Error)
+			if !ok || ferr.Code() != ErrInvalidSignature {
+				t.Fatalf("expected an invalid signature error, got %v", err)
+			}
+		}
+
+		t.Run(kind.name+"-genuine", func(t *testing.T) {
+			topic := tamper(t, "genuine", func(addr, data []byte) []byte {
+				return data
+			})
+			fd := New(acc.GetUserAccountInfo(), client, logger)
+			_, data, err := fd.GetFeedData(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "genuine" {
+				t.Fatalf("expected %q, got %q", "genuine", data)
+			}
+		})
+
+		t.Run(kind.name+"-flipped-payload", func(t *testing.T) {
+			topic := tamper(t, "flipped-payload", func(addr, data []byte) []byte {
+				tampered := append([]byte(nil), data...)
+				tampered[len(tampered)-1] ^= 0xff
+				return tampered
+			})
+			expectInvalid(t, topic)
+		})
+
+		t.Run(kind.name+"-flipped-signature", func(t *testing.T) {
+			topic := tamper(t, "flipped-signature", func(addr, data []byte) []byte {
+				tampered := append([]byte(nil), data...)
+				tampered[idLength+1] ^= 0xff
+				return tampered
+			})
+			expectInvalid(t, topic)
+		})
+
+		t.Run(kind.name+"-truncated", func(t *testing.T) {
+			topic := tamper(t, "truncated", func(addr, data []byte) []byte {
+				return data[:idLength+signatureLength]
+			})
+			expectInvalid(t, topic)
+		})
+
+		t.Run(kind.name+"-signed-by-other", func(t *testing.T) {
+			// a valid update of the same topic, but of another user's feed
+			topic := hashString(kind.name + "signed-by-other")
+			addr, err := kind.create(New(other.GetUserAccountInfo(), client, logger), topic, otherUser, []byte("spoofed"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			spoofed, err := client.DownloadChunk(context.Background(), addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tamper(t, "signed-by-other", func(addr, data []byte) []byte {
+				return spoofed
+			})
+			expectInvalid(t, topic)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// DefaultWatchInterval is how often a watcher polls its feeds unless told
+// otherwise.
+const DefaultWatchInterval = 5 * time.Second
+
+// WatchEvent tells that a watched feed has a new latest update.
+type WatchEvent struct {
+	Topic   []byte
+	User    utils.Address
+	Address []byte // address of the new latest update
+	Data    []byte // data of the new latest update
+}
+
+// Watcher polls a set of feeds and sends an event whenever the latest update
+// of one of them changes. Each poll starts from the latest update known of the
+// feed, so it costs a few reads per feed however long the feed is.
+type Watcher struct {
+	fd       *API
+	interval time.Duration
+	events   chan *WatchEvent
+	feeds    map[string]*watchedFeed // hex(user)+hex(topic) -> feed
+	feedsMu  sync.Mutex
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	closed   sync.Once
+}
+
+type watchedFeed struct {
+	feed   Feed
+	latest []byte // address of the latest update seen, nil if there is none
+}
+
+// NewWatcher creates a watcher which reads the feeds through this API every
+// interval, or every DefaultWatchInterval if interval is not positive. It
+// polls until it is closed.
+func (a *API) NewWatcher(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	w := &Watcher{
+		fd:       a,
+		interval: interval,
+		events:   make(chan *WatchEvent, 16),
+		feeds:    make(map[string]*watchedFeed),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.wg.Add(1)
+	go w.run(ctx)
+	return w
+}
+
+// Events returns the channel the events are sent on. It is closed when the
+// watcher is.
+func (w *Watcher) Events() <-chan *WatchEvent {
+	return w.events
+}
+
+// Watch adds a feed to the watched ones. Only the updates made after it is
+// added are told, whether the feed has updates yet or not.
+func (w *Watcher) Watch(ctx context.Context, topic []byte, user utils.Address) error {
+	if len(topic) != TopicLength {
+		return ErrInvalidTopicSize
+	}
+	wf := &watchedFeed{}
+	wf.feed.User = user
+	copy(wf.feed.Topic[:], topic)
+	entry, err := w.latest(ctx, &wf.feed)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		wf.latest = entry.lastKey
+	}
+
+	w.feedsMu.Lock()
+	defer w.feedsMu.Unlock()
+	if _, ok := w.feeds[watchKey(topic, user)]; !ok {
+		w.feeds[watchKey(topic, user)] = wf
+	}
+	return nil
+}
+
+// Unwatch removes a feed from the watched ones.
+func (w *Watcher) Unwatch(topic []byte, user utils.Address) {
+	w.feedsMu.Lock()
+	defer w.feedsMu.Unlock()
+	delete(w.feeds, watchKey(topic, user))
+}
+
+// Close stops the polling and closes the events channel.
+func (w *Watcher) Close() error {
+	w.closed.Do(func() {
+		w.cancel()
+		w.wg.Wait()
+		close(w.events)
+	})
+	return nil
+}
+
+func (w *Watcher) run(ctx context.Context) {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.poll(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll looks up the latest update of every watched feed and sends an event
+// for each one which changed since the last poll.
+func (w *Watcher) poll(ctx context.Context) {
+	w.feedsMu.Lock()
+	feeds := make([]*watchedFeed, 0, len(w.feeds))
+	for _, wf := range w.feeds {
+		feeds = append(feeds, wf)
+	}
+	w.feedsMu.Unlock()
+
+	for _, wf := range feeds {
+		entry, err := w.latest(ctx, &wf.feed)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.fd.logger.Warningf("watch feed %x of %s: %v", wf.feed.Topic[:], wf.feed.User.Hex(), err)
+			continue
+		}
+		if entry == nil || bytes.Equal(entry.lastKey, wf.latest) {
+			continue
+		}
+		data, err := w.fd.fromPayload(ctx, entry.data)
+		if err != nil {
+			w.fd.logger.Warningf("watch feed %x of %s: %v", wf.feed.Topic[:], wf.feed.User.Hex(), err)
+			continue
+		}
+		wf.latest = entry.lastKey
+		event := &WatchEvent{
+			Topic:   append([]byte(nil), wf.feed.Topic[:]...),
+			User:    wf.feed.User,
+			Address: entry.lastKey,
+			Data:    data,
+		}
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// latest returns the latest update of a feed, nil if it has none.
+func (w *Watcher) latest(ctx context.Context, feed *Feed) (*CacheEntry, error) {
+	entry, err := w.fd.handler.LookupLatest(ctx, feed)
+	if err != nil {
+		if ferr, ok := err.(*Error); ok && ferr.code == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func watchKey(topic []byte, user utils.Address) string {
+	return hex.EncodeToString(user[:]) + hex.EncodeToString(topic)
+}
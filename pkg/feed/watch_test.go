@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package feed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestWatcher(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	client := mock.NewMockBeeClient()
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := acc.GetAddress(account.UserAccountIndex)
+
+	// the writer and the watcher do not share a cache, as two devices would not
+	writer := New(acc.GetUserAccountInfo(), client, logger)
+	reader := New(acc.GetUserAccountInfo(), client, logger)
+
+	expectEvent := func(t *testing.T, w *Watcher, topic, addr, data []byte) {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			if !bytes.Equal(event.Topic, topic) || event.User != user {
+				t.Fatalf("event for the wrong feed %x of %s", event.Topic, event.User.Hex())
+			}
+			if !bytes.Equal(event.Address, addr) || !bytes.Equal(event.Data, data) {
+				t.Fatalf("expected update %x with %q, got %x with %q", addr, data, event.Address, event.Data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event for the update")
+		}
+	}
+	expectNoEvent := func(t *testing.T, w *Watcher) {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			t.Fatalf("unexpected event for update %x", event.Address)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	t.Run("updates", func(t *testing.T) {
+		epochTopic := hashString("watch-epoch")
+		_, err := writer.CreateFeed(context.Background(), epochTopic, user, []byte("epoch 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sequenceTopic := hashString("watch-sequence")
+		_, err = writer.CreateSequenceFeed(context.Background(), sequenceTopic, user, []byte("sequence 0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := reader.NewWatcher(10 * time.Millisecond)
+		defer w.Close()
+		for _, topic := range [][]byte{epochTopic, sequenceTopic} {
+			err = w.Watch(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// what was there before watching is not told
+		expectNoEvent(t, w)
+
+		for i := 1; i < 4; i++ {
+			for _, topic := range [][]byte{epochTopic, sequenceTopic} {
+				data := []byte(fmt.Sprintf("%x %d", topic[:4], i))
+				addr, err := writer.UpdateFeed(context.Background(), topic, user, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expectEvent(t, w, topic, addr, data)
+			}
+		}
+		expectNoEvent(t, w)
+	})
+
+	t.Run("first-update", func(t *testing.T) {
+		topic := hashString("watch-new")
+		w := reader.NewWatcher(10 * time.Millisecond)
+		defer w.Close()
+		err := w.Watch(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectNoEvent(t, w)
+
+		addr, err := writer.CreateSequenceFeed(context.Background(), topic, user, []byte("first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectEvent(t, w, topic, addr, []byte("first"))
+	})
+
+	t.Run("unwatch-and-close", func(t *testing.T) {
+		topic := hashString("watch-unwatch")
+		_, err := writer.CreateSequenceFeed(context.Background(), topic, user, []byte("first"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := reader.NewWatcher(10 * time.Millisecond)
+		err = w.Watch(context.Background(), topic, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Unwatch(topic, user)
+		_, err = writer.UpdateFeed(context.Background(), topic, user, []byte("second"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectNoEvent(t, w)
+
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := <-w.Events(); ok {
+			t.Fatal("events not closed")
+		}
+	})
+}
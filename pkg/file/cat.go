@@ -17,21 +17,21 @@ limitations under the License.
 package file
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 )
 
-func (f *File) Cat(fileName string) error {
+func (f *File) Cat(ctx context.Context, fileName string) error {
 	//TODO: need to change the access time
 	meta := f.GetFromFileMap(fileName)
 	if meta == nil {
 		return fmt.Errorf("file not found")
 	}
 
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	fileInodeBytes, _, err := f.getClient().DownloadBlob(ctx, meta.InodeAddress)
 	if err != nil {
 		return err
 	}
@@ -43,24 +43,34 @@ func (f *File) Cat(fileName string) error {
 
 	totalBytes := uint32(0)
 	for _, fb := range fileInode.FileBlocks {
-		stdoutBytes, _, err := f.getClient().DownloadBlob(fb.Address)
+		err = f.catBlock(ctx, fb, meta.Compression, meta.BlockSize)
 		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("could not find file block")
+			return err
 		}
+		totalBytes += fb.Size
+	}
+	return nil
+}
 
-		if uint32(len(stdoutBytes)) != fb.Size {
-			return fmt.Errorf("received less bytes than expected in a block")
-		}
+// catBlock streams a single block to stdout without holding all of it in memory.
+func (f *File) catBlock(ctx context.Context, fb *FileBlock, compression string, blockSize uint32) error {
+	body, _, err := f.getClient().DownloadBlobStream(ctx, fb.Address)
+	if err != nil {
+		return fmt.Errorf("could not find file block")
+	}
+	defer body.Close()
+	br, err := newBlockReader(body, compression, blockSize)
+	if err != nil {
+		return err
+	}
+	defer br.Close()
 
-		buf := bytes.NewBuffer(stdoutBytes)
-		n, err := io.Copy(os.Stdout, buf)
-		if err != nil || uint32(n) != fb.Size {
-			return fmt.Errorf("could not write to stdout")
-		}
-		totalBytes += fb.Size
+	n, err := io.Copy(os.Stdout, br)
+	if err != nil {
+		return fmt.Errorf("could not write to stdout")
+	}
+	if uint32(n) != fb.Size {
+		return fmt.Errorf("received less bytes than expected in a block")
 	}
 	return nil
 }
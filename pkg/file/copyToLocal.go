@@ -18,6 +18,7 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -27,7 +28,7 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (f *File) CopyToFile(podFile string, localDir string) error {
+func (f *File) CopyToFile(ctx context.Context, podFile string, localDir string) error {
 	//TODO: need to change the access time for podFile
 
 	base := filepath.Base(podFile)
@@ -36,7 +37,7 @@ func (f *File) CopyToFile(podFile string, localDir string) error {
 		return fmt.Errorf("file not found in dfs")
 	}
 
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	fileInodeBytes, _, err := f.getClient().DownloadBlob(ctx, meta.InodeAddress)
 	if err != nil {
 		return err
 	}
@@ -54,7 +55,7 @@ func (f *File) CopyToFile(podFile string, localDir string) error {
 
 	totalBytes := uint32(0)
 	for _, fb := range fileInode.FileBlocks {
-		stdoutBytes, _, err := f.getClient().DownloadBlob(fb.Address)
+		stdoutBytes, _, err := f.getClient().DownloadBlob(ctx, fb.Address)
 		if err != nil {
 			if err == io.EOF {
 				break
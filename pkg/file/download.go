@@ -17,15 +17,17 @@ limitations under the License.
 package file
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"strconv"
 
 	"github.com/ethersphere/bee/pkg/swarm"
 )
 
-func (f *File) Download(podFile string) (io.ReadCloser, string, string, error) {
+// Download returns a reader of the file, its reference and its size. The
+// reader can seek and read at any offset of the file.
+func (f *File) Download(ctx context.Context, podFile string) (*Reader, string, string, error) {
 	//TODO: need to change the access time for podFile
 
 	meta := f.GetFromFileMap(podFile)
@@ -33,7 +35,7 @@ func (f *File) Download(podFile string) (io.ReadCloser, string, string, error) {
 		return nil, "", "", fmt.Errorf("file not found in dfs")
 	}
 
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	fileInodeBytes, _, err := f.getClient().DownloadBlob(ctx, meta.InodeAddress)
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -43,7 +45,7 @@ func (f *File) Download(podFile string) (io.ReadCloser, string, string, error) {
 		return nil, "", "", err
 	}
 
-	reader := NewReader(fileInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression)
+	reader := NewReader(ctx, fileInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression)
 	ref := swarm.NewAddress(meta.InodeAddress).String()
 	size := strconv.FormatUint(meta.FileSize, 10)
 	return reader, ref, size, nil
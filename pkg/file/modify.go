@@ -0,0 +1,191 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
+)
+
+// Modification is a change made to a file in place. The file is published
+// under a new metadata reference, which replaces the old one in its directory.
+type Modification struct {
+	Reference    []byte   // the metadata of the file after the change
+	OldReference []byte   // the metadata of the file before the change
+	Released     [][]byte // the blobs which no version of the file uses any more
+}
+
+// WriteAt writes data in to the file at filePath from offset on. The file
+// grows if the data goes past its end, but offset may not be past it, so no
+// blocks of zeros are made up. Only the blocks the data falls in are
+// rewritten.
+func (f *File) WriteAt(ctx context.Context, filePath string, data []byte, offset uint64) (*Modification, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	if offset > meta.FileSize || offset > math.MaxUint64-uint64(len(data)) {
+		return nil, ErrInvalidOffset
+	}
+	size := meta.FileSize
+	if end := offset + uint64(len(data)); end > size {
+		size = end
+	}
+	return f.modify(ctx, filePath, meta, data, offset, size)
+}
+
+// Append adds data to the end of the file at filePath. Only the last block
+// of the file and the ones after it are written.
+func (f *File) Append(ctx context.Context, filePath string, data []byte) (*Modification, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	if meta.FileSize > math.MaxUint64-uint64(len(data)) {
+		return nil, ErrInvalidOffset
+	}
+	return f.modify(ctx, filePath, meta, data, meta.FileSize, meta.FileSize+uint64(len(data)))
+}
+
+// Truncate cuts the file at filePath down to size, dropping what is past it.
+// A file can not be grown by it.
+func (f *File) Truncate(ctx context.Context, filePath string, size uint64) (*Modification, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	if size > meta.FileSize {
+		return nil, ErrInvalidOffset
+	}
+	return f.modify(ctx, filePath, meta, nil, size, size)
+}
+
+// modify makes a file of the given size out of the file of meta, with data
+// written at offset, and stores it. A block of the new file is rewritten
+// only if its content changes, the rest of them are the blocks of the old
+// file. The old file is kept as a version of the new one.
+func (f *File) modify(ctx context.Context, filePath string, meta *m.FileMetaData, data []byte, offset, size uint64) (*Modification, error) {
+	oldInode, err := f.loadInode(ctx, meta.InodeAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := NewReader(ctx, *oldInode, f.getClient(), meta.FileSize, meta.BlockSize, meta.Compression)
+	reader.SetReadAhead(0)
+	defer reader.Close()
+
+	blockSize := uint64(meta.BlockSize)
+	end := offset + uint64(len(data))
+	noOfBlocks := int((size + blockSize - 1) / blockSize)
+	fileINode := FileINode{
+		FileBlocks: make([]*FileBlock, noOfBlocks),
+	}
+
+	var head []byte             // the start of the file, when the first block is rewritten
+	errC := make(chan error, 1) // the first error of the workers
+	worker := make(chan bool, NoOfParallelWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < noOfBlocks; i++ {
+		start := uint64(i) * blockSize
+		blockEnd := minUint64(start+blockSize, size)
+		oldEnd := minUint64(start+blockSize, meta.FileSize)
+		written := len(data) > 0 && offset < blockEnd && end > start
+		if i < len(oldInode.FileBlocks) && blockEnd == oldEnd && !written {
+			fileINode.FileBlocks[i] = oldInode.FileBlocks[i]
+			continue
+		}
+
+		wg.Add(1)
+		worker <- true
+		go func(i int, start, blockEnd uint64, written bool) {
+			defer func() {
+				<-worker
+				wg.Done()
+			}()
+
+			// what is left of the old block, then the data over it
+			block := make([]byte, blockEnd-start)
+			if start < meta.FileSize {
+				n, err := reader.ReadAt(block[:minUint64(blockEnd, meta.FileSize)-start], int64(start))
+				if err != nil && !(err == io.EOF && start+uint64(n) == minUint64(blockEnd, meta.FileSize)) {
+					sendError(errC, err)
+					return
+				}
+			}
+			if written {
+				from := maxUint64(offset, start)
+				copy(block[from-start:], data[from-offset:minUint64(end, blockEnd)-offset])
+			}
+			if i == 0 {
+				head = block
+			}
+
+			fileBlock, err := f.UploadBlock(ctx, block, i, meta.BlockSize, meta.Compression)
+			if err != nil {
+				sendError(errC, err)
+				return
+			}
+			fileINode.FileBlocks[i] = fileBlock
+		}(i, start, blockEnd, written)
+	}
+	wg.Wait()
+	select {
+	case err := <-errC:
+		return nil, err
+	default:
+	}
+
+	newMeta := *meta
+	now := f.fd.Clock().Now().Unix()
+	newMeta.FileSize = size
+	newMeta.AccessTime = now
+	newMeta.ModificationTime = now
+	newMeta.Versions = appendVersion(meta.Versions, meta)
+	if head != nil {
+		newMeta.ContentType = f.GetContentType(bufio.NewReader(bytes.NewReader(head)))
+	}
+	ref, err := f.storeFile(ctx, filePath, &newMeta, &fileINode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Modification{
+		Reference:    ref,
+		OldReference: meta.MetaReference,
+	}, nil
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import "context"
+
+var (
+	// ReadAheadBlocks is the number of blocks after the one being read that
+	// readers fetch ahead of time, unless told otherwise with SetReadAhead.
+	ReadAheadBlocks = 4
+)
+
+// prefetch is a block being fetched ahead of time.
+type prefetch struct {
+	done   chan struct{} // closed once block or err is set
+	block  []byte
+	err    error
+	cancel context.CancelFunc
+}
+
+// SetReadAhead sets the number of blocks fetched ahead of the one Read is in,
+// all at the same time. At most that many blocks are kept in memory besides
+// the one being read. Zero fetches the blocks only when they are read.
+func (r *Reader) SetReadAhead(blocks int) {
+	if blocks < 0 {
+		blocks = 0
+	}
+	r.prefetchMu.Lock()
+	r.readAhead = blocks
+	r.prefetchMu.Unlock()
+}
+
+// prefetch starts fetching the blocks after the one at index, as many as the
+// read ahead tells, and forgets the ones fetched that are not among them any
+// more.
+func (r *Reader) prefetch(index int) {
+	r.prefetchMu.Lock()
+	defer r.prefetchMu.Unlock()
+	if r.ctx.Err() != nil {
+		return
+	}
+
+	last := index + r.readAhead
+	if last >= len(r.fileInode.FileBlocks) {
+		last = len(r.fileInode.FileBlocks) - 1
+	}
+	for i, p := range r.prefetches {
+		if i < index || i > last {
+			p.cancel()
+			delete(r.prefetches, i)
+		}
+	}
+	for i := index + 1; i <= last; i++ {
+		if _, ok := r.prefetches[i]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(r.ctx)
+		p := &prefetch{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		r.prefetches[i] = p
+		r.prefetchWg.Add(1)
+		go func(i int) {
+			defer r.prefetchWg.Done()
+			defer cancel()
+			p.block, p.err = r.fetchBlock(ctx, i)
+			close(p.done)
+		}(i)
+	}
+}
+
+// prefetched returns the block at index if it was fetched ahead, waiting for
+// its fetch to end.
+func (r *Reader) prefetched(index int) ([]byte, bool) {
+	r.prefetchMu.Lock()
+	p, ok := r.prefetches[index]
+	if ok {
+		delete(r.prefetches, index)
+	}
+	r.prefetchMu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	<-p.done
+	if p.err != nil {
+		// fetched again by the reader, to return the error of that
+		return nil, false
+	}
+	return p.block, true
+}
@@ -18,167 +18,233 @@ package file
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
+	"sync"
 
 	"github.com/golang/snappy"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	"github.com/klauspost/pgzip"
 )
 
+// Reader reads a file from its blocks, at any offset. Read continues from
+// where the last one ended or Seek moved to, ReadAt reads without moving
+// either. The blocks are found from the index of the file inode, and the
+// one read last is kept for the reads following it. Read also fetches the
+// blocks after the one it is in ahead of time, see SetReadAhead.
 type Reader struct {
-	offset      int64
-	client      blockstore.Client
-	fileInode   FileINode
-	fileC       chan []byte
-	lastBlock   []byte
-	fileSize    uint64
-	blockSize   uint32
-	blockCursor uint32
-	totalSize   uint64
-	compression string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	offset       int64 // offset Read continues from
+	client       blockstore.Client
+	fileInode    FileINode
+	blockOffsets []int64 // offset in the file of each block
+	fileSize     uint64
+	blockSize    uint32
+	compression  string
+
+	blockMu    sync.Mutex
+	lastBlock  []byte // data of the block read last
+	blockIndex int    // index of lastBlock, -1 if none is read yet
+
+	readAhead  int
+	prefetchMu sync.Mutex
+	prefetches map[int]*prefetch // blocks being fetched ahead, by index
+	prefetchWg sync.WaitGroup
 }
 
-func NewReader(fileInode FileINode, client blockstore.Client, fileSize uint64, blockSize uint32, compression string) *Reader {
+func NewReader(ctx context.Context, fileInode FileINode, client blockstore.Client, fileSize uint64, blockSize uint32, compression string) *Reader {
+	blockOffsets := make([]int64, len(fileInode.FileBlocks))
+	var offset int64
+	for i, fb := range fileInode.FileBlocks {
+		blockOffsets[i] = offset
+		offset += int64(fb.Size)
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	r := &Reader{
-		fileInode:   fileInode,
-		client:      client,
-		fileC:       make(chan []byte),
-		fileSize:    fileSize,
-		blockSize:   blockSize,
-		compression: compression,
+		ctx:          ctx,
+		cancel:       cancel,
+		fileInode:    fileInode,
+		blockOffsets: blockOffsets,
+		client:       client,
+		fileSize:     fileSize,
+		blockSize:    blockSize,
+		compression:  compression,
+		blockIndex:   -1,
+		readAhead:    ReadAheadBlocks,
+		prefetches:   make(map[int]*prefetch),
 	}
 	return r
 }
 
 func (r *Reader) Read(b []byte) (n int, err error) {
-	if r.totalSize >= r.fileSize {
-		return 0, io.EOF
-	}
-	bytesToRead := uint32(len(b))
-	bytesRead := 0
-	if r.lastBlock != nil {
-		remDataSize := r.blockSize - r.blockCursor
-		if bytesToRead <= remDataSize {
-			copy(b, r.lastBlock[r.blockCursor:r.blockCursor+bytesToRead])
-			r.blockCursor += bytesToRead
-			r.offset += int64(bytesToRead)
-			bytesRead = int(bytesToRead)
-			//bytesToRead = 0
-			if r.blockCursor == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			}
-			r.totalSize += uint64(bytesToRead)
-			return bytesRead, nil
-		} else {
-			copy(b, r.lastBlock[r.blockCursor:r.blockSize])
-			r.lastBlock = nil
-			r.blockCursor = 0
-			r.offset += int64(remDataSize)
-			bytesRead += int(remDataSize)
-			bytesToRead -= remDataSize
-			r.totalSize += uint64(remDataSize)
-
-			// this situation comes when the block ends
-			if r.totalSize >= r.fileSize {
-				fmt.Println("returning 2", bytesRead, r.blockCursor, r.totalSize, r.fileSize)
-				return bytesRead, io.EOF
-			}
-
-			// read spans across block.. so flow down and read the next block
+	if r.offset < int64(r.fileSize) {
+		index := r.blockAt(r.offset)
+		if index >= 0 {
+			r.prefetch(index)
 		}
 	}
+	n, err = r.ReadAt(b, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		// the end is told by the next read
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(b) bytes of the file starting at off. It can be called
+// from many goroutines at once, and does not change the offset of Read.
+func (r *Reader) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, ErrInvalidOffset
+	}
+	size := int64(r.fileSize)
+	for n < len(b) && off < size {
+		index := r.blockAt(off)
+		if index < 0 {
+			return n, fmt.Errorf("asking past EOF")
+		}
+		block, err := r.readBlock(index)
+		if err != nil {
+			return n, err
+		}
+		cursor := off - r.blockOffsets[index]
+		if cursor >= int64(len(block)) {
+			return n, fmt.Errorf("asking past EOF")
+		}
+		copied := copy(b[n:], block[cursor:])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek sets the offset the next Read starts from, as io.Seeker tells.
+// Seeking past the end of the file is allowed, the reads from there return
+// io.EOF.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += int64(r.fileSize)
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, ErrInvalidOffset
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// Size returns the size of the file.
+func (r *Reader) Size() int64 {
+	return int64(r.fileSize)
+}
+
+// blockAt returns the index of the block holding the byte at off, -1 if none
+// does.
+func (r *Reader) blockAt(off int64) int {
+	return sort.Search(len(r.blockOffsets), func(i int) bool {
+		return r.blockOffsets[i] > off
+	}) - 1
+}
+
+// readBlock returns the data of the block at index, downloading it unless it
+// is the one read last or it is fetched ahead.
+func (r *Reader) readBlock(index int) ([]byte, error) {
+	r.blockMu.Lock()
+	if index == r.blockIndex {
+		block := r.lastBlock
+		r.blockMu.Unlock()
+		return block, nil
+	}
+	r.blockMu.Unlock()
 
-	if r.lastBlock == nil {
-		noOfBlocks := int((bytesToRead / r.blockSize) + 1)
-		for i := 0; i < noOfBlocks; i++ {
-			if r.lastBlock == nil {
-				blockIndex := (r.offset / int64(r.blockSize))
-				if blockIndex > int64(len(r.fileInode.FileBlocks)) {
-					return bytesRead, fmt.Errorf("asking past EOF")
-				}
-				if blockIndex >= int64(len(r.fileInode.FileBlocks)) {
-					return 0, io.EOF
-				}
-				r.lastBlock, err = r.getBlock(r.fileInode.FileBlocks[blockIndex].Address, r.compression, r.blockSize)
-				if err != nil {
-					return bytesRead, err
-				}
-				r.blockSize = uint32(len(r.lastBlock))
-			}
-
-			// if length of bytes to read is greater than block size
-			if bytesToRead > r.blockSize {
-				bytesToRead = r.blockSize
-			}
-
-			if uint32(len(r.lastBlock)) < bytesToRead {
-				bytesToRead = uint32(len(r.lastBlock))
-			}
-
-			cursor := uint32(bytesRead)
-			copy(b[cursor:cursor+bytesToRead], r.lastBlock[:bytesToRead])
-			r.totalSize += uint64(bytesToRead)
-			if bytesToRead == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			} else {
-				r.blockCursor += bytesToRead
-			}
-			r.offset += int64(bytesToRead)
-			bytesRead += int(bytesToRead)
-			bytesToRead -= bytesToRead
-
-			if bytesToRead <= 0 {
-				return bytesRead, nil
-			}
+	block, ok := r.prefetched(index)
+	if !ok {
+		var err error
+		block, err = r.fetchBlock(r.ctx, index)
+		if err != nil {
+			return nil, err
 		}
 	}
-	return 0, nil
+
+	r.blockMu.Lock()
+	r.lastBlock = block
+	r.blockIndex = index
+	r.blockMu.Unlock()
+	return block, nil
 }
 
-func (r *Reader) getBlock(addr []byte, compression string, blockSize uint32) ([]byte, error) {
-	stdoutBytes, _, err := r.client.DownloadBlob(addr)
+// fetchBlock downloads and decompresses the block at index.
+func (r *Reader) fetchBlock(ctx context.Context, index int) ([]byte, error) {
+	fb := r.fileInode.FileBlocks[index]
+	block, err := r.getBlock(ctx, fb.Address, r.compression, r.blockSize)
 	if err != nil {
 		return nil, err
 	}
-	decompressedData, err := decompress(stdoutBytes, compression, blockSize)
+	if uint32(len(block)) != fb.Size {
+		return nil, fmt.Errorf("received less bytes than expected in a block")
+	}
+	return block, nil
+}
+
+func (r *Reader) getBlock(ctx context.Context, addr []byte, compression string, blockSize uint32) ([]byte, error) {
+	body, _, err := r.client.DownloadBlobStream(ctx, addr)
 	if err != nil {
 		return nil, err
 	}
-	return decompressedData, nil
+	defer body.Close()
+	br, err := newBlockReader(body, compression, blockSize)
+	if err != nil {
+		return nil, err
+	}
+	defer br.Close()
+	buf := bytes.NewBuffer(make([]byte, 0, blockSize))
+	_, err = buf.ReadFrom(br)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
+// Close stops the fetches of the blocks ahead and waits for them to end.
 func (r *Reader) Close() error {
+	r.cancel()
+	r.prefetchMu.Lock()
+	r.prefetches = make(map[int]*prefetch)
+	r.prefetchMu.Unlock()
+	r.prefetchWg.Wait()
 	return nil
 }
 
-func decompress(dataToDecompress []byte, compression string, blockSize uint32) ([]byte, error) {
+// newBlockReader returns a reader which decompresses the block read from body.
+func newBlockReader(body io.Reader, compression string, blockSize uint32) (io.ReadCloser, error) {
 	switch compression {
 	case "gzip":
-		br := bytes.NewReader(dataToDecompress)
 		block := int(blockSize / 10)
-		r, err := pgzip.NewReaderN(br, block, 10)
-		if err != nil {
-			return nil, err
-		}
-		s, err := ioutil.ReadAll(r)
-		if err != nil {
-			return nil, err
-		}
-		err = r.Close()
+		return pgzip.NewReaderN(body, block, 10)
+	case "snappy":
+		// snappy blocks are not framed, so they can only be decoded as a whole
+		data, err := ioutil.ReadAll(body)
 		if err != nil {
 			return nil, err
 		}
-		return s, nil
-	case "snappy":
-		decoded, err := snappy.Decode(nil, dataToDecompress)
+		decoded, err := snappy.Decode(nil, data)
 		if err != nil {
 			return nil, err
 		}
-		return decoded, nil
+		return ioutil.NopCloser(bytes.NewReader(decoded)), nil
 	}
-	return dataToDecompress, nil
+	return ioutil.NopCloser(body), nil
 }
@@ -0,0 +1,101 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
+)
+
+// GetReferences returns the references of all the blobs a file is made of,
+// its metadata, its inode and its blocks, and those of its earlier versions.
+func (f *File) GetReferences(ctx context.Context, metaReference []byte) ([][]byte, error) {
+	meta, refs, err := f.versionReferences(ctx, metaReference)
+	if err != nil {
+		return nil, err
+	}
+
+	// the versions share the blocks they did not change
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		seen[hex.EncodeToString(ref)] = true
+	}
+	for _, version := range meta.Versions {
+		_, versionRefs, err := f.versionReferences(ctx, version.Reference)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range versionRefs {
+			if !seen[hex.EncodeToString(ref)] {
+				seen[hex.EncodeToString(ref)] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// versionReferences returns the metadata of a version of a file and the
+// references of the blobs of that version alone.
+func (f *File) versionReferences(ctx context.Context, metaReference []byte) (*m.FileMetaData, [][]byte, error) {
+	meta, err := f.loadMeta(ctx, metaReference)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileInode, err := f.loadInode(ctx, meta.InodeAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := [][]byte{metaReference, meta.InodeAddress}
+	for _, fb := range fileInode.FileBlocks {
+		refs = append(refs, fb.Address)
+	}
+	return meta, refs, nil
+}
+
+func (f *File) loadMeta(ctx context.Context, metaReference []byte) (*m.FileMetaData, error) {
+	data, respCode, err := f.getClient().DownloadBlob(ctx, metaReference)
+	if err != nil || respCode != http.StatusOK {
+		return nil, fmt.Errorf("could not load file meta: %v", err)
+	}
+	var meta m.FileMetaData
+	err = json.Unmarshal(data, &meta)
+	if err != nil {
+		return nil, err
+	}
+	meta.MetaReference = metaReference
+	return &meta, nil
+}
+
+func (f *File) loadInode(ctx context.Context, inodeAddress []byte) (*FileINode, error) {
+	data, respCode, err := f.getClient().DownloadBlob(ctx, inodeAddress)
+	if err != nil || respCode != http.StatusOK {
+		return nil, fmt.Errorf("could not load file inode: %v", err)
+	}
+	var fileInode FileINode
+	err = json.Unmarshal(data, &fileInode)
+	if err != nil {
+		return nil, err
+	}
+	return &fileInode, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package file
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -34,12 +35,12 @@ func (f *File) GetFileReference(podFile string) ([]byte, string, error) {
 	return meta.MetaReference, meta.Name, nil
 }
 
-func (f *File) AddFileToPath(filePath, metaHexRef string) error {
+func (f *File) AddFileToPath(ctx context.Context, filePath, metaHexRef string) error {
 	metaReferenace, err := utils.ParseHexReference(metaHexRef)
 	if err != nil {
 		return err
 	}
-	data, respCode, err := f.getClient().DownloadBlob(metaReferenace.Bytes())
+	data, respCode, err := f.getClient().DownloadBlob(ctx, metaReferenace.Bytes())
 	if err != nil || respCode != http.StatusOK {
 		return err
 	}
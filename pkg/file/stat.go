@@ -17,6 +17,7 @@ limitations under the License.
 package file
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -45,13 +46,13 @@ type Blocks struct {
 	CompressedSize string `json:"compressed_size"`
 }
 
-func (f *File) FileStat(podName, fileName, account string) (*FileStats, error) {
+func (f *File) FileStat(ctx context.Context, podName, fileName, account string) (*FileStats, error) {
 	meta := f.GetFromFileMap(fileName)
 	if meta == nil {
 		return nil, fmt.Errorf("file not found")
 	}
 
-	fileInodeBytes, _, err := f.getClient().DownloadBlob(meta.InodeAddress)
+	fileInodeBytes, _, err := f.getClient().DownloadBlob(ctx, meta.InodeAddress)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +19,7 @@ package file
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,7 +27,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"sync"
-	"time"
 
 	"github.com/golang/snappy"
 	m "github.com/jmozah/intOS-dfs/pkg/meta"
@@ -37,9 +37,12 @@ var (
 	NoOfParallelWorkers = runtime.NumCPU() * 4
 )
 
-func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize uint32, filePath, compression string) ([]byte, error) {
+// Upload stores the file read from fd at filePath, and returns the reference
+// of its metadata. A file already at filePath is replaced by it, and kept as
+// the latest of its versions.
+func (f *File) Upload(ctx context.Context, fd io.Reader, fileName string, fileSize int64, blockSize uint32, filePath, compression string) ([]byte, error) {
 	reader := bufio.NewReader(fd)
-	now := time.Now().Unix()
+	now := f.fd.Clock().Now().Unix()
 	meta := m.FileMetaData{
 		Version:          m.FileMetaVersion,
 		Path:             filepath.Dir(filePath),
@@ -56,8 +59,7 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 
 	var totalLength uint64
 	i := 0
-	errC := make(chan error)
-	doneC := make(chan bool)
+	errC := make(chan error, 1) // the first error of the workers
 	worker := make(chan bool, NoOfParallelWorkers)
 	var wg sync.WaitGroup
 	refMap := make(map[int]*FileBlock)
@@ -95,26 +97,11 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 				<-worker
 				wg.Done()
 			}()
-			// compress the data
-			uploadData := data[:size]
-			if compression != "" {
-				uploadData, err = compress(data[:size], compression, blockSize)
-				if err != nil {
-					errC <- err
-				}
-			}
-
-			addr, err := f.client.UploadBlob(uploadData, true, true)
+			fileBlock, err := f.UploadBlock(ctx, data[:size], counter, blockSize, compression)
 			if err != nil {
-				errC <- err
+				sendError(errC, err)
 				return
 			}
-			fileBlock := &FileBlock{
-				Name:           fmt.Sprintf("block-%05d", counter),
-				Size:           uint32(size),
-				CompressedSize: uint32(len(uploadData)),
-				Address:        addr,
-			}
 
 			refMapMu.Lock()
 			defer refMapMu.Unlock()
@@ -124,17 +111,13 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 		i++
 	}
 
-	go func() {
-		wg.Wait()
-		close(doneC)
-	}()
-
+	// all the workers are done before looking for an error, so that a
+	// failed block is never mistaken for a complete upload
+	wg.Wait()
 	select {
-	case <-doneC:
-		break
 	case err := <-errC:
-		close(errC)
 		return nil, err
+	default:
 	}
 
 	// copy the block references to the fileInode
@@ -142,30 +125,113 @@ func (f *File) Upload(fd io.Reader, fileName string, fileSize int64, blockSize u
 		fileINode.FileBlocks = append(fileINode.FileBlocks, refMap[i])
 	}
 
-	fileInodeData, err := json.Marshal(fileINode)
+	// a file already at filePath is replaced, and kept as the latest version
+	if oldMeta := f.GetFromFileMap(filePath); oldMeta != nil {
+		meta.CreationTime = oldMeta.CreationTime
+		meta.Versions = appendVersion(oldMeta.Versions, oldMeta)
+	}
+	return f.storeFile(ctx, filePath, &meta, &fileINode)
+}
+
+// UploadBlock compresses and stores one block of a file, index being its
+// place in the file.
+func (f *File) UploadBlock(ctx context.Context, data []byte, index int, blockSize uint32, compression string) (*FileBlock, error) {
+	uploadData := data
+	if compression != "" {
+		compressed, err := compress(data, compression, blockSize)
+		if err != nil {
+			return nil, err
+		}
+		uploadData = compressed
+	}
+
+	addr, err := f.client.UploadBlobStream(ctx, bytes.NewReader(uploadData), true, true)
 	if err != nil {
 		return nil, err
 	}
+	return &FileBlock{
+		Name:           fmt.Sprintf("block-%05d", index),
+		Size:           uint32(len(data)),
+		CompressedSize: uint32(len(uploadData)),
+		Address:        addr,
+	}, nil
+}
 
-	addr, err := f.client.UploadBlob(fileInodeData, true, true)
+// UploadFromBlocks makes a file of blocks stored with UploadBlock, in the
+// order of the file.
+func (f *File) UploadFromBlocks(ctx context.Context, blocks []*FileBlock, fileName string, fileSize uint64, blockSize uint32, filePath, compression string) ([]byte, error) {
+	now := f.fd.Clock().Now().Unix()
+	meta := m.FileMetaData{
+		Version:          m.FileMetaVersion,
+		Path:             filepath.Dir(filePath),
+		Name:             fileName,
+		FileSize:         fileSize,
+		BlockSize:        blockSize,
+		Compression:      compression,
+		CreationTime:     now,
+		AccessTime:       now,
+		ModificationTime: now,
+	}
+	fileINode := FileINode{
+		FileBlocks: blocks,
+	}
+
+	// determine the content type from the first 512 bytes of the file
+	if fileSize >= 512 {
+		reader := NewReader(ctx, fileINode, f.client, fileSize, blockSize, compression)
+		reader.SetReadAhead(0)
+		contentBytes := make([]byte, 512)
+		_, err := reader.ReadAt(contentBytes, 0)
+		_ = reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		meta.ContentType = f.GetContentType(bufio.NewReader(bytes.NewReader(contentBytes)))
+	}
+	return f.storeFile(ctx, filePath, &meta, &fileINode)
+}
+
+// storeFile stores the inode and the metadata of a file whose blocks are
+// stored, and returns the reference of the metadata.
+func (f *File) storeFile(ctx context.Context, filePath string, meta *m.FileMetaData, fileINode *FileINode) ([]byte, error) {
+	fileInodeData, err := json.Marshal(fileINode)
 	if err != nil {
 		return nil, err
 	}
 
+	addr, err := f.client.UploadBlob(ctx, fileInodeData, true, true)
+	if err != nil {
+		return nil, err
+	}
 	meta.InodeAddress = addr
+	return f.storeMeta(ctx, filePath, meta)
+}
+
+// storeMeta stores the metadata of a file whose inode is stored, and returns
+// its reference.
+func (f *File) storeMeta(ctx context.Context, filePath string, meta *m.FileMetaData) ([]byte, error) {
+	meta.MetaReference = nil
 	fileMetaBytes, err := json.Marshal(meta)
 	if err != nil {
 		return nil, err
 	}
-	metaAddr, err := f.client.UploadBlob(fileMetaBytes, true, true)
+	metaAddr, err := f.client.UploadBlob(ctx, fileMetaBytes, true, true)
 	if err != nil {
 		return nil, err
 	}
 	meta.MetaReference = metaAddr // the self address is stored to share this file easily
-	f.AddToFileMap(filePath, &meta)
+	f.AddToFileMap(filePath, meta)
 	return metaAddr, nil
 }
 
+// sendError keeps the first error of the upload workers and drops the rest.
+func sendError(errC chan error, err error) {
+	select {
+	case errC <- err:
+	default:
+	}
+}
+
 func compress(dataToCompress []byte, compression string, blockSize uint32) ([]byte, error) {
 	switch compression {
 	case "gzip":
@@ -0,0 +1,252 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	uploadsDirPerm  = 0700
+	uploadsFilePerm = 0600
+	uploadIdLength  = 16
+)
+
+// UploadSession is a file being uploaded block by block. The blocks can come
+// in any order and over many requests, and the file is made of them once
+// they are all stored.
+type UploadSession struct {
+	Id           string             `json:"id"`
+	UserName     string             `json:"user_name"`
+	PodName      string             `json:"pod_name"`
+	DirPath      string             `json:"dir_path"` // directory the file goes in
+	FileName     string             `json:"file_name"`
+	FileSize     uint64             `json:"file_size"`
+	BlockSize    uint32             `json:"block_size"`
+	Compression  string             `json:"compression"`
+	CreationTime int64              `json:"creation_time"`
+	Blocks       map[int]*FileBlock `json:"blocks"` // blocks stored, by index
+}
+
+// NoOfBlocks returns the number of blocks the file is made of.
+func (s *UploadSession) NoOfBlocks() int {
+	return int((s.FileSize + uint64(s.BlockSize) - 1) / uint64(s.BlockSize))
+}
+
+// BlockLength returns the length of the block at index, which is the block
+// size for all of them but the last.
+func (s *UploadSession) BlockLength(index int) (int, error) {
+	if index < 0 || index >= s.NoOfBlocks() {
+		return 0, ErrInvalidBlock
+	}
+	if index == s.NoOfBlocks()-1 {
+		return int(s.FileSize - uint64(index)*uint64(s.BlockSize)), nil
+	}
+	return int(s.BlockSize), nil
+}
+
+// StoredBlocks returns the indexes of the blocks stored, in order.
+func (s *UploadSession) StoredBlocks() []int {
+	stored := make([]int, 0, len(s.Blocks))
+	for index := range s.Blocks {
+		stored = append(stored, index)
+	}
+	sort.Ints(stored)
+	return stored
+}
+
+// MissingBlocks returns the indexes of the blocks not stored yet, in order.
+func (s *UploadSession) MissingBlocks() []int {
+	var missing []int
+	for index := 0; index < s.NoOfBlocks(); index++ {
+		if _, ok := s.Blocks[index]; !ok {
+			missing = append(missing, index)
+		}
+	}
+	return missing
+}
+
+// FileBlocks returns the blocks of the file in order, once they are all
+// stored.
+func (s *UploadSession) FileBlocks() ([]*FileBlock, error) {
+	if len(s.MissingBlocks()) > 0 {
+		return nil, ErrUploadIncomplete
+	}
+	blocks := make([]*FileBlock, s.NoOfBlocks())
+	for index := range blocks {
+		blocks[index] = s.Blocks[index]
+	}
+	return blocks, nil
+}
+
+func (s *UploadSession) copy() *UploadSession {
+	c := *s
+	c.Blocks = make(map[int]*FileBlock, len(s.Blocks))
+	for index, fb := range s.Blocks {
+		c.Blocks[index] = fb
+	}
+	return &c
+}
+
+// Uploads keeps the upload sessions in progress, each in a file named by its
+// id under the directory of the uploads, so that they are resumed after a
+// restart. A block is recorded as soon as it is stored, so a dropped upload
+// only has to send the blocks missing.
+//
+// Uploads are safe for concurrent use.
+type Uploads struct {
+	dir      string
+	sessions map[string]*UploadSession
+	mu       sync.Mutex
+	logger   logging.Logger
+}
+
+// NewUploads opens the upload sessions kept in dir, creating it if needed.
+func NewUploads(dir string, logger logging.Logger) (*Uploads, error) {
+	err := os.MkdirAll(dir, uploadsDirPerm)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	u := &Uploads{
+		dir:      dir,
+		sessions: make(map[string]*UploadSession),
+		logger:   logger,
+	}
+	for _, fi := range files {
+		path := filepath.Join(dir, fi.Name())
+		if strings.HasPrefix(fi.Name(), utils.TmpFilePrefix) {
+			_ = os.Remove(path)
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var s UploadSession
+		err = json.Unmarshal(data, &s)
+		if err != nil || s.Id != fi.Name() || s.BlockSize == 0 {
+			logger.Warningf("dropping corrupt upload session %s", path)
+			_ = os.Remove(path)
+			continue
+		}
+		if s.Blocks == nil {
+			s.Blocks = make(map[int]*FileBlock)
+		}
+		u.sessions[s.Id] = &s
+	}
+	return u, nil
+}
+
+// Open starts the upload session s, giving it its id.
+func (u *Uploads) Open(s *UploadSession) error {
+	if s.BlockSize == 0 {
+		return ErrInvalidBlock
+	}
+	id := make([]byte, uploadIdLength)
+	_, err := rand.Read(id)
+	if err != nil {
+		return err
+	}
+	s.Id = hex.EncodeToString(id)
+	s.Blocks = make(map[int]*FileBlock)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	err = u.write(s)
+	if err != nil {
+		return err
+	}
+	u.sessions[s.Id] = s.copy()
+	return nil
+}
+
+// Get returns a copy of the upload session with the given id.
+func (u *Uploads) Get(id string) (*UploadSession, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s, ok := u.sessions[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return s.copy(), nil
+}
+
+// AddBlock records that the block at index of an upload session is stored.
+// A block stored again replaces the one before, which is returned.
+func (u *Uploads) AddBlock(id string, index int, fb *FileBlock) (*FileBlock, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	s, ok := u.sessions[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	length, err := s.BlockLength(index)
+	if err != nil {
+		return nil, err
+	}
+	if int(fb.Size) != length {
+		return nil, ErrInvalidBlock
+	}
+	c := s.copy()
+	c.Blocks[index] = fb
+	err = u.write(c)
+	if err != nil {
+		return nil, err
+	}
+	u.sessions[id] = c
+	return s.Blocks[index], nil
+}
+
+// Remove ends an upload session.
+func (u *Uploads) Remove(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.sessions[id]; !ok {
+		return ErrUploadNotFound
+	}
+	err := os.Remove(filepath.Join(u.dir, id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(u.sessions, id)
+	return nil
+}
+
+// write replaces the file of an upload session, so that it is never seen
+// half written.
+func (u *Uploads) write(s *UploadSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filepath.Join(u.dir, s.Id), data, uploadsFilePerm)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package file
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -26,8 +27,8 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (f *File) LoadFileMeta(podName string, addr []byte) (int, error) {
-	data, respCode, err := f.getClient().DownloadBlob(addr)
+func (f *File) LoadFileMeta(ctx context.Context, podName string, addr []byte) (int, error) {
+	data, respCode, err := f.getClient().DownloadBlob(ctx, addr)
 	if err != nil {
 		return respCode, fmt.Errorf("not a file")
 	}
@@ -0,0 +1,195 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
+)
+
+// ListVersions returns the earlier versions of the file at filePath, the
+// latest first.
+func (f *File) ListVersions(filePath string) ([]m.FileVersion, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	versions := make([]m.FileVersion, 0, len(meta.Versions))
+	for i := len(meta.Versions) - 1; i >= 0; i-- {
+		versions = append(versions, meta.Versions[i])
+	}
+	return versions, nil
+}
+
+// DownloadVersion returns a reader of an earlier version of the file at
+// filePath, along with the reference and the size of the version, like
+// Download does for the file.
+func (f *File) DownloadVersion(ctx context.Context, filePath string, versionReference []byte) (*Reader, string, string, error) {
+	version, err := f.getVersion(ctx, filePath, versionReference)
+	if err != nil {
+		return nil, "", "", err
+	}
+	fileInode, err := f.loadInode(ctx, version.InodeAddress)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	reader := NewReader(ctx, *fileInode, f.getClient(), version.FileSize, version.BlockSize, version.Compression)
+	ref := swarm.NewAddress(version.InodeAddress).String()
+	size := strconv.FormatUint(version.FileSize, 10)
+	return reader, ref, size, nil
+}
+
+// RestoreVersion makes an earlier version of the file at filePath its
+// current one. The file before the restore is kept as a version too.
+func (f *File) RestoreVersion(ctx context.Context, filePath string, versionReference []byte) (*Modification, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	version, err := f.getVersion(ctx, filePath, versionReference)
+	if err != nil {
+		return nil, err
+	}
+
+	// the content of the version, under the name of the file now
+	newMeta := *version
+	now := f.fd.Clock().Now().Unix()
+	newMeta.Path = meta.Path
+	newMeta.Name = meta.Name
+	newMeta.CreationTime = meta.CreationTime
+	newMeta.AccessTime = now
+	newMeta.ModificationTime = now
+	newMeta.Versions = appendVersion(meta.Versions, meta)
+	ref, err := f.storeMeta(ctx, filePath, &newMeta)
+	if err != nil {
+		return nil, err
+	}
+	return &Modification{
+		Reference:    ref,
+		OldReference: meta.MetaReference,
+	}, nil
+}
+
+// PruneVersions drops the versions of the file at filePath which are older
+// than maxAge, and the ones past the latest maxCount of them. A zero maxAge
+// or a negative maxCount leaves that limit out. The blobs only the dropped
+// versions used are released. The file is left as it is if there is nothing
+// to drop.
+func (f *File) PruneVersions(ctx context.Context, filePath string, maxAge time.Duration, maxCount int) (*Modification, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+
+	now := f.fd.Clock().Now()
+	var kept, dropped []m.FileVersion
+	for i, version := range meta.Versions {
+		tooOld := maxAge > 0 && now.Sub(time.Unix(version.ModificationTime, 0)) > maxAge
+		tooMany := maxCount >= 0 && len(meta.Versions)-i > maxCount
+		if tooOld || tooMany {
+			dropped = append(dropped, version)
+			continue
+		}
+		kept = append(kept, version)
+	}
+	if len(dropped) == 0 {
+		return &Modification{
+			Reference:    meta.MetaReference,
+			OldReference: meta.MetaReference,
+		}, nil
+	}
+
+	newMeta := *meta
+	newMeta.Versions = kept
+	ref, err := f.storeMeta(ctx, filePath, &newMeta)
+	if err != nil {
+		return nil, err
+	}
+	restore := func() {
+		f.AddToFileMap(filePath, meta)
+	}
+
+	// what the file and the versions kept use stays, the metadata it had
+	// before the prune goes with the dropped versions
+	inUse, err := f.GetReferences(ctx, ref)
+	if err != nil {
+		restore()
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, addr := range inUse {
+		used[hex.EncodeToString(addr)] = true
+	}
+	var released [][]byte
+	release := func(addrs [][]byte) {
+		for _, addr := range addrs {
+			if !used[hex.EncodeToString(addr)] {
+				used[hex.EncodeToString(addr)] = true
+				released = append(released, addr)
+			}
+		}
+	}
+	release([][]byte{meta.MetaReference})
+	for _, version := range dropped {
+		_, refs, err := f.versionReferences(ctx, version.Reference)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		release(refs)
+	}
+	return &Modification{
+		Reference:    ref,
+		OldReference: meta.MetaReference,
+		Released:     released,
+	}, nil
+}
+
+// getVersion returns the metadata of the earlier version of the file at
+// filePath with the given reference.
+func (f *File) getVersion(ctx context.Context, filePath string, versionReference []byte) (*m.FileMetaData, error) {
+	meta := f.GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, fmt.Errorf("file not found in dfs")
+	}
+	for _, version := range meta.Versions {
+		if bytes.Equal(version.Reference, versionReference) {
+			return f.loadMeta(ctx, version.Reference)
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+// appendVersion returns versions with the file of meta added as the latest.
+func appendVersion(versions []m.FileVersion, meta *m.FileMetaData) []m.FileVersion {
+	newVersions := make([]m.FileVersion, 0, len(versions)+1)
+	newVersions = append(newVersions, versions...)
+	return append(newVersions, m.FileVersion{
+		Reference:        meta.MetaReference,
+		FileSize:         meta.FileSize,
+		ModificationTime: meta.ModificationTime,
+	})
+}
@@ -33,4 +33,13 @@ type FileMetaData struct {
 	ModificationTime int64
 	MetaReference    []byte
 	InodeAddress     []byte
+	Versions         []FileVersion // the earlier versions of the file, the oldest first
+}
+
+// FileVersion is an earlier version of a file, kept by the versions of the
+// file which came after it.
+type FileVersion struct {
+	Reference        []byte // the metadata of the version
+	FileSize         uint64
+	ModificationTime int64
 }
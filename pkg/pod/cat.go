@@ -17,12 +17,13 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) Cat(podName string, fileName string) error {
+func (p *Pod) Cat(ctx context.Context, podName string, fileName string) error {
 
 	if !p.isPodOpened(podName) {
 		return fmt.Errorf("login to pod to do this operation")
@@ -40,5 +41,5 @@ func (p *Pod) Cat(podName string, fileName string) error {
 		fname = podInfo.GetCurrentDirPathAndName() + utils.PathSeperator + fileName
 	}
 
-	return podInfo.getFile().Cat(fname)
+	return podInfo.getFile().Cat(ctx, fname)
 }
@@ -17,13 +17,14 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) ChangeDir(podName string, dirName string) (*Info, error) {
+func (p *Pod) ChangeDir(ctx context.Context, podName string, dirName string) (*Info, error) {
 	directoryName, err := CleanDirName(dirName)
 	if err != nil {
 		return nil, err
@@ -55,7 +56,7 @@ func (p *Pod) ChangeDir(podName string, dirName string) (*Info, error) {
 		if podInfo.IsCurrentDirRoot() {
 			return podInfo, nil
 		}
-		_, dirInode, err := directory.GetDirNode(podInfo.GetCurrentDirPathOnly(), fd, accountInfo)
+		_, dirInode, err := directory.GetDirNode(ctx, podInfo.GetCurrentDirPathOnly(), fd, accountInfo)
 		if err != nil {
 			return nil, err
 		}
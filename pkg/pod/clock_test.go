@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestPod_Clock(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewMock(time.Unix(1600000000, 0))
+	fd := feed.NewWithOptions(acc.GetUserAccountInfo(), mockClient, feed.Options{Clock: clk}, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+	podName1 := "test1"
+
+	t.Run("metadata-timed-by-clock", func(t *testing.T) {
+		created := clk.Now().Unix()
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s: %v", podName1, err)
+		}
+		podInode := info.getDirectory().GetDirFromDirectoryMap(utils.PathSeperator + podName1)
+		if podInode == nil {
+			t.Fatalf("pod directory not found")
+		}
+		if podInode.Meta.CreationTime != created {
+			t.Fatalf("pod created at %d, expected %d", podInode.Meta.CreationTime, created)
+		}
+
+		clk.Add(time.Hour)
+		err = pod1.MakeDir(context.Background(), podName1, "dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirStat, err := pod1.DirectoryStat(context.Background(), podName1, "dir1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dirStat.CreationTime != strconv.FormatInt(clk.Now().Unix(), 10) {
+			t.Fatalf("directory created at %s, expected %d", dirStat.CreationTime, clk.Now().Unix())
+		}
+
+		clk.Add(time.Hour)
+		fileName := createRandomFileInPod(t, 100, pod1, podName1, "/dir1")
+		fileStat, err := pod1.FileStat(context.Background(), podName1, fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileStat.CreationTime != strconv.FormatInt(clk.Now().Unix(), 10) {
+			t.Fatalf("file created at %s, expected %d", fileStat.CreationTime, clk.Now().Unix())
+		}
+		dirStat, err = pod1.DirectoryStat(context.Background(), podName1, "dir1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dirStat.ModificationTime != strconv.FormatInt(clk.Now().Unix(), 10) {
+			t.Fatalf("directory modified at %s, expected %d", dirStat.ModificationTime, clk.Now().Unix())
+		}
+	})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	d "github.com/jmozah/intOS-dfs/pkg/dir"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestPod_TwoDevices(t *testing.T) {
+	client := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	device1 := NewPod(client, feed.New(acc.GetUserAccountInfo(), client, logger), acc, logger)
+	device2 := NewPod(client, feed.New(acc.GetUserAccountInfo(), client, logger), acc, logger)
+	podName1 := "test1"
+
+	t.Run("update-while-the-other-updates", func(t *testing.T) {
+		info, err := device1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		_, err = device2.OpenPod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error opening pod %s on the other device: %v", podName1, err)
+		}
+		podPath := info.GetCurrentPodPathAndName()
+		file1 := podPath + "/file1"
+		data := []byte("written on device 1")
+		ref, err := info.getFile().Upload(context.Background(), bytes.NewReader(data), "file1", int64(len(data)), 1024, file1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// device 2 adds a file between device 1 reading and updating the pod
+		var file2 string
+		attempts := 0
+		_, _, err = info.getDirectory().ModifyDirectory(context.Background(), podPath, func(dirInode *d.DirInode) error {
+			attempts++
+			if attempts == 1 {
+				file2 = podPath + createRandomFileInPod(t, 540, device2, podName1, podPath)
+			}
+			dirInode.Hashes = append(dirInode.Hashes, ref)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != 2 {
+			t.Fatalf("expected the update to be redone once, it was done %d times", attempts)
+		}
+
+		fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+		fresh, err := NewPod(client, fd, acc, logger).OpenPod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := fresh.getFile().ListFiles("")
+		sort.Strings(files)
+		expected := []string{file1, file2}
+		sort.Strings(expected)
+		if len(files) != 2 || files[0] != expected[0] || files[1] != expected[1] {
+			t.Fatalf("expected files %v, found %v", expected, files)
+		}
+	})
+	t.Run("pods-updated-while-the-other-updates", func(t *testing.T) {
+		// device 2 creates a pod between device 1 reading and updating the
+		// list of pods
+		attempts := 0
+		err := device1.modifyUserPods(context.Background(), func(pods map[int]string) error {
+			attempts++
+			if attempts == 1 {
+				_, err := device2.CreatePod(context.Background(), "test2", "password")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			id, err := device1.getFreeId(pods)
+			if err != nil {
+				return err
+			}
+			pods[id] = "test3"
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attempts != 2 {
+			t.Fatalf("expected the update to be redone once, it was done %d times", attempts)
+		}
+
+		pods, err := device1.loadUserPods(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, podName := range []string{podName1, "test2", "test3"} {
+			if !device1.checkIfPodPresent(pods, podName) {
+				t.Fatalf("pod %s lost, found %v", podName, pods)
+			}
+		}
+		if len(pods) != 3 {
+			t.Fatalf("expected 3 pods, found %v", pods)
+		}
+	})
+}
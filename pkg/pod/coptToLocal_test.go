@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"crypto/rand"
 	"io/ioutil"
 	"os"
@@ -45,13 +46,13 @@ func TestPod_CopyToLocal(t *testing.T) {
 	podName1 := "test1"
 	firstDir := "dir1"
 	t.Run("copy-file-from-root", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 		podFile := createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName())
 
-		err = pod1.CopyToLocal(podName1, podFile, os.TempDir())
+		err = pod1.CopyToLocal(context.Background(), podName1, podFile, os.TempDir())
 		if err != nil {
 			t.Fatalf("error copying file to local dir %s", err.Error())
 		}
@@ -66,18 +67,18 @@ func TestPod_CopyToLocal(t *testing.T) {
 		}
 
 		os.Remove(fileInfo.Name())
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("copy-file-from-firstdir", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
-		err = pod1.MakeDir(podName1, firstDir)
+		err = pod1.MakeDir(context.Background(), podName1, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
@@ -88,7 +89,7 @@ func TestPod_CopyToLocal(t *testing.T) {
 		}
 		podFile := createRandomFileInPod(t, 540, pod1, podName1, dirPath)
 
-		err = pod1.CopyToLocal(podName1, podFile, os.TempDir())
+		err = pod1.CopyToLocal(context.Background(), podName1, podFile, os.TempDir())
 		if err != nil {
 			t.Fatalf("error copying file to local dir %s", err.Error())
 		}
@@ -103,14 +104,14 @@ func TestPod_CopyToLocal(t *testing.T) {
 		}
 
 		os.Remove(fileInfo.Name())
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("copy-file-to-dot", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
@@ -119,7 +120,7 @@ func TestPod_CopyToLocal(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = pod1.CopyToLocal(podName1, podFile, pwd)
+		err = pod1.CopyToLocal(context.Background(), podName1, podFile, pwd)
 		if err != nil {
 			t.Fatalf("error copying file to local dir %s", err.Error())
 		}
@@ -137,7 +138,7 @@ func TestPod_CopyToLocal(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
@@ -176,7 +177,7 @@ func createRandomFileInPod(t *testing.T, size int, pod1 *Pod, podName string, po
 		t.Fatal(err)
 	}
 	fName := filepath.Base(file.Name())
-	_, err = pod1.UploadFile(podName, fName, int64(size), fd, podDir, "100", "false")
+	_, err = pod1.UploadFile(context.Background(), podName, fName, int64(size), fd, podDir, "100", "false")
 	if err != nil {
 		t.Fatalf("createRandomFileInPod failed: %s", err.Error())
 	}
@@ -17,13 +17,14 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) CopyToLocal(podName string, podFile string, localDir string) error {
+func (p *Pod) CopyToLocal(ctx context.Context, podName string, podFile string, localDir string) error {
 	if !p.isPodOpened(podName) {
 		return fmt.Errorf("login to pod to do this operation")
 	}
@@ -53,7 +54,7 @@ func (p *Pod) CopyToLocal(podName string, podFile string, localDir string) error
 		return fmt.Errorf("file not present in pod")
 	}
 
-	err = podInfo.getFile().CopyToFile(path, localDir)
+	err = podInfo.getFile().CopyToFile(ctx, path, localDir)
 	if err != nil {
 		return err
 	}
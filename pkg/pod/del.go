@@ -17,48 +17,74 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
-func (p *Pod) DeletePod(podName string) error {
-	pods, err := p.loadUserPods()
+func (p *Pod) DeletePod(ctx context.Context, podName string) error {
+	pods, err := p.loadUserPods(ctx)
 	if err != nil {
 		return err
 	}
-	found := false
-	var podIndex int
-	for index, pod := range pods {
-		if strings.Trim(pod, "\n") == podName {
-			delete(pods, index)
-			podIndex = index
-			found = true
-		}
-	}
-	if !found {
+	if !p.checkIfPodPresent(pods, podName) {
 		return fmt.Errorf("pod not found")
 	}
 
-	// if last pod is deleted.. something should be there to update the feed
-	if pods == nil {
-		pods = make(map[int]string)
-		pods[0] = ""
+	// collect what the pod holds before it is unlinked, which needs it open
+	// and synced so that its tree can be walked. A closed pod is opened for
+	// it if its account is at hand, otherwise what it pinned is left pinned.
+	if !p.isPodOpened(podName) && p.acc.HasPodAccount(p.getIndex(pods, podName)) {
+		_, err = p.OpenPod(ctx, podName, "")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if p.isPodOpened(podName) {
+				_ = p.ClosePod(podName)
+			}
+		}()
+	}
+	var podInfo *Info
+	var refs []garbage
+	if p.isPodOpened(podName) {
+		podInfo, err = p.GetPodInfoFromPodMap(podName)
+		if err != nil {
+			return err
+		}
+		refs, err = p.podGarbage(ctx, podInfo)
+		if err != nil {
+			return err
+		}
+	} else {
+		p.logger.Warningf("pod %s is deleted without being opened, what it pinned stays pinned", podName)
 	}
 
-	err = p.storeUserPods(pods)
+	var podIndex int
+	err = p.modifyUserPods(ctx, func(pods map[int]string) error {
+		found := false
+		for index, pod := range pods {
+			if strings.Trim(pod, "\n") == podName {
+				delete(pods, index)
+				podIndex = index
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("pod not found")
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if p.isPodOpened(podName) {
-		return p.ClosePod(podName)
-	} else {
-		podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if podInfo != nil {
+		p.unpin(ctx, podInfo, refs)
+		err = p.ClosePod(podName)
 		if err != nil {
 			return err
 		}
-		podInfo.dir.RemoveFromDirectoryMap(podName)
-		p.removePodFromPodMap(podName)
 	}
 	p.acc.DeletePodAccount(podIndex)
 	return nil
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -42,12 +43,12 @@ func TestDeleteNewPod(t *testing.T) {
 	podName1 := "test1"
 	podName2 := "test2"
 	t.Run("create-one-pod-and-del", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
-		pods, err := pod1.loadUserPods()
+		pods, err := pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
@@ -56,12 +57,12 @@ func TestDeleteNewPod(t *testing.T) {
 			t.Fatalf("podName is not %s", podName1)
 		}
 
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		pods, err = pod1.loadUserPods()
+		pods, err = pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
@@ -90,16 +91,16 @@ func TestDeleteNewPod(t *testing.T) {
 	})
 
 	t.Run("create-two-pod-and-del", func(t *testing.T) {
-		info1, err := pod1.CreatePod(podName1, "password")
+		info1, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
-		info2, err := pod1.CreatePod(podName2, "password")
+		info2, err := pod1.CreatePod(context.Background(), podName2, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
-		pods, err := pod1.loadUserPods()
+		pods, err := pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
@@ -112,12 +113,12 @@ func TestDeleteNewPod(t *testing.T) {
 			t.Fatalf("podName is not %s", podName2)
 		}
 
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		pods, err = pod1.loadUserPods()
+		pods, err = pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
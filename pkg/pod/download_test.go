@@ -0,0 +1,311 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	mrand "math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestPod_DownloadFile(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+	podName1 := "test1"
+	_, err = pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+
+	// upload writes a file of size random bytes in blocks of blockSize bytes
+	upload := func(t *testing.T, name string, size, blockSize int, compression string) []byte {
+		t.Helper()
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.UploadFile(context.Background(), podName1, name, int64(size), bytes.NewReader(data), ".", strconv.Itoa(blockSize), compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	for _, tc := range []struct {
+		compression string
+		blockSize   int
+		size        int
+	}{
+		{compression: "", blockSize: 100, size: 1234},
+		{compression: "snappy", blockSize: 100, size: 1234},
+		{compression: "gzip", blockSize: 200000, size: 654321}, // gzip writes blocks in ten parts of over 16k
+	} {
+		name := "file-" + tc.compression
+		data := upload(t, name, tc.size, tc.blockSize, tc.compression)
+
+		t.Run("read-all-"+name, func(t *testing.T) {
+			reader, _, size, err := pod1.DownloadFile(context.Background(), podName1, "/"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != strconv.Itoa(tc.size) {
+				t.Fatalf("expected size %d, got %s", tc.size, size)
+			}
+			got, err := ioutil.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("file read is not the one uploaded")
+			}
+		})
+
+		t.Run("seek-"+name, func(t *testing.T) {
+			reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rnd := mrand.New(mrand.NewSource(1))
+			for i := 0; i < 50; i++ {
+				offset := rnd.Int63n(int64(len(data)))
+				length := rnd.Intn(3*tc.blockSize + 50)
+				whence := rnd.Intn(3)
+				var seekTo int64
+				switch whence {
+				case io.SeekStart:
+					seekTo = offset
+				case io.SeekCurrent:
+					current, err := reader.Seek(0, io.SeekCurrent)
+					if err != nil {
+						t.Fatal(err)
+					}
+					seekTo = offset - current
+				case io.SeekEnd:
+					seekTo = offset - int64(len(data))
+				}
+				got, err := reader.Seek(seekTo, whence)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != offset {
+					t.Fatalf("seeked to %d, expected %d", got, offset)
+				}
+
+				buf := make([]byte, length)
+				n, err := io.ReadFull(reader, buf)
+				end := offset + int64(length)
+				if end > int64(len(data)) {
+					end = int64(len(data))
+					if err != io.ErrUnexpectedEOF && !(n == 0 && err == io.EOF) {
+						t.Fatalf("read past the end: %v", err)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf[:n], data[offset:end]) {
+					t.Fatalf("read %d bytes at %d are not the ones uploaded", length, offset)
+				}
+			}
+
+			_, err = reader.Seek(-1, io.SeekStart)
+			if err != file.ErrInvalidOffset {
+				t.Fatalf("expected %v, got %v", file.ErrInvalidOffset, err)
+			}
+			_, err = reader.Seek(int64(len(data)+10), io.SeekStart)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := reader.Read(make([]byte, 10))
+			if n != 0 || err != io.EOF {
+				t.Fatalf("expected EOF past the end, got %d bytes, %v", n, err)
+			}
+		})
+
+		t.Run("read-at-"+name, func(t *testing.T) {
+			reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					offset := int64(i * tc.size / 19)
+					buf := make([]byte, 3*tc.blockSize/2)
+					n, err := reader.ReadAt(buf, offset)
+					if err != nil && err != io.EOF {
+						errs <- err
+						return
+					}
+					if !bytes.Equal(buf[:n], data[offset:offset+int64(n)]) || (n < len(buf) && offset+int64(n) != int64(len(data))) {
+						errs <- fmt.Errorf("read %d bytes at %d are not the ones uploaded", n, offset)
+					}
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatal(err)
+			}
+
+			// reading at an offset does not move the reader
+			buf := make([]byte, 10)
+			_, err = io.ReadFull(reader, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf, data[:10]) {
+				t.Fatalf("read after ReadAt does not start at the beginning")
+			}
+		})
+	}
+}
+
+func TestPod_ReadAhead(t *testing.T) {
+	client := fault.New(mock.NewMockBeeClient(), 1)
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+	podName1 := "test1"
+	_, err = pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	data := make([]byte, 2000) // 20 blocks
+	_, err = rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pod1.UploadFile(context.Background(), podName1, "file1", int64(len(data)), bytes.NewReader(data), ".", "100", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// readAll reads the file with the given read ahead, every block taking
+	// latency to download, and returns how long it took
+	readAll := func(t *testing.T, readAhead int, latency time.Duration) time.Duration {
+		t.Helper()
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		reader.SetReadAhead(readAhead)
+		client.SetLatency(latency)
+		defer client.Reset()
+
+		start := time.Now()
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("file read is not the one uploaded")
+		}
+		return time.Since(start)
+	}
+
+	t.Run("blocks-fetched-in-parallel", func(t *testing.T) {
+		sequential := readAll(t, 0, 20*time.Millisecond)
+		parallel := readAll(t, 8, 20*time.Millisecond)
+		if parallel > sequential/2 {
+			t.Fatalf("read ahead took %v, reading block by block took %v", parallel, sequential)
+		}
+	})
+
+	t.Run("window-bounded", func(t *testing.T) {
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.SetReadAhead(3)
+		calls := client.Calls(fault.DownloadBlob)
+		_, err = io.ReadFull(reader, make([]byte, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fetched := client.Calls(fault.DownloadBlob) - calls; fetched != 4 {
+			t.Fatalf("expected the block read and 3 ahead of it fetched, got %d", fetched)
+		}
+	})
+
+	t.Run("close-cancels", func(t *testing.T) {
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.SetLatency(time.Hour)
+		defer client.Reset()
+
+		readErr := make(chan error, 1)
+		go func() {
+			_, err := reader.Read(make([]byte, 10))
+			readErr <- err
+		}()
+		time.Sleep(50 * time.Millisecond)
+
+		closed := make(chan error, 1)
+		go func() {
+			closed <- reader.Close()
+		}()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("close did not stop the fetches")
+		}
+		if err := <-readErr; err == nil {
+			t.Fatalf("read of a closed reader succeeded")
+		}
+	})
+}
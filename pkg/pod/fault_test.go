@@ -0,0 +1,333 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	mrand "math/rand"
+	"sort"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// TestPod_Faults checks that a pod which fails half way through an operation
+// is left as it is seen by a fresh open of the pod from swarm.
+func TestPod_Faults(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	client := fault.New(mockClient, 1)
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+
+	podName1 := "test1"
+	podName2 := "test2"
+	podName3 := "test3"
+	podName4 := "test4"
+	podName5 := "test5"
+	podName6 := "test6"
+	podName7 := "test7"
+	firstDir := "dir1"
+
+	uploadFile := func(podName, podDir, fileName string, size int) error {
+		data := make([]byte, size)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.UploadFile(context.Background(), podName, fileName, int64(size), bytes.NewReader(data), podDir, "100", "false")
+		return err
+	}
+
+	t.Run("upload-fails-at-directory-update", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+
+		client.SetErrorRate(1, fault.UploadChunk)
+		err = uploadFile(podName1, ".", "file1", 540)
+		client.Reset()
+		if err == nil {
+			t.Fatalf("upload did not fail")
+		}
+		if info.getFile().IsFileAlreadyPResent(info.GetCurrentPodPathAndName() + "/file1") {
+			t.Fatalf("failed upload left the file in the pod")
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		// the blocks of the failed upload are garbage
+		stats, err := pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Blobs == 0 {
+			t.Fatalf("blocks of the failed upload not collected")
+		}
+
+		err = uploadFile(podName1, ".", "file1", 540)
+		if err != nil {
+			t.Fatalf("upload failed after the fault is gone: %v", err)
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("upload-fails-half-way-through-blocks", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName2, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+
+		client.FailAfter(fault.UploadBlob, 2)
+		err = uploadFile(podName2, ".", "file1", 1000)
+		client.Reset()
+		if err == nil {
+			t.Fatalf("upload did not fail")
+		}
+		if info.getFile().IsFileAlreadyPResent(info.GetCurrentPodPathAndName() + "/file1") {
+			t.Fatalf("failed upload left the file in the pod")
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.DeletePod(context.Background(), podName2)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("rm-fails-at-directory-update", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName3, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName3)
+		}
+		err = pod1.MakeDir(context.Background(), podName3, firstDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = uploadFile(podName3, ".", "file1", 540)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filePath := info.GetCurrentPodPathAndName() + "/file1"
+		refs, err := info.getFile().GetReferences(context.Background(), info.getFile().GetFromFileMap(filePath).MetaReference)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.SetErrorRate(1, fault.UploadChunk)
+		err = pod1.RemoveFile(context.Background(), podName3, "/file1")
+		client.Reset()
+		if err == nil {
+			t.Fatalf("rm did not fail")
+		}
+		if !info.getFile().IsFileAlreadyPResent(filePath) {
+			t.Fatalf("failed rm removed the file from the pod")
+		}
+		for _, ref := range refs {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("failed rm unpinned %s", hex.EncodeToString(ref))
+			}
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.RemoveFile(context.Background(), podName3, "/file1")
+		if err != nil {
+			t.Fatalf("rm failed after the fault is gone: %v", err)
+		}
+		checkConsistent(t, client, acc, logger, info)
+		if info.getDirectory().GetDirFromDirectoryMap(info.GetCurrentPodPathAndName()+utils.PathSeperator+firstDir) == nil {
+			t.Fatalf("rm of a file removed a directory next to it")
+		}
+
+		err = pod1.DeletePod(context.Background(), podName3)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("mkdir-fails-at-parent-update", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName4, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName4)
+		}
+
+		// the feed of the new directory gets created, its parent is not updated
+		client.FailAfter(fault.UploadChunk, 1)
+		err = pod1.MakeDir(context.Background(), podName4, firstDir)
+		client.Reset()
+		if err == nil {
+			t.Fatalf("mkdir did not fail")
+		}
+		dirPath := info.GetCurrentPodPathAndName() + utils.PathSeperator + firstDir
+		if info.getDirectory().GetDirFromDirectoryMap(dirPath) != nil {
+			t.Fatalf("failed mkdir left the directory in the pod")
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.MakeDir(context.Background(), podName4, firstDir)
+		if err != nil {
+			t.Fatalf("mkdir failed after the fault is gone: %v", err)
+		}
+		if info.getDirectory().GetDirFromDirectoryMap(dirPath) == nil {
+			t.Fatalf("directory not created")
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.DeletePod(context.Background(), podName4)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("receive-fails-releases-pins", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName5, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName5)
+		}
+		err = uploadFile(podName5, ".", "file1", 540)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := info.getFile().GetFromFileMap(info.GetCurrentPodPathAndName() + "/file1")
+
+		client.SetErrorRate(1, fault.UploadChunk)
+		err = pod1.ReceiveFileAndStore(context.Background(), podName5, utils.PathSeperator, "file2", hex.EncodeToString(meta.MetaReference))
+		client.Reset()
+		if err == nil {
+			t.Fatalf("receive did not fail")
+		}
+		if mockClient.PinCount(meta.MetaReference) != 1 {
+			t.Fatalf("failed receive kept its pins, count %d", mockClient.PinCount(meta.MetaReference))
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.DeletePod(context.Background(), podName5)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("partial-responses", func(t *testing.T) {
+		_, err := pod1.CreatePod(context.Background(), podName6, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName6)
+		}
+		err = uploadFile(podName6, ".", "file1", 540)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.SetPartialRate(1)
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName6, "/file1")
+		if err == nil {
+			_, err = ioutil.ReadAll(reader)
+		}
+		client.Reset()
+		if err == nil {
+			t.Fatalf("truncated file read without an error")
+		}
+
+		err = pod1.DeletePod(context.Background(), podName6)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("random-faults", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName7, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName7)
+		}
+		err = pod1.MakeDir(context.Background(), podName7, firstDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rnd := mrand.New(mrand.NewSource(1))
+		client.SetErrorRate(0.2)
+		for i := 0; i < 40; i++ {
+			switch rnd.Intn(4) {
+			case 0:
+				_ = uploadFile(podName7, ".", fmt.Sprintf("file%d", i), 300)
+			case 1:
+				_ = uploadFile(podName7, utils.PathSeperator+firstDir, fmt.Sprintf("file%d", i), 300)
+			case 2:
+				_ = pod1.MakeDir(context.Background(), podName7, fmt.Sprintf("dir%d", i))
+			case 3:
+				files := info.getFile().ListFiles(info.GetCurrentPodPathAndName() + utils.PathSeperator + "file")
+				if len(files) > 0 {
+					sort.Strings(files)
+					name := files[rnd.Intn(len(files))][len(info.GetCurrentPodPathAndName()):]
+					_ = pod1.RemoveFile(context.Background(), podName7, name)
+				}
+			}
+		}
+		client.Reset()
+		checkConsistent(t, client, acc, logger, info)
+
+		err = pod1.DeletePod(context.Background(), podName7)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+}
+
+// checkConsistent opens the pod afresh from swarm and compares its files and
+// directories with the ones of the pod in memory.
+func checkConsistent(t *testing.T, client blockstore.Client, acc *account.Account, logger logging.Logger, info *Info) {
+	t.Helper()
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	fresh, err := NewPod(client, fd, acc, logger).OpenPod(context.Background(), info.podName, "password")
+	if err != nil {
+		t.Fatalf("could not open pod afresh: %v", err)
+	}
+
+	podPath := info.GetCurrentPodPathAndName()
+	files, freshFiles := info.getFile().ListFiles(""), fresh.getFile().ListFiles("")
+	sort.Strings(files)
+	sort.Strings(freshFiles)
+	if fmt.Sprint(files) != fmt.Sprint(freshFiles) {
+		t.Fatalf("files %v in memory, %v in swarm", files, freshFiles)
+	}
+	dirs, freshDirs := info.getDirectory().ListDirsUnder(podPath), fresh.getDirectory().ListDirsUnder(podPath)
+	sort.Strings(dirs)
+	sort.Strings(freshDirs)
+	if fmt.Sprint(dirs) != fmt.Sprint(freshDirs) {
+		t.Fatalf("directories %v in memory, %v in swarm", dirs, freshDirs)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// GCStats tells what a garbage collection unpinned.
+type GCStats struct {
+	Blobs  int `json:"blobs"`
+	Chunks int `json:"chunks"`
+	Failed int `json:"failed"`
+}
+
+// CollectGarbage unpins the blobs and chunks pinned for the pod which are not
+// reachable from its tree any more, and retries the unpins which failed earlier. The
+// files and directories removed from the pod are unpinned as they are
+// removed, so this mostly finds what was left behind by failed operations.
+func (p *Pod) CollectGarbage(ctx context.Context, podName string) (*GCStats, error) {
+	if !p.isPodOpened(podName) {
+		return nil, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	podInfo.gcMu.Lock()
+	defer podInfo.gcMu.Unlock()
+
+	reachable, err := p.reachableReferences(ctx, podInfo)
+	if err != nil {
+		return nil, err
+	}
+	var refs []garbage
+	for _, g := range podInfo.pins.candidates() {
+		if !reachable[hex.EncodeToString(g.ref)] {
+			refs = append(refs, g)
+		}
+	}
+	return p.unpin(ctx, podInfo, refs), nil
+}
+
+// gcCheckpoint is what the last garbage collection of a pod found reachable,
+// so that the next one walks only what changed since: the updates of the
+// directory feeds after the latest ones it saw, and the files whose metadata
+// it did not see. It is kept in memory only, so the first collection after a
+// restart walks the whole pod.
+type gcCheckpoint struct {
+	feeds map[string]feedCheckpoint // directory path -> its feed
+	files map[string][][]byte       // metadata reference -> what it holds
+}
+
+// feedCheckpoint is what the updates of the feed of a directory hold, up to
+// the latest one seen.
+type feedCheckpoint struct {
+	latest []byte
+	refs   []garbage
+}
+
+// reachableReferences walks the directories and files of the pod and returns
+// all the chunks and blobs they are made of. That is every update of the feed
+// of every directory, as the older ones are still read by lookups. Only what
+// changed since the checkpoint of the last walk is read, and the checkpoint
+// is moved to this walk.
+func (p *Pod) reachableReferences(ctx context.Context, podInfo *Info) (map[string]bool, error) {
+	last := podInfo.gc
+	next := gcCheckpoint{
+		feeds: make(map[string]feedCheckpoint),
+		files: make(map[string][][]byte),
+	}
+	reachable := make(map[string]bool)
+	for _, path := range podInfo.getDirectory().ListDirsUnder(utils.PathSeperator + podInfo.podName) {
+		checkpoint, err := p.feedReferencesAfter(ctx, podInfo, path, last.feeds[path])
+		if err != nil {
+			return nil, err
+		}
+		next.feeds[path] = checkpoint
+		for _, g := range checkpoint.refs {
+			reachable[hex.EncodeToString(g.ref)] = true
+		}
+	}
+	file := podInfo.getFile()
+	for _, filePath := range file.ListFiles("") {
+		meta := file.GetFromFileMap(filePath)
+		if meta == nil {
+			continue
+		}
+
+		// the metadata is content addressed, so what it holds never changes
+		key := hex.EncodeToString(meta.MetaReference)
+		refs, ok := last.files[key]
+		if !ok {
+			var err error
+			refs, err = file.GetReferences(ctx, meta.MetaReference)
+			if err != nil {
+				return nil, err
+			}
+		}
+		next.files[key] = refs
+		for _, ref := range refs {
+			reachable[hex.EncodeToString(ref)] = true
+		}
+	}
+	podInfo.gc = next
+	return reachable, nil
+}
+
+// fileGarbage returns the references held by a file, to be unpinned once the
+// file is removed.
+func (p *Pod) fileGarbage(ctx context.Context, podInfo *Info, filePath string) ([]garbage, error) {
+	meta := podInfo.getFile().GetFromFileMap(filePath)
+	if meta == nil {
+		return nil, nil
+	}
+	refs, err := podInfo.getFile().GetReferences(ctx, meta.MetaReference)
+	if err != nil {
+		return nil, err
+	}
+	var refsGarbage []garbage
+	for _, ref := range refs {
+		refsGarbage = append(refsGarbage, garbage{ref: ref})
+	}
+	return refsGarbage, nil
+}
+
+// dirGarbage returns the references held by a directory and everything under
+// it, along with the paths of the files under it. That is every update of the
+// feed of every directory, as the older ones are pinned too.
+func (p *Pod) dirGarbage(ctx context.Context, podInfo *Info, dirPath string) ([]garbage, []string, error) {
+	var refs []garbage
+	for _, path := range podInfo.getDirectory().ListDirsUnder(dirPath) {
+		feedRefs, err := p.feedReferences(ctx, podInfo, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		refs = append(refs, feedRefs...)
+	}
+	files := podInfo.getFile().ListFiles(dirPath + utils.PathSeperator)
+	for _, filePath := range files {
+		fileRefs, err := p.fileGarbage(ctx, podInfo, filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		refs = append(refs, fileRefs...)
+	}
+	return refs, files, nil
+}
+
+// feedReferences returns the chunks of every update of the feed of a
+// directory, and the blobs holding the inode when it is too large for them.
+func (p *Pod) feedReferences(ctx context.Context, podInfo *Info, dirPath string) ([]garbage, error) {
+	checkpoint, err := p.feedReferencesAfter(ctx, podInfo, dirPath, feedCheckpoint{})
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint.refs, nil
+}
+
+// feedReferencesAfter returns what the feed of a directory holds, reading
+// only the updates after the latest one of the checkpoint.
+func (p *Pod) feedReferencesAfter(ctx context.Context, podInfo *Info, dirPath string, checkpoint feedCheckpoint) (feedCheckpoint, error) {
+	topic := utils.HashString(dirPath)
+	updates, found, err := podInfo.getFeed().GetFeedHistoryAfter(ctx, topic, podInfo.getAccountInfo().GetAddress(), checkpoint.latest)
+	if err != nil {
+		return feedCheckpoint{}, err
+	}
+	var next feedCheckpoint
+	for _, update := range updates {
+		next.refs = append(next.refs, garbage{ref: update.Address, chunk: true})
+		if update.PayloadReference != nil {
+			next.refs = append(next.refs, garbage{ref: update.PayloadReference})
+		}
+	}
+
+	// the walk stops at the checkpoint if it is in the history, otherwise it
+	// is the whole history
+	if found {
+		next.latest = checkpoint.latest
+		next.refs = append(next.refs, checkpoint.refs...)
+	}
+	if len(updates) > 0 {
+		next.latest = updates[0].Address
+	}
+	return next, nil
+}
+
+// podGarbage returns everything held by a pod, including what was pinned for it
+// which never got linked in to its tree.
+func (p *Pod) podGarbage(ctx context.Context, podInfo *Info) ([]garbage, error) {
+	refs, _, err := p.dirGarbage(ctx, podInfo, utils.PathSeperator+podInfo.podName)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, g := range refs {
+		seen[hex.EncodeToString(g.ref)] = true
+	}
+	for _, g := range podInfo.pins.candidates() {
+		if !seen[hex.EncodeToString(g.ref)] {
+			refs = append(refs, g)
+		}
+	}
+	return refs, nil
+}
+
+// pinAll pins the given blobs, releasing the ones already pinned if one of
+// them fails.
+func (p *Pod) pinAll(ctx context.Context, refs [][]byte) error {
+	for i, ref := range refs {
+		err := p.client.PinBlob(ctx, utils.NewReference(ref))
+		if err != nil {
+			p.unpinAll(ctx, refs[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// unpinAll releases the pins taken by pinAll.
+func (p *Pod) unpinAll(ctx context.Context, refs [][]byte) {
+	for _, ref := range refs {
+		err := p.client.UnpinBlob(ctx, utils.NewReference(ref))
+		if err != nil {
+			p.logger.Warningf("could not unpin %s: %v", hex.EncodeToString(ref), err)
+		}
+	}
+}
+
+// unpin releases the given references. The ones which fail are kept to be
+// retried by the next collection.
+func (p *Pod) unpin(ctx context.Context, podInfo *Info, refs []garbage) *GCStats {
+	stats := &GCStats{}
+	for _, g := range refs {
+		var err error
+		if g.chunk {
+			err = p.client.UnpinChunk(ctx, utils.NewReference(g.ref))
+		} else {
+			err = p.client.UnpinBlob(ctx, utils.NewReference(g.ref))
+		}
+		if err != nil {
+			p.logger.Warningf("gc: could not unpin %s: %v", hex.EncodeToString(g.ref), err)
+			podInfo.pins.addPending(g)
+			stats.Failed++
+			continue
+		}
+		podInfo.pins.forget(g.ref)
+		if g.chunk {
+			stats.Chunks++
+		} else {
+			stats.Blobs++
+		}
+	}
+	fields := logrus.Fields{
+		"pod":    podInfo.podName,
+		"blobs":  stats.Blobs,
+		"chunks": stats.Chunks,
+		"failed": stats.Failed,
+	}
+	p.logger.WithFields(fields).Log(logrus.DebugLevel, "gc: ")
+	return stats
+}
@@ -0,0 +1,490 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestPod_CollectGarbage(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	podName2 := "test2"
+	firstDir := "dir1"
+
+	fileReferences := func(t *testing.T, info *Info, filePath string) [][]byte {
+		t.Helper()
+		meta := info.getFile().GetFromFileMap(filePath)
+		if meta == nil {
+			t.Fatalf("file %s not found", filePath)
+		}
+		refs, err := info.getFile().GetReferences(context.Background(), meta.MetaReference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return refs
+	}
+
+	t.Run("rm-unpins-file", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName())
+		refs := fileReferences(t, info, info.GetCurrentPodPathAndName()+podFile)
+		for _, ref := range refs {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %s not pinned after upload", hex.EncodeToString(ref))
+			}
+		}
+
+		err = pod1.RemoveFile(context.Background(), podName1, podFile)
+		if err != nil {
+			t.Fatalf("error removing file: %v", err)
+		}
+		for _, ref := range refs {
+			if mockClient.PinCount(ref) != 0 {
+				t.Fatalf("blob %s still pinned after rm", hex.EncodeToString(ref))
+			}
+		}
+
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("rmdir-unpins-files-and-feeds", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		err = pod1.MakeDir(context.Background(), podName1, firstDir)
+		if err != nil {
+			t.Fatalf("error creating directory %s", firstDir)
+		}
+		dirPath := utils.PathSeperator + podName1 + utils.PathSeperator + firstDir
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, dirPath)
+		refs := fileReferences(t, info, info.GetCurrentPodPathAndName()+podFile)
+		feedAddr, _, err := info.getFeed().GetFeedData(context.Background(), utils.HashString(dirPath), info.getAccountInfo().GetAddress())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mockClient.PinCount(feedAddr) == 0 {
+			t.Fatalf("directory feed not pinned")
+		}
+		updates, err := info.getFeed().GetFeedHistory(context.Background(), utils.HashString(dirPath), info.getAccountInfo().GetAddress())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) < 2 {
+			t.Fatalf("expected the directory feed to be updated by the upload")
+		}
+
+		err = pod1.RemoveDir(context.Background(), podName1, firstDir)
+		if err != nil {
+			t.Fatalf("error removing directory: %v", err)
+		}
+		for _, ref := range refs {
+			if mockClient.PinCount(ref) != 0 {
+				t.Fatalf("blob %s still pinned after rmdir", hex.EncodeToString(ref))
+			}
+		}
+		for _, update := range updates {
+			if mockClient.PinCount(update.Address) != 0 {
+				t.Fatalf("directory feed update %s still pinned after rmdir", hex.EncodeToString(update.Address))
+			}
+		}
+		if info.getFile().IsFileAlreadyPResent(info.GetCurrentPodPathAndName() + podFile) {
+			t.Fatalf("file of removed directory still in file map")
+		}
+
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("gc-unpins-orphaned-blobs", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName())
+		refs := fileReferences(t, info, info.GetCurrentPodPathAndName()+podFile)
+
+		// a block of an upload which never got linked in to the pod
+		orphan, err := info.pins.UploadBlob(context.Background(), []byte("orphaned block"), true, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("error collecting garbage: %v", err)
+		}
+		if stats.Blobs != 1 || stats.Chunks != 0 || stats.Failed != 0 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		if mockClient.PinCount(orphan) != 0 {
+			t.Fatalf("orphaned blob still pinned")
+		}
+		for _, ref := range refs {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("reachable blob %s unpinned", hex.EncodeToString(ref))
+			}
+		}
+
+		stats, err = pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("error collecting garbage: %v", err)
+		}
+		if stats.Blobs != 0 {
+			t.Fatalf("blobs unpinned twice")
+		}
+
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+
+	t.Run("received-file-outlives-sender", func(t *testing.T) {
+		info1, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		info2, err := pod1.CreatePod(context.Background(), podName2, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, info1.GetCurrentPodPathAndName())
+		filePath := info1.GetCurrentPodPathAndName() + podFile
+		refs := fileReferences(t, info1, filePath)
+		meta := info1.getFile().GetFromFileMap(filePath)
+
+		err = pod1.ReceiveFileAndStore(context.Background(), podName2, utils.PathSeperator, filepath.Base(podFile), hex.EncodeToString(meta.MetaReference))
+		if err != nil {
+			t.Fatalf("error receiving file: %v", err)
+		}
+		err = pod1.RemoveFile(context.Background(), podName1, podFile)
+		if err != nil {
+			t.Fatalf("error removing file: %v", err)
+		}
+		for _, ref := range refs {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %s of received file unpinned", hex.EncodeToString(ref))
+			}
+		}
+
+		err = pod1.RemoveFile(context.Background(), podName2, podFile)
+		if err != nil {
+			t.Fatalf("error removing file: %v", err)
+		}
+		for _, ref := range refs {
+			if mockClient.PinCount(ref) != 0 {
+				t.Fatalf("blob %s still pinned after both copies are removed", hex.EncodeToString(ref))
+			}
+		}
+		if info2.getFile().IsFileAlreadyPResent(info2.GetCurrentPodPathAndName() + podFile) {
+			t.Fatalf("received file not removed")
+		}
+
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+		err = pod1.DeletePod(context.Background(), podName2)
+		if err != nil {
+			t.Fatalf("could not delete pod")
+		}
+	})
+}
+
+func TestPod_CollectGarbageAfterRestart(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataDir, err := ioutil.TempDir("", "pins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	pins, err := NewPins(dataDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPodWithOptions(mockClient, fd, acc, Options{Pins: pins}, logger)
+
+	podName1 := "test1"
+
+	t.Run("orphans-found-after-restart", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName())
+		orphan, err := info.pins.UploadBlob(context.Background(), []byte("orphaned before restart"), true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := make([]byte, swarm.HashSize)
+		_, err = rand.Read(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orphanChunk, err := info.pins.UploadChunk(context.Background(), swarm.NewChunk(swarm.NewAddress(addr), []byte("orphaned chunk")), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a new session of the same user, which only has the pins kept
+		pod2 := NewPodWithOptions(mockClient, feed.New(acc.GetUserAccountInfo(), mockClient, logger), acc, Options{Pins: pins}, logger)
+		info, err = pod2.OpenPod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error opening pod: %v", err)
+		}
+		stats, err := pod2.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("error collecting garbage: %v", err)
+		}
+		if stats.Blobs != 1 || stats.Chunks != 1 || stats.Failed != 0 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		if mockClient.PinCount(orphan) != 0 || mockClient.PinCount(orphanChunk) != 0 {
+			t.Fatalf("orphans still pinned")
+		}
+
+		// the updates of the directory feeds are all reachable
+		updates, err := info.getFeed().GetFeedHistory(context.Background(), utils.HashString(info.GetCurrentPodPathAndName()), info.getAccountInfo().GetAddress())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updates) < 2 {
+			t.Fatalf("expected the pod feed to be updated by the upload")
+		}
+		for _, update := range updates {
+			if mockClient.PinCount(update.Address) == 0 {
+				t.Fatalf("pod feed update %s unpinned", hex.EncodeToString(update.Address))
+			}
+		}
+		err = pod2.CopyToLocal(context.Background(), podName1, podFile, dataDir)
+		if err != nil {
+			t.Fatalf("file not readable after collection: %v", err)
+		}
+
+		err = pod2.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatalf("could not delete pod: %v", err)
+		}
+		for _, update := range updates {
+			if mockClient.PinCount(update.Address) != 0 {
+				t.Fatalf("pod feed update %s still pinned after delete", hex.EncodeToString(update.Address))
+			}
+		}
+	})
+
+	t.Run("delete-closed-pod", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		podFile := createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName())
+		meta := info.getFile().GetFromFileMap(info.GetCurrentPodPathAndName() + podFile)
+		refs, err := info.getFile().GetReferences(context.Background(), meta.MetaReference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.ClosePod(podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the account of the pod is at hand, so it is opened to unpin it
+		err = pod1.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pods, err := pod1.ListPods(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pods) != 0 {
+			t.Fatalf("pod not deleted: %v", pods)
+		}
+		if pod1.isPodOpened(podName1) {
+			t.Fatalf("pod left open by the delete")
+		}
+		for _, ref := range refs {
+			if mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %s still pinned after delete", hex.EncodeToString(ref))
+			}
+		}
+	})
+
+	t.Run("delete-pod-never-opened", func(t *testing.T) {
+		acc1 := account.New(logger)
+		mnemonic, _, err := acc1.CreateUserAccount("password", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		device1 := NewPod(mockClient, feed.New(acc1.GetUserAccountInfo(), mockClient, logger), acc1, logger)
+		_, err = device1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+
+		// the user on another device, where the pod was never opened
+		acc2 := account.New(logger)
+		_, _, err = acc2.CreateUserAccount("password", mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		device2 := NewPod(mockClient, feed.New(acc2.GetUserAccountInfo(), mockClient, logger), acc2, logger)
+		err = device2.DeletePod(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pods, err := device2.ListPods(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pods) != 0 {
+			t.Fatalf("pod not deleted: %v", pods)
+		}
+	})
+}
+
+func TestPod_CollectGarbageCheckpoint(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	client := fault.New(mockClient, 1)
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+	podName1 := "test1"
+	info, err := pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	podPath := info.GetCurrentPodPathAndName()
+	for i := 0; i < 3; i++ {
+		createRandomFileInPod(t, 540, pod1, podName1, podPath)
+	}
+
+	// collect runs a collection and returns how many blobs it read
+	collect := func(t *testing.T) (*GCStats, int) {
+		t.Helper()
+		before := client.Calls(fault.DownloadBlob)
+		stats, err := pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stats, client.Calls(fault.DownloadBlob) - before
+	}
+
+	_, read := collect(t)
+	if read == 0 {
+		t.Fatalf("first collection read no files")
+	}
+	_, read = collect(t)
+	if read != 0 {
+		t.Fatalf("collection read %d blobs of files it walked before", read)
+	}
+
+	// what changed after the checkpoint is walked, and what was before it is
+	// still reachable
+	podFile := createRandomFileInPod(t, 540, pod1, podName1, podPath)
+	orphan, err := info.pins.UploadBlob(context.Background(), []byte("orphaned after the checkpoint"), true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := collect(t)
+	if stats.Blobs != 1 || stats.Chunks != 0 || stats.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if mockClient.PinCount(orphan) != 0 {
+		t.Fatalf("orphan still pinned")
+	}
+	updates, err := info.getFeed().GetFeedHistory(context.Background(), utils.HashString(podPath), info.getAccountInfo().GetAddress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, update := range updates {
+		if mockClient.PinCount(update.Address) == 0 {
+			t.Fatalf("pod feed update %s unpinned", hex.EncodeToString(update.Address))
+		}
+	}
+	for _, filePath := range info.getFile().ListFiles("") {
+		meta := info.getFile().GetFromFileMap(filePath)
+		refs, err := info.getFile().GetReferences(context.Background(), meta.MetaReference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range refs {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %s of %s unpinned", hex.EncodeToString(ref), filePath)
+			}
+		}
+	}
+	dataDir, err := ioutil.TempDir("", "gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	err = pod1.CopyToLocal(context.Background(), podName1, podFile, dataDir)
+	if err != nil {
+		t.Fatalf("file not readable after collection: %v", err)
+	}
+}
@@ -32,6 +32,10 @@ type Info struct {
 	file            *f.File
 	accountInfo     *account.AccountInfo
 	feed            *feed.API
+	pins            *pinTracker
+	modifyMu        sync.Mutex // one file of the pod is modified at a time
+	gcMu            sync.Mutex // one garbage collection of the pod runs at a time
+	gc              gcCheckpoint
 	currentPodInode *di.DirInode
 	curPodMu        sync.RWMutex
 	currentDirInode *di.DirInode
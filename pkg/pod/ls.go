@@ -17,14 +17,15 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"strings"
 
 	"github.com/jmozah/intOS-dfs/pkg/dir"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) ListPods() ([]string, error) {
-	pods, err := p.loadUserPods()
+func (p *Pod) ListPods(ctx context.Context) ([]string, error) {
+	pods, err := p.loadUserPods(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +37,7 @@ func (p *Pod) ListPods() ([]string, error) {
 	return listPods, nil
 }
 
-func (p *Pod) ListEntiesInDir(podName, dirName string) ([]dir.DirOrFileEntry, error) {
+func (p *Pod) ListEntiesInDir(ctx context.Context, podName, dirName string) ([]dir.DirOrFileEntry, error) {
 	if !p.isPodOpened(podName) {
 		return nil, ErrPodNotOpened
 	}
@@ -60,5 +61,5 @@ func (p *Pod) ListEntiesInDir(podName, dirName string) ([]dir.DirOrFileEntry, er
 		path = strings.TrimSuffix(path, utils.PathSeperator)
 	}
 
-	return directory.ListDir(podName, path, printNames), nil
+	return directory.ListDir(ctx, podName, path, printNames), nil
 }
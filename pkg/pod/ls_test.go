@@ -17,6 +17,8 @@ limitations under the License.
 package pod
 
 import (
+	"context"
+	"fmt"
 	"io/ioutil"
 	"testing"
 
@@ -42,23 +44,23 @@ func TestPod_ListPods(t *testing.T) {
 	podName2 := "test2"
 
 	t.Run("list-without-pods", func(t *testing.T) {
-		_, err = pod1.ListPods()
+		_, err = pod1.ListPods(context.Background())
 		if err != nil {
 			t.Fatal(err)
 		}
 	})
 
 	t.Run("create-two-pods", func(t *testing.T) {
-		_, err := pod1.CreatePod(podName1, "password")
+		_, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod: %v", err)
 		}
-		_, err = pod1.CreatePod(podName2, "password")
+		_, err = pod1.CreatePod(context.Background(), podName2, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
-		pods, err := pod1.ListPods()
+		pods, err := pod1.ListPods(context.Background())
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -71,3 +73,40 @@ func TestPod_ListPods(t *testing.T) {
 		}
 	})
 }
+
+func TestPod_ListLargeDirectory(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+
+	podName1 := "test1"
+	t.Run("directory-larger-than-a-chunk", func(t *testing.T) {
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+
+		// the inode of the pod outgrows a chunk after a hundred or so entries
+		count := 150
+		for i := 0; i < count; i++ {
+			err = pod1.MakeDir(context.Background(), podName1, fmt.Sprintf("dir%d", i))
+			if err != nil {
+				t.Fatalf("error creating directory %d: %v", i, err)
+			}
+		}
+		entries, err := pod1.ListEntiesInDir(context.Background(), podName1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != count {
+			t.Fatalf("expected %d entries, got %d", count, len(entries))
+		}
+		checkConsistent(t, mockClient, acc, logger, info)
+	})
+}
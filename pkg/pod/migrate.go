@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pod
+
+import (
+	"context"
+
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// MigrateToBeeFeeds rewrites the feeds of all the directories of an open pod
+// in the format of the feeds of bee, so that the pod resolves through bee's
+// /feeds endpoint and so through any Swarm gateway. It returns how many
+// directories there are. The feeds already in that format are left as they
+// are, so a migration which failed half way can just be run again.
+func (p *Pod) MigrateToBeeFeeds(ctx context.Context, podName string) (int, error) {
+	if !p.isPodOpened(podName) {
+		return 0, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return 0, err
+	}
+
+	dirs := podInfo.getDirectory().ListDirsUnder(utils.PathSeperator + podInfo.podName)
+	for _, path := range dirs {
+		topic := utils.HashString(path)
+		_, err := podInfo.getFeed().MigrateToBeeFormat(ctx, topic, podInfo.getAccountInfo().GetAddress())
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(dirs), nil
+}
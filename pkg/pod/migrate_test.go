@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestPod_MigrateToBeeFeeds(t *testing.T) {
+	client := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	podName1 := "test1"
+	podName2 := "test2"
+
+	// inBeeFormat tells whether all the directories of the pod have their
+	// feeds in the bee format, which migrating leaves as they are
+	inBeeFormat := func(t *testing.T, info *Info) bool {
+		t.Helper()
+		fd := info.getFeed()
+		user := info.getAccountInfo().GetAddress()
+		for _, path := range info.getDirectory().ListDirsUnder(info.GetCurrentPodPathAndName()) {
+			topic := utils.HashString(path)
+			addr, _, err := fd.GetFeedData(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			migrated, err := fd.MigrateToBeeFormat(context.Background(), topic, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(addr, migrated) {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("migrate", func(t *testing.T) {
+		pod1 := NewPod(client, feed.New(acc.GetUserAccountInfo(), client, logger), acc, logger)
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		err = pod1.MakeDir(context.Background(), podName1, "dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName()+"/dir1")
+
+		dirs, err := pod1.MigrateToBeeFeeds(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dirs != 2 {
+			t.Fatalf("expected 2 directories migrated, got %d", dirs)
+		}
+		if !inBeeFormat(t, info) {
+			t.Fatal("directories not migrated")
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		// the pod is changed as before
+		createRandomFileInPod(t, 540, pod1, podName1, info.GetCurrentPodPathAndName()+"/dir1")
+		err = pod1.MakeDir(context.Background(), podName1, "dir2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkConsistent(t, client, acc, logger, info)
+
+		// the directories made after it are in the format they are created in
+		if inBeeFormat(t, info) {
+			t.Fatal("new directory not in the dfs format")
+		}
+	})
+
+	t.Run("created-in-bee-format", func(t *testing.T) {
+		fd := feed.NewWithOptions(acc.GetUserAccountInfo(), client, feed.Options{Format: feed.BeeFormat}, logger)
+		pod2 := NewPod(client, fd, acc, logger)
+		info, err := pod2.CreatePod(context.Background(), podName2, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+		err = pod2.MakeDir(context.Background(), podName2, "dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		createRandomFileInPod(t, 540, pod2, podName2, info.GetCurrentPodPathAndName()+"/dir1")
+		if !inBeeFormat(t, info) {
+			t.Fatal("directories not created in the bee format")
+		}
+		checkConsistent(t, client, acc, logger, info)
+	})
+}
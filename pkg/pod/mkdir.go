@@ -18,14 +18,14 @@ package pod
 
 import (
 	"bytes"
+	"context"
 	gopath "path"
-	"time"
 
 	d "github.com/jmozah/intOS-dfs/pkg/dir"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) MakeDir(podName string, dirName string) error {
+func (p *Pod) MakeDir(ctx context.Context, podName string, dirName string) error {
 	dirs, err := CleanDirName(dirName)
 	if err != nil {
 		return err
@@ -52,15 +52,15 @@ func (p *Pod) MakeDir(podName string, dirName string) error {
 	if len(dirs) > 1 {
 		for i, dirName := range dirs {
 			path := p.buildPath(podInfo, dirs, i)
-			_, dirInode, err = directory.GetDirNode(path, podInfo.getFeed(), podInfo.getAccountInfo())
+			_, dirInode, err = directory.GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
 			if err != nil {
 				if previousDirINode == nil {
 					if podInfo.IsCurrentDirRoot() {
 						addToPod = true
 					}
-					dirInode, topic, err = directory.CreateDirINode(podName, dirName, podInfo.GetCurrentDirInode())
+					dirInode, topic, err = directory.CreateDirINode(ctx, podName, dirName, podInfo.GetCurrentDirInode())
 				} else {
-					dirInode, topic, err = directory.CreateDirINode(podName, dirName, previousDirINode)
+					dirInode, topic, err = directory.CreateDirINode(ctx, podName, dirName, previousDirINode)
 				}
 				if err != nil {
 					return err
@@ -69,21 +69,16 @@ func (p *Pod) MakeDir(podName string, dirName string) error {
 					firstTopic = topic
 				}
 
-				if previousDirINode != nil {
-					found := false
-					for _, hash := range previousDirINode.Hashes {
-						if bytes.Equal(hash, topic) {
-							found = true
-						}
-					}
-					if !found {
-						previousDirINode.Hashes = append(previousDirINode.Hashes, topic)
-						dirInode.Meta.Path = previousDirINode.Meta.Path + utils.PathSeperator + previousDirINode.Meta.Name
-						previousDirINode.Meta.ModificationTime = time.Now().Unix()
-						_, err = directory.UpdateDirectory(previousDirINode)
-						if err != nil {
-							return err
+				if previousDirINode != nil && !containsHash(previousDirINode.Hashes, topic) {
+					dirInode.Meta.Path = previousDirINode.Meta.Path + utils.PathSeperator + previousDirINode.Meta.Name
+					_, _, err = directory.ModifyDirectory(ctx, p.buildPath(podInfo, dirs, i-1), func(parent *d.DirInode) error {
+						if !containsHash(parent.Hashes, topic) {
+							parent.Hashes = append(parent.Hashes, topic)
 						}
+						return nil
+					})
+					if err != nil {
+						return err
 					}
 				}
 			}
@@ -92,14 +87,36 @@ func (p *Pod) MakeDir(podName string, dirName string) error {
 		topic = firstTopic
 	} else {
 		dirInode = podInfo.GetCurrentDirInode()
-		if directory.IsDirINodePresent(podName, dirs[0], dirInode) {
-			return err
+		parentPath := podInfo.GetCurrentDirPathAndName()
+		if podInfo.IsCurrentDirRoot() {
+			parentPath = podInfo.GetCurrentPodPathAndName()
+		}
+		dirPath := parentPath + utils.PathSeperator + dirs[0]
+		if directory.IsDirINodePresent(ctx, podName, dirs[0], dirInode) {
+			// the feed of a directory can be left unlinked from its parent
+			// by an earlier mkdir which failed half way, link it now
+			topic = utils.HashString(dirPath)
+			linked, err := p.isLinked(ctx, podInfo, parentPath, topic)
+			if err != nil || linked {
+				return err
+			}
+			_, newDirInode, err := directory.GetDirNode(ctx, dirPath, podInfo.getFeed(), podInfo.getAccountInfo())
+			if err != nil {
+				return err
+			}
+			directory.AddToDirectoryMap(dirPath, newDirInode)
+		} else {
+			_, topic, err = directory.CreateDirINode(ctx, podName, dirs[0], dirInode)
+			if err != nil {
+				return err
+			}
 		}
-		_, topic, err = directory.CreateDirINode(podName, dirs[0], dirInode)
+		err = p.UpdateTillThePod(ctx, podName, directory, topic, parentPath, true)
 		if err != nil {
+			directory.RemoveFromDirectoryMap(dirPath)
 			return err
 		}
-		addToPod = true
+		return nil
 	}
 
 	if addToPod {
@@ -107,7 +124,7 @@ func (p *Pod) MakeDir(podName string, dirName string) error {
 		if podInfo.IsCurrentDirRoot() {
 			path = podInfo.GetCurrentPodPathAndName()
 		}
-		err = p.UpdateTillThePod(podName, directory, topic, path, true)
+		err = p.UpdateTillThePod(ctx, podName, directory, topic, path, true)
 		if err != nil {
 			return err
 		}
@@ -115,8 +132,26 @@ func (p *Pod) MakeDir(podName string, dirName string) error {
 	return nil
 }
 
+// isLinked tells if the directory at path holds the topic.
+func (p *Pod) isLinked(ctx context.Context, podInfo *Info, path string, topic []byte) (bool, error) {
+	_, dirInode, err := podInfo.getDirectory().GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
+	if err != nil {
+		return false, err
+	}
+	return containsHash(dirInode.Hashes, topic), nil
+}
+
+func containsHash(hashes [][]byte, hash []byte) bool {
+	for _, h := range hashes {
+		if bytes.Equal(h, hash) {
+			return true
+		}
+	}
+	return false
+}
+
 // Assumption is that the d.currentDirInode is the newly updated one
-func (p *Pod) UpdateTillThePod(podName string, directory *d.Directory, topic []byte, path string, isAddHash bool) error {
+func (p *Pod) UpdateTillThePod(ctx context.Context, podName string, directory *d.Directory, topic []byte, path string, isAddHash bool) error {
 	podInfo, err := p.GetPodInfoFromPodMap(podName)
 	if err != nil {
 		return err
@@ -124,44 +159,38 @@ func (p *Pod) UpdateTillThePod(podName string, directory *d.Directory, topic []b
 
 	var dirInode *d.DirInode
 	for path != utils.PathSeperator {
-		_, dirInode, err = directory.GetDirNode(path, podInfo.getFeed(), podInfo.getAccountInfo())
-		if err != nil {
-			return err
+		// ignore if it is the current dir, otherwise there will be a loop
+		if isAddHash && bytes.Equal(utils.HashString(path), topic) {
+			_, dirInode, err = directory.GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
+			if err != nil {
+				return err
+			}
+			path = gopath.Dir(path)
+			continue
 		}
-		if isAddHash {
-			// Add or update a hash
-			found := false
-			for i, hash := range dirInode.Hashes {
-				if bytes.Equal(hash, topic) {
-					found = true
-					dirInode.Hashes[i] = topic
+		removeHash := !isAddHash
+		child := topic
+		dirInode, topic, err = directory.ModifyDirectory(ctx, path, func(parent *d.DirInode) error {
+			if !removeHash {
+				// add the hash if it is not there yet
+				if !containsHash(parent.Hashes, child) {
+					parent.Hashes = append(parent.Hashes, child)
 				}
+				return nil
 			}
-			// ignore if it is the current dir, otherwise there will be a loop
-			pathTopic := utils.HashString(path)
-			if bytes.Equal(pathTopic, topic) {
-				path = gopath.Dir(path)
-				continue
-			}
-			if !found {
-				dirInode.Hashes = append(dirInode.Hashes, topic)
-			}
-		} else {
-			// remove hash
 			var newHashes [][]byte
-			for _, hash := range dirInode.Hashes {
-				if !bytes.Equal(hash, topic) {
+			for _, hash := range parent.Hashes {
+				if !bytes.Equal(hash, child) {
 					newHashes = append(newHashes, hash)
 				}
 			}
-			dirInode.Hashes = newHashes
-			isAddHash = true // after the first deletion, the rest of the parent links should be updated
-		}
-		dirInode.Meta.ModificationTime = time.Now().Unix()
-		topic, err = directory.UpdateDirectory(dirInode)
+			parent.Hashes = newHashes
+			return nil
+		})
 		if err != nil {
 			return err
 		}
+		isAddHash = true // after the first deletion, the rest of the parent links should be updated
 		path = gopath.Dir(path)
 	}
 	podInfo.SetCurrentPodInode(dirInode)
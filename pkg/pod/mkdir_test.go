@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -50,12 +51,12 @@ func TestPod_MakeDir(t *testing.T) {
 	thirdDir := "dir3/dir4"
 	fourthDir := "/dir5"
 	t.Run("mkdir-on-root-of-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
-		err = pod1.MakeDir(podName1, firstDir)
+		err = pod1.MakeDir(context.Background(), podName1, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
@@ -76,29 +77,29 @@ func TestPod_MakeDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("mkdir-second-dir-from-first-dir", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName2, "password")
+		info, err := pod1.CreatePod(context.Background(), podName2, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName2)
 		}
 
-		err = pod1.MakeDir(podName2, firstDir)
+		err = pod1.MakeDir(context.Background(), podName2, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
 
-		_, err = pod1.ChangeDir(podName2, firstDir)
+		_, err = pod1.ChangeDir(context.Background(), podName2, firstDir)
 		if err != nil {
 			t.Fatalf("error changing directory")
 		}
 
-		err = pod1.MakeDir(podName2, secondDir)
+		err = pod1.MakeDir(context.Background(), podName2, secondDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", secondDir)
 		}
@@ -119,24 +120,24 @@ func TestPod_MakeDir(t *testing.T) {
 		}
 
 		// cleanup directory and pod
-		err = pod1.DeletePod(podName2)
+		err = pod1.DeletePod(context.Background(), podName2)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("mkdir-second-dir-from-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName3, "password")
+		info, err := pod1.CreatePod(context.Background(), podName3, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName3)
 		}
 
-		err = pod1.MakeDir(podName3, firstDir)
+		err = pod1.MakeDir(context.Background(), podName3, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", err)
 		}
 		time.Sleep(1 * time.Second)
-		err = pod1.MakeDir(podName3, firstDir+utils.PathSeperator+secondDir)
+		err = pod1.MakeDir(context.Background(), podName3, firstDir+utils.PathSeperator+secondDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", err)
 		}
@@ -157,19 +158,19 @@ func TestPod_MakeDir(t *testing.T) {
 		}
 
 		// cleanup directory and pod
-		err = pod1.DeletePod(podName3)
+		err = pod1.DeletePod(context.Background(), podName3)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("mkdir-multiple-dirs-from-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName4, "password")
+		info, err := pod1.CreatePod(context.Background(), podName4, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName4)
 		}
 
-		err = pod1.MakeDir(podName4, thirdDir)
+		err = pod1.MakeDir(context.Background(), podName4, thirdDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", thirdDir)
 		}
@@ -207,19 +208,19 @@ func TestPod_MakeDir(t *testing.T) {
 		}
 
 		// cleanup directory and pod
-		err = pod1.DeletePod(podName4)
+		err = pod1.DeletePod(context.Background(), podName4)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("mkdir-with-slash-on-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName5, "password")
+		info, err := pod1.CreatePod(context.Background(), podName5, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName5)
 		}
 
-		err = pod1.MakeDir(podName5, fourthDir)
+		err = pod1.MakeDir(context.Background(), podName5, fourthDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", fourthDir)
 		}
@@ -240,7 +241,7 @@ func TestPod_MakeDir(t *testing.T) {
 		}
 
 		// cleanup directory and pod
-		err = pod1.DeletePod(podName5)
+		err = pod1.DeletePod(context.Background(), podName5)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
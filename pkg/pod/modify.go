@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	gopath "path"
+
+	d "github.com/jmozah/intOS-dfs/pkg/dir"
+	f "github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// WriteFile writes data in to a file of the pod from offset on, and returns
+// the new reference of the file. An offset past the end of the file is
+// rejected with file.ErrInvalidOffset.
+func (p *Pod) WriteFile(ctx context.Context, podName, podFile string, data []byte, offset uint64) (string, error) {
+	return p.modifyFile(ctx, podName, podFile, func(file *f.File, path string) (*f.Modification, error) {
+		return file.WriteAt(ctx, path, data, offset)
+	})
+}
+
+// AppendFile adds data to the end of a file of the pod, and returns the new
+// reference of the file.
+func (p *Pod) AppendFile(ctx context.Context, podName, podFile string, data []byte) (string, error) {
+	return p.modifyFile(ctx, podName, podFile, func(file *f.File, path string) (*f.Modification, error) {
+		return file.Append(ctx, path, data)
+	})
+}
+
+// TruncateFile cuts a file of the pod down to size, and returns the new
+// reference of the file. A size past the end of the file is rejected with
+// file.ErrInvalidOffset.
+func (p *Pod) TruncateFile(ctx context.Context, podName, podFile string, size uint64) (string, error) {
+	return p.modifyFile(ctx, podName, podFile, func(file *f.File, path string) (*f.Modification, error) {
+		return file.Truncate(ctx, path, size)
+	})
+}
+
+// modifyFile publishes the file made by modify in the place of the old one
+// in its directory, and unpins the blobs the change released.
+func (p *Pod) modifyFile(ctx context.Context, podName, podFile string, modify func(file *f.File, path string) (*f.Modification, error)) (string, error) {
+	if !p.isPodOpened(podName) {
+		return "", fmt.Errorf("login to pod to do this operation")
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return "", err
+	}
+	podInfo.modifyMu.Lock()
+	defer podInfo.modifyMu.Unlock()
+
+	path := p.getPodFilePath(podFile, podInfo)
+	file := podInfo.getFile()
+	oldMeta := file.GetFromFileMap(path)
+	if oldMeta == nil {
+		return "", fmt.Errorf("file not present in pod")
+	}
+	mod, err := modify(file, path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(mod.Reference, mod.OldReference) {
+		return utils.NewReference(mod.Reference).String(), nil
+	}
+
+	dirPath := gopath.Dir(path)
+	_, topic, err := podInfo.getDirectory().ModifyDirectory(ctx, dirPath, func(dirInode *d.DirInode) error {
+		for i, hash := range dirInode.Hashes {
+			if bytes.Equal(hash, mod.OldReference) {
+				dirInode.Hashes[i] = mod.Reference
+				return nil
+			}
+		}
+		return fmt.Errorf("file not present in directory")
+	})
+	if err != nil {
+		// the new file is not linked, its blocks are left to the gc
+		file.AddToFileMap(path, oldMeta)
+		return "", err
+	}
+
+	if dirPath != podInfo.GetCurrentPodPathAndName() {
+		err = p.UpdateTillThePod(ctx, podName, podInfo.getDirectory(), topic, dirPath, true)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var refs []garbage
+	for _, ref := range mod.Released {
+		refs = append(refs, garbage{ref: ref})
+	}
+	p.unpin(ctx, podInfo, refs)
+	return utils.NewReference(mod.Reference).String(), nil
+}
@@ -0,0 +1,255 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestPod_ModifyFile(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	client := fault.New(mockClient, 1)
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), client, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+	podName1 := "test1"
+	info, err := pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.MakeDir(context.Background(), podName1, "dir1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// upload writes a file of size random bytes in blocks of 100 bytes
+	upload := func(t *testing.T, name string, size int, compression string) []byte {
+		t.Helper()
+		data := randomBytes(t, size)
+		_, err := pod1.UploadFile(context.Background(), podName1, name, int64(size), bytes.NewReader(data), "/dir1", "100", compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// check reads the file back and compares it with what it should be
+	check := func(t *testing.T, podFile string, expected []byte) {
+		t.Helper()
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, podFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Fatalf("file is not the one expected, got %d bytes, expected %d", len(got), len(expected))
+		}
+		stat, err := pod1.FileStat(context.Background(), podName1, podFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.FileSize != strconv.Itoa(len(expected)) {
+			t.Fatalf("expected size %d, got %s", len(expected), stat.FileSize)
+		}
+	}
+
+	// blocks returns the addresses of the blocks the file is made of
+	blocks := func(t *testing.T, podFile string) [][]byte {
+		t.Helper()
+		meta := info.getFile().GetFromFileMap("/" + podName1 + podFile)
+		data, _, err := mockClient.DownloadBlob(context.Background(), meta.InodeAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var inode file.FileINode
+		err = json.Unmarshal(data, &inode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var addrs [][]byte
+		for _, fb := range inode.FileBlocks {
+			addrs = append(addrs, fb.Address)
+		}
+		return addrs
+	}
+
+	for _, compression := range []string{"", "snappy"} {
+		name := "file-" + compression
+		podFile := "/dir1/" + name
+		data := upload(t, name, 1000, compression)
+
+		t.Run("append-"+name, func(t *testing.T) {
+			before := blocks(t, podFile)
+			calls := client.Calls(fault.UploadBlob)
+			extra := randomBytes(t, 150)
+			_, err := pod1.AppendFile(context.Background(), podName1, podFile, extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = append(data, extra...)
+			check(t, podFile, data)
+
+			// the two new blocks, the inode and the meta
+			if got := client.Calls(fault.UploadBlob) - calls; got != 4 {
+				t.Fatalf("expected 4 blobs uploaded, got %d", got)
+			}
+			after := blocks(t, podFile)
+			for i := range before {
+				if !bytes.Equal(before[i], after[i]) {
+					t.Fatalf("block %d rewritten", i)
+				}
+			}
+		})
+
+		t.Run("write-at-"+name, func(t *testing.T) {
+			before := blocks(t, podFile)
+			patch := randomBytes(t, 120)
+			_, err := pod1.WriteFile(context.Background(), podName1, podFile, patch, 250)
+			if err != nil {
+				t.Fatal(err)
+			}
+			copy(data[250:], patch)
+			check(t, podFile, data)
+
+			after := blocks(t, podFile)
+			for i := range before {
+				rewritten := i == 2 || i == 3
+				if bytes.Equal(before[i], after[i]) == rewritten {
+					t.Fatalf("block %d rewritten: %v, expected %v", i, !rewritten, rewritten)
+				}
+			}
+			// the version before the write keeps the blocks it replaced
+			for _, i := range []int{2, 3} {
+				if !mockClient.IsBlobPinned(before[i]) {
+					t.Fatalf("replaced block %d unpinned", i)
+				}
+			}
+		})
+
+		t.Run("write-past-end-"+name, func(t *testing.T) {
+			patch := randomBytes(t, 30)
+			_, err := pod1.WriteFile(context.Background(), podName1, podFile, patch, uint64(len(data)+200))
+			if err != file.ErrInvalidOffset {
+				t.Fatalf("expected %v, got %v", file.ErrInvalidOffset, err)
+			}
+			_, err = pod1.WriteFile(context.Background(), podName1, podFile, patch, math.MaxUint64)
+			if err != file.ErrInvalidOffset {
+				t.Fatalf("expected %v, got %v", file.ErrInvalidOffset, err)
+			}
+			check(t, podFile, data)
+
+			// right at the end is fine
+			_, err = pod1.WriteFile(context.Background(), podName1, podFile, patch, uint64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = append(data, patch...)
+			check(t, podFile, data)
+		})
+
+		t.Run("truncate-"+name, func(t *testing.T) {
+			before := blocks(t, podFile)
+			_, err := pod1.TruncateFile(context.Background(), podName1, podFile, 420)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = data[:420]
+			check(t, podFile, data)
+			for i := 5; i < len(before); i++ {
+				if !mockClient.IsBlobPinned(before[i]) {
+					t.Fatalf("block %d past the end unpinned", i)
+				}
+			}
+
+			_, err = pod1.TruncateFile(context.Background(), podName1, podFile, 650)
+			if err != file.ErrInvalidOffset {
+				t.Fatalf("expected %v, got %v", file.ErrInvalidOffset, err)
+			}
+			check(t, podFile, data)
+
+			_, err = pod1.TruncateFile(context.Background(), podName1, podFile, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, podFile, nil)
+		})
+	}
+
+	t.Run("survives-sync", func(t *testing.T) {
+		data := upload(t, "file2", 500, "")
+		extra := randomBytes(t, 10)
+		_, err := pod1.AppendFile(context.Background(), podName1, "/dir1/file2", extra)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pod1.SyncPod(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, "/dir1/file2", append(data, extra...))
+
+		// nothing the file holds now is taken for garbage
+		_, err = pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, addr := range blocks(t, "/dir1/file2") {
+			if !mockClient.IsBlobPinned(addr) {
+				t.Fatalf("block of the modified file unpinned")
+			}
+		}
+	})
+
+	t.Run("file-not-present", func(t *testing.T) {
+		_, err := pod1.AppendFile(context.Background(), podName1, "/dir1/nofile", []byte("data"))
+		if err == nil {
+			t.Fatalf("appended to a file which is not present")
+		}
+	})
+}
+
+func randomBytes(t *testing.T, size int) []byte {
+	t.Helper()
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
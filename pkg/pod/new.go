@@ -19,6 +19,7 @@ package pod
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strconv"
@@ -36,78 +37,98 @@ const (
 	podFile = "Pods"
 )
 
-func (p *Pod) CreatePod(podName, passPhrase string) (*Info, error) {
+func (p *Pod) CreatePod(ctx context.Context, podName, passPhrase string) (*Info, error) {
 	podName, err := CleanPodName(podName)
 	if err != nil {
 		return nil, err
 	}
 
-	// check if pods is present and get free index
-	pods, err := p.loadUserPods()
-	if err != nil {
-		return nil, err
-	}
-	if p.checkIfPodPresent(pods, podName) {
-		return nil, ErrPodAlreadyExists
-	}
-	freeId, err := p.getFreeId(pods)
+	// the pod takes the first free index in the list of pods, which may be
+	// taken meanwhile on another device, so it is made afresh for the index
+	// of every attempt
+	var podInfo *Info
+	podId := -1
+	err = p.modifyUserPods(ctx, func(pods map[int]string) error {
+		if p.checkIfPodPresent(pods, podName) {
+			return ErrPodAlreadyExists
+		}
+		freeId, err := p.getFreeId(pods)
+		if err != nil {
+			return err
+		}
+		if freeId != podId {
+			podInfo, err = p.newPodInfo(ctx, podName, passPhrase, freeId)
+			if err != nil {
+				return err
+			}
+			podId = freeId
+		}
+		pods[freeId] = podName
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	// store the pod info in the podMap
+	p.addPodToPodMap(podName, podInfo)
+	podInfo.getDirectory().AddToDirectoryMap(podName, podInfo.currentPodInode)
+	return podInfo, nil
+}
+
+// newPodInfo creates the account of the pod at index podId in the list of
+// pods, and its inode.
+func (p *Pod) newPodInfo(ctx context.Context, podName, passPhrase string, podId int) (*Info, error) {
 	// create a child account for the user and other data structures for the pod
-	err = p.acc.CreatePodAccount(freeId, passPhrase, true)
+	err := p.acc.CreatePodAccount(podId, passPhrase, true)
 	if err != nil {
 		return nil, err
 	}
-	accountInfo, err := p.acc.GetPodAccountInfo(freeId)
+	accountInfo, err := p.acc.GetPodAccountInfo(podId)
 	if err != nil {
 		return nil, err
 	}
-	fd := feed.New(accountInfo, p.client, p.logger)
-	file := f.NewFile(podName, p.client, fd, accountInfo, p.logger)
-	dir := d.NewDirectory(podName, p.client, fd, accountInfo, file, p.logger)
+	pins := p.pins.tracker(p.client, accountInfo.GetAddress())
+	fd := feed.NewWithOptions(accountInfo, pins, p.fd.Options(), p.logger)
+	file := f.NewFile(podName, pins, fd, accountInfo, p.logger)
+	dir := d.NewDirectory(podName, pins, fd, accountInfo, file, p.logger)
 
 	// create the pod inode
-	dirInode, _, err := dir.CreatePodINode(podName)
+	dirInode, _, err := dir.CreatePodINode(ctx, podName)
 	if err != nil {
 		return nil, err
 	}
 
-	// store the pod file
-	pods[freeId] = podName
-	err = p.storeUserPods(pods)
-	if err != nil {
-		return nil, err
-	}
-
-	// create the pod info and store it in the podMap
-	podInfo := &Info{
+	return &Info{
 		podName:         podName,
 		dir:             dir,
 		file:            file,
 		accountInfo:     accountInfo,
 		feed:            fd,
+		pins:            pins,
 		currentPodInode: dirInode,
 		curPodMu:        sync.RWMutex{},
 		currentDirInode: dirInode,
 		curDirMu:        sync.RWMutex{},
-	}
-	pods[freeId] = podName
-	p.addPodToPodMap(podName, podInfo)
-	dir.AddToDirectoryMap(podName, dirInode)
+	}, nil
+}
 
-	return podInfo, nil
+func (p *Pod) loadUserPods(ctx context.Context) (map[int]string, error) {
+	_, pods, err := p.readUserPods(ctx)
+	return pods, err
 }
 
-func (p *Pod) loadUserPods() (map[int]string, error) {
+// readUserPods returns the list of pods along with the address of the
+// update of the feed it was read from, which is nil if there is none.
+func (p *Pod) readUserPods(ctx context.Context) ([]byte, map[int]string, error) {
 	// The user pod file topic should be in the name of the user account
 	topic := utils.HashString(podFile)
-	_, data, err := p.fd.GetFeedData(topic, p.acc.GetAddress(account.UserAccountIndex))
+	addr, data, err := p.fd.GetFeedData(ctx, topic, p.acc.GetAddress(account.UserAccountIndex))
 	if err != nil {
 		if err.Error() != "no feed updates found" {
-			return nil, err
+			return nil, nil, err
 		}
+		addr = nil
 	}
 
 	buf := bytes.NewBuffer(data)
@@ -119,20 +140,47 @@ func (p *Pod) loadUserPods() (map[int]string, error) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("loading pods: %w", err)
+			return nil, nil, fmt.Errorf("loading pods: %w", err)
 		}
 		line = strings.Trim(line, "\n")
 		lines := strings.Split(line, ",")
 		index, err := strconv.ParseInt(lines[1], 10, 64)
 		if err != nil {
-			return pods, err
+			return nil, pods, err
 		}
 		pods[int(index)] = lines[0]
 	}
-	return pods, nil
+	return addr, pods, nil
+}
+
+// modifyUserPods applies change to the latest list of pods and stores it,
+// unless the list was updated by someone else in the meantime, like the same
+// user on another device. Then the list is read again and change applied to
+// it afresh. An error returned by change aborts the update.
+func (p *Pod) modifyUserPods(ctx context.Context, change func(pods map[int]string) error) error {
+	for attempt := 1; ; attempt++ {
+		addr, pods, err := p.readUserPods(ctx)
+		if err != nil {
+			return err
+		}
+		err = change(pods)
+		if err != nil {
+			return err
+		}
+		err = p.storeUserPods(ctx, pods, addr)
+		if err == nil {
+			return nil
+		}
+		if !feed.IsConflict(err) || attempt == updateAttempts {
+			return err
+		}
+		p.logger.Debugf("list of pods was updated meanwhile, updating it again: %v", err)
+	}
 }
 
-func (p *Pod) storeUserPods(pods map[int]string) error {
+// storeUserPods stores the list of pods if the latest update of its feed is
+// still the one at expected.
+func (p *Pod) storeUserPods(ctx context.Context, pods map[int]string, expected []byte) error {
 	buf := bytes.NewBuffer(nil)
 	podLen := len(pods)
 	for index, pod := range pods {
@@ -145,7 +193,7 @@ func (p *Pod) storeUserPods(pods map[int]string) error {
 	}
 
 	topic := utils.HashString(podFile)
-	_, err := p.fd.UpdateFeed(topic, p.acc.GetAddress(account.UserAccountIndex), buf.Bytes())
+	_, err := p.fd.UpdateFeedIf(ctx, topic, p.acc.GetAddress(account.UserAccountIndex), buf.Bytes(), expected)
 	if err != nil {
 		return err
 	}
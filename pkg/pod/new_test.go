@@ -17,12 +17,16 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 	"testing"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/fault"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/metrics"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
 	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 )
@@ -41,7 +45,7 @@ func TestPod_New(t *testing.T) {
 	podName1 := "test1"
 	podName2 := "test2"
 	t.Run("create-first-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
@@ -54,7 +58,7 @@ func TestPod_New(t *testing.T) {
 			t.Fatalf("invalid pod name: expected %s got %s", podName1, info.GetCurrentPodNameOnly())
 		}
 
-		pods, err := pod1.loadUserPods()
+		pods, err := pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
@@ -84,7 +88,7 @@ func TestPod_New(t *testing.T) {
 	})
 
 	t.Run("create-second-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName2, "password")
+		info, err := pod1.CreatePod(context.Background(), podName2, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName2)
 		}
@@ -93,7 +97,7 @@ func TestPod_New(t *testing.T) {
 			t.Fatalf("invalid pod name: expected %s got %s", podName2, info.GetCurrentPodNameOnly())
 		}
 
-		pods, err := pod1.loadUserPods()
+		pods, err := pod1.loadUserPods(context.Background())
 		if err != nil {
 			t.Fatalf("error getting pods")
 		}
@@ -121,3 +125,41 @@ func TestPod_New(t *testing.T) {
 		}
 	})
 }
+
+// cachingClient is a block store which owns a cache, like the bee client.
+type cachingClient struct {
+	*mock.MockBeeClient
+	cache *cache.Cache
+}
+
+func (c *cachingClient) Cache() *cache.Cache {
+	return c.cache
+}
+
+func TestPod_SharedCache(t *testing.T) {
+	shared := cache.New(cache.DefaultSize)
+	store := &cachingClient{MockBeeClient: mock.NewMockBeeClient(), cache: shared}
+	// the wrappers dfs puts around the block store keep the cache visible
+	client := metrics.New(fault.New(store, 1), 0, logging.New(ioutil.Discard, 0))
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the feed of the user caches on its own, so only the pod feed can
+	// use the shared cache
+	fd := feed.New(acc.GetUserAccountInfo(), store.MockBeeClient, logger)
+	pod1 := NewPod(client, fd, acc, logger)
+
+	info, err := pod1.CreatePod(context.Background(), "test1", "password")
+	if err != nil {
+		t.Fatalf("error creating pod: %v", err)
+	}
+	if info.pins.Cache() != shared {
+		t.Fatalf("pod block store does not share the cache")
+	}
+	if shared.Stats()[feed.CacheSegment].Entries == 0 {
+		t.Fatalf("pod feed updates not cached in the shared cache")
+	}
+}
@@ -17,18 +17,20 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 
 	d "github.com/jmozah/intOS-dfs/pkg/dir"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	f "github.com/jmozah/intOS-dfs/pkg/file"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) OpenPod(podName, passPhrase string) (*Info, error) {
+func (p *Pod) OpenPod(ctx context.Context, podName, passPhrase string) (*Info, error) {
 	// check if pods is present and get the index of the pod
-	pods, err := p.loadUserPods()
+	pods, err := p.loadUserPods(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -51,11 +53,13 @@ func (p *Pod) OpenPod(podName, passPhrase string) (*Info, error) {
 	if err != nil {
 		return nil, err
 	}
-	file := f.NewFile(podName, p.client, p.fd, accountInfo, p.logger)
-	dir := d.NewDirectory(podName, p.client, p.fd, accountInfo, file, p.logger)
+	pins := p.pins.tracker(p.client, accountInfo.GetAddress())
+	fd := feed.NewWithOptions(accountInfo, pins, p.fd.Options(), p.logger)
+	file := f.NewFile(podName, pins, fd, accountInfo, p.logger)
+	dir := d.NewDirectory(podName, pins, fd, accountInfo, file, p.logger)
 
 	// get the pod's inode
-	_, dirInode, err := dir.GetDirNode(utils.PathSeperator+podName, p.fd, accountInfo)
+	_, dirInode, err := dir.GetDirNode(ctx, utils.PathSeperator+podName, fd, accountInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +68,10 @@ func (p *Pod) OpenPod(podName, passPhrase string) (*Info, error) {
 	podInfo := &Info{
 		podName:         podName,
 		accountInfo:     accountInfo,
-		feed:            p.fd,
+		feed:            fd,
 		dir:             dir,
 		file:            file,
+		pins:            pins,
 		currentPodInode: dirInode,
 		curPodMu:        sync.RWMutex{},
 		currentDirInode: dirInode,
@@ -77,7 +82,7 @@ func (p *Pod) OpenPod(podName, passPhrase string) (*Info, error) {
 	dir.AddToDirectoryMap(podName, dirInode)
 
 	// sync the pod's files and directories
-	err = p.SyncPod(podName)
+	err = p.SyncPod(ctx, podName)
 	if err != nil {
 		return nil, err
 	}
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"crypto/rand"
 	"io/ioutil"
 	"os"
@@ -44,7 +45,7 @@ func TestPod_LoginPod(t *testing.T) {
 	podName1 := "test1"
 	firstDir := "dir1"
 	t.Run("simple-login-to-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
@@ -53,7 +54,7 @@ func TestPod_LoginPod(t *testing.T) {
 			t.Fatalf("could not logout")
 		}
 
-		infoLogin, err := pod1.OpenPod(podName1, "password")
+		infoLogin, err := pod1.OpenPod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("login failed")
 		}
@@ -64,20 +65,20 @@ func TestPod_LoginPod(t *testing.T) {
 			t.Fatalf("invalid podname path and name")
 		}
 
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("login-with-sync-contents", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
 		//Make a dir
-		err = pod1.MakeDir(podName1, firstDir)
+		err = pod1.MakeDir(context.Background(), podName1, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
@@ -98,7 +99,7 @@ func TestPod_LoginPod(t *testing.T) {
 			t.Fatal(err)
 		}
 		defer fd.Close()
-		_, err = pod1.UploadFile(podName1, fileName, 540, fd, podDir, "100", "false")
+		_, err = pod1.UploadFile(context.Background(), podName1, fileName, 540, fd, podDir, "100", "false")
 		if err != nil {
 			t.Fatalf("upload failed: %s", err.Error())
 		}
@@ -112,7 +113,7 @@ func TestPod_LoginPod(t *testing.T) {
 		}
 
 		// Now login and check if the dir and file exists
-		infoLogin, err := pod1.OpenPod(podName1, "password")
+		infoLogin, err := pod1.OpenPod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("login failed")
 		}
@@ -137,7 +138,7 @@ func TestPod_LoginPod(t *testing.T) {
 			t.Fatalf("file not synced")
 		}
 
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
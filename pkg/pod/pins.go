@@ -0,0 +1,294 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	"github.com/jmozah/intOS-dfs/pkg/blockstore"
+	"github.com/jmozah/intOS-dfs/pkg/cache"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+const (
+	pinsDirPerm  = 0700
+	pinsFilePerm = 0600
+
+	pinRecord     = "pin"
+	unpinRecord   = "unpin"
+	pendingRecord = "pending"
+	blobKind      = "blob"
+	chunkKind     = "chunk"
+)
+
+// Pins keeps the pins tracked for the pods in a journal per pod, named by the
+// address of the pod, under the directory of the pins. The journal of a pod is
+// replayed when the pod is created or opened, so that what it left pinned
+// before a restart is still found by its garbage collection.
+//
+// Pins are safe for concurrent use and can be shared by many users. A nil
+// *Pins keeps nothing.
+type Pins struct {
+	dir    string
+	logger logging.Logger
+}
+
+// NewPins opens the pins kept in dir, creating it if needed.
+func NewPins(dir string, logger logging.Logger) (*Pins, error) {
+	err := os.MkdirAll(dir, pinsDirPerm)
+	if err != nil {
+		return nil, err
+	}
+	return &Pins{
+		dir:    dir,
+		logger: logger,
+	}, nil
+}
+
+// tracker returns the pin tracker of the pod with the given address, loaded
+// from its journal.
+func (s *Pins) tracker(client blockstore.Client, address utils.Address) *pinTracker {
+	t := newPinTracker(client)
+	if s == nil {
+		return t
+	}
+	t.path = filepath.Join(s.dir, address.Hex())
+	t.logger = s.logger
+	err := t.load()
+	if err != nil {
+		s.logger.Warningf("could not load pins %s: %v", t.path, err)
+	}
+	return t
+}
+
+// garbage is a reference which is pinned but not reachable from the pod.
+type garbage struct {
+	ref   []byte
+	chunk bool
+}
+
+// pinTracker is the blockstore.Client of the files and directories of a pod.
+// It remembers the blobs and chunks pinned through it, so that the ones which
+// never got linked in to the pod's tree, like the blocks of an upload which
+// failed half way, can be found and unpinned. Unpins which fail are kept to
+// be retried later. Every change is appended to the journal of the pod, if it
+// has one.
+type pinTracker struct {
+	blockstore.Client
+	pinned  map[string]garbage
+	pending map[string]garbage
+	path    string // of the journal, empty if it is not kept
+	logger  logging.Logger
+	mu      sync.Mutex
+}
+
+func newPinTracker(client blockstore.Client) *pinTracker {
+	return &pinTracker{
+		Client:  client,
+		pinned:  make(map[string]garbage),
+		pending: make(map[string]garbage),
+	}
+}
+
+// Cache returns the cache of the wrapped client, if it has one, so that the
+// feed of the pod caches in the budget shared by the other pods.
+func (t *pinTracker) Cache() *cache.Cache {
+	if provider, ok := t.Client.(cache.Provider); ok {
+		return provider.Cache()
+	}
+	return nil
+}
+
+func (t *pinTracker) UploadChunk(ctx context.Context, ch swarm.Chunk, pin bool) ([]byte, error) {
+	address, err := t.Client.UploadChunk(ctx, ch, pin)
+	if err == nil && pin {
+		t.add(garbage{ref: address, chunk: true})
+	}
+	return address, err
+}
+
+func (t *pinTracker) UploadBlob(ctx context.Context, data []byte, pin bool, encrypt bool) ([]byte, error) {
+	address, err := t.Client.UploadBlob(ctx, data, pin, encrypt)
+	if err == nil && pin {
+		t.add(garbage{ref: address})
+	}
+	return address, err
+}
+
+func (t *pinTracker) UploadBlobStream(ctx context.Context, r io.Reader, pin bool, encrypt bool) ([]byte, error) {
+	address, err := t.Client.UploadBlobStream(ctx, r, pin, encrypt)
+	if err == nil && pin {
+		t.add(garbage{ref: address})
+	}
+	return address, err
+}
+
+func (t *pinTracker) add(g garbage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := hex.EncodeToString(g.ref)
+	if _, ok := t.pinned[key]; ok {
+		return
+	}
+	t.pinned[key] = g
+	t.record(pinRecord, g)
+}
+
+// forget drops a reference once it is unpinned.
+func (t *pinTracker) forget(ref []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := hex.EncodeToString(ref)
+	_, pinned := t.pinned[key]
+	_, pending := t.pending[key]
+	if !pinned && !pending {
+		return
+	}
+	delete(t.pinned, key)
+	delete(t.pending, key)
+	t.record(unpinRecord, garbage{ref: ref})
+}
+
+// addPending keeps a reference whose unpin failed, to be retried.
+func (t *pinTracker) addPending(g garbage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := hex.EncodeToString(g.ref)
+	if _, ok := t.pending[key]; ok {
+		return
+	}
+	t.pending[key] = g
+	t.record(pendingRecord, g)
+}
+
+// candidates returns the tracked pins and the pending unpins, which are all
+// kept until they are unpinned.
+func (t *pinTracker) candidates() []garbage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	refs := make([]garbage, 0, len(t.pinned)+len(t.pending))
+	for _, g := range t.pinned {
+		refs = append(refs, g)
+	}
+	for key, g := range t.pending {
+		if _, ok := t.pinned[key]; !ok {
+			refs = append(refs, g)
+		}
+	}
+	return refs
+}
+
+// record appends a change to the journal. Failing to keep it is not an
+// error, the reference is just not found by a collection after a restart.
+func (t *pinTracker) record(op string, g garbage) {
+	if t.path == "" {
+		return
+	}
+	f, err := os.OpenFile(t.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, pinsFilePerm)
+	if err == nil {
+		_, err = f.Write(formatPinRecord(op, g))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		t.logger.Warningf("could not keep pins %s: %v", t.path, err)
+	}
+}
+
+// load replays the journal and rewrites it with only what is left, so that it
+// does not grow with every pin ever taken.
+func (t *pinTracker) load() error {
+	data, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		op, g, err := parsePinRecord(scanner.Text())
+		if err != nil {
+			// a record cut short by a crash is the last one written
+			t.logger.Warningf("dropping corrupt pins record in %s: %v", t.path, err)
+			continue
+		}
+		key := hex.EncodeToString(g.ref)
+		switch op {
+		case pinRecord:
+			t.pinned[key] = g
+		case pendingRecord:
+			t.pending[key] = g
+		case unpinRecord:
+			delete(t.pinned, key)
+			delete(t.pending, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, g := range t.pinned {
+		buf.Write(formatPinRecord(pinRecord, g))
+	}
+	for _, g := range t.pending {
+		buf.Write(formatPinRecord(pendingRecord, g))
+	}
+	return utils.WriteFileAtomic(t.path, buf.Bytes(), pinsFilePerm)
+}
+
+func formatPinRecord(op string, g garbage) []byte {
+	kind := blobKind
+	if g.chunk {
+		kind = chunkKind
+	}
+	return []byte(fmt.Sprintf("%s %s %s\n", op, hex.EncodeToString(g.ref), kind))
+}
+
+func parsePinRecord(line string) (string, garbage, error) {
+	var op, ref, kind string
+	_, err := fmt.Sscanf(line, "%s %s %s", &op, &ref, &kind)
+	if err != nil {
+		return "", garbage{}, err
+	}
+	if op != pinRecord && op != unpinRecord && op != pendingRecord {
+		return "", garbage{}, fmt.Errorf("unknown record %q", op)
+	}
+	if kind != blobKind && kind != chunkKind {
+		return "", garbage{}, fmt.Errorf("unknown kind %q", kind)
+	}
+	g := garbage{chunk: kind == chunkKind}
+	g.ref, err = hex.DecodeString(ref)
+	if err != nil || len(g.ref) == 0 {
+		return "", garbage{}, fmt.Errorf("invalid reference %q", ref)
+	}
+	return op, g, nil
+}
@@ -31,6 +31,10 @@ import (
 
 const (
 	maxPodId = 65535
+
+	// updateAttempts is how many times the list of pods is read and updated
+	// before giving up on other writers updating it at the same time
+	updateAttempts = 5
 )
 
 type Pod struct {
@@ -39,16 +43,30 @@ type Pod struct {
 	client blockstore.Client
 	podMap map[string]*Info //  podName -> dir
 	podMu  *sync.RWMutex
+	pins   *Pins
 	logger logging.Logger
 }
 
+// Options are the optional settings of the pods of a user.
+type Options struct {
+	// Pins keeps what is pinned for the pods across restarts. Nothing is
+	// kept if nil.
+	Pins *Pins
+}
+
 func NewPod(client blockstore.Client, feed *feed.API, account *account.Account, logger logging.Logger) *Pod {
+	return NewPodWithOptions(client, feed, account, Options{}, logger)
+}
+
+// NewPodWithOptions creates the pods of a user with the given options.
+func NewPodWithOptions(client blockstore.Client, feed *feed.API, account *account.Account, opts Options, logger logging.Logger) *Pod {
 	return &Pod{
 		fd:     feed,
 		acc:    account,
 		client: client,
 		podMap: make(map[string]*Info),
 		podMu:  &sync.RWMutex{},
+		pins:   opts.Pins,
 		logger: logger,
 	}
 }
@@ -17,19 +17,16 @@ limitations under the License.
 package pod
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
-	"net/http"
 	gopath "path"
-	"time"
 
-	"github.com/ethersphere/bee/pkg/swarm"
-
-	m "github.com/jmozah/intOS-dfs/pkg/meta"
+	d "github.com/jmozah/intOS-dfs/pkg/dir"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) RemoveFile(podName string, podFile string) error {
+func (p *Pod) RemoveFile(ctx context.Context, podName string, podFile string) error {
 	if !p.isPodOpened(podName) {
 		return fmt.Errorf("login to pod to do this operation")
 	}
@@ -51,48 +48,43 @@ func (p *Pod) RemoveFile(podName string, podFile string) error {
 		return fmt.Errorf("file not present in pod")
 	}
 
-	_, dirInode, err := dir.GetDirNode(gopath.Dir(path), podInfo.getFeed(), podInfo.getAccountInfo())
+	// collect what the file holds before it is unlinked
+	refs, err := p.fileGarbage(ctx, podInfo, path)
 	if err != nil {
-		return err
+		p.logger.Warningf("could not collect references of %s, they stay pinned: %v", path, err)
 	}
 
-	// remove the file
-	var newHashes [][]byte
-	for _, hash := range dirInode.Hashes {
-		_, _, err := podInfo.getFeed().GetFeedData(hash, podInfo.getAccountInfo().GetAddress())
-		if err != nil {
-			data, respCode, err := p.GetClient().DownloadBlob(hash)
-			if err != nil || respCode != http.StatusOK {
-				p.logger.Warningf("could not load address ", swarm.NewAddress(hash).String())
-				continue
-			}
-			var meta *m.FileMetaData
-			err = json.Unmarshal(data, &meta)
-			if err != nil {
-				p.logger.Warningf("could not unmarshall data in address ", swarm.NewAddress(hash).String())
+	// remove the file, by its meta reference as a received file can be
+	// linked under a name which is not the one in its meta
+	metaReference := podInfo.getFile().GetFromFileMap(path).MetaReference
+	_, topic, err := dir.ModifyDirectory(ctx, gopath.Dir(path), func(dirInode *d.DirInode) error {
+		var newHashes [][]byte
+		removed := false
+		for _, hash := range dirInode.Hashes {
+			if !removed && bytes.Equal(hash, metaReference) {
+				removed = true
 				continue
 			}
-			if meta.Name != gopath.Base(path) {
-				newHashes = append(newHashes, hash)
-			} else {
-				podInfo.getFile().RemoveFromFileMap(path)
-			}
+			newHashes = append(newHashes, hash)
 		}
-	}
-	dirInode.Hashes = newHashes
-
-	dirInode.Meta.ModificationTime = time.Now().Unix()
-	topic, err := dir.UpdateDirectory(dirInode)
+		if !removed {
+			return fmt.Errorf("file not present in directory")
+		}
+		dirInode.Hashes = newHashes
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	podInfo.getFile().RemoveFromFileMap(path)
 
 	if path != podInfo.GetCurrentPodPathAndName() {
-		err = p.UpdateTillThePod(podName, podInfo.getDirectory(), topic, gopath.Dir(path), true)
+		err = p.UpdateTillThePod(ctx, podName, podInfo.getDirectory(), topic, gopath.Dir(path), true)
 		if err != nil {
 			return err
 		}
 	}
+	p.unpin(ctx, podInfo, refs)
 	return nil
 
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -24,7 +25,7 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) RemoveDir(podName string, dirName string) error {
+func (p *Pod) RemoveDir(ctx context.Context, podName string, dirName string) error {
 	if !p.isPodOpened(podName) {
 		return ErrPodNotOpened
 	}
@@ -50,12 +51,24 @@ func (p *Pod) RemoveDir(podName string, dirName string) error {
 	if info.IsCurrentDirRoot() {
 		topic = info.GetCurrentPodPathAndName() + utils.PathSeperator + dirName
 	}
+
+	// collect what the directory holds before it is unlinked
+	refs, files, err := p.dirGarbage(ctx, info, topic)
+	if err != nil {
+		p.logger.Warningf("could not collect references under %s, they stay pinned: %v", topic, err)
+		refs, files = nil, nil
+	}
+
 	topicBytes := utils.HashString(topic)
-	err = p.UpdateTillThePod(podName, directory, topicBytes, dirInode.GetDirInodePathOnly(), false)
+	err = p.UpdateTillThePod(ctx, podName, directory, topicBytes, dirInode.GetDirInodePathOnly(), false)
 	if err != nil {
 		return err
 	}
 	directory.GetPrefixPodFromPathMap(topic)
+	for _, filePath := range files {
+		info.getFile().RemoveFromFileMap(filePath)
+	}
+	p.unpin(ctx, info, refs)
 	return nil
 }
 
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"io/ioutil"
 	"testing"
 
@@ -48,12 +49,12 @@ func TestPod_RemoveDir(t *testing.T) {
 	thirdAndFourthDir := "dir3/dir4"
 	fifthDir := "/dir5"
 	t.Run("rmdir-on-root-of-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName1, "password")
+		info, err := pod1.CreatePod(context.Background(), podName1, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName1)
 		}
 
-		err = pod1.MakeDir(podName1, firstDir)
+		err = pod1.MakeDir(context.Background(), podName1, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
@@ -63,7 +64,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName1, firstDir)
+		err = pod1.RemoveDir(context.Background(), podName1, firstDir)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -74,27 +75,27 @@ func TestPod_RemoveDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName1)
+		err = pod1.DeletePod(context.Background(), podName1)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("rmdir-second-dir-from-first-dir", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName2, "password")
+		info, err := pod1.CreatePod(context.Background(), podName2, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName2)
 		}
 
-		err = pod1.MakeDir(podName2, firstDir)
+		err = pod1.MakeDir(context.Background(), podName2, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", firstDir)
 		}
-		_, err = pod1.ChangeDir(podName2, firstDir)
+		_, err = pod1.ChangeDir(context.Background(), podName2, firstDir)
 		if err != nil {
 			t.Fatalf("error changing directory")
 		}
-		err = pod1.MakeDir(podName2, secondDir)
+		err = pod1.MakeDir(context.Background(), podName2, secondDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", secondDir)
 		}
@@ -104,7 +105,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName2, secondDir)
+		err = pod1.RemoveDir(context.Background(), podName2, secondDir)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -115,23 +116,23 @@ func TestPod_RemoveDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName2)
+		err = pod1.DeletePod(context.Background(), podName2)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("rmdir-second-dir-from-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName3, "password")
+		info, err := pod1.CreatePod(context.Background(), podName3, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName3)
 		}
 
-		err = pod1.MakeDir(podName3, firstDir)
+		err = pod1.MakeDir(context.Background(), podName3, firstDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", err)
 		}
-		err = pod1.MakeDir(podName3, firstDir+utils.PathSeperator+secondDir)
+		err = pod1.MakeDir(context.Background(), podName3, firstDir+utils.PathSeperator+secondDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", err)
 		}
@@ -141,7 +142,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName3, firstDir+utils.PathSeperator+secondDir)
+		err = pod1.RemoveDir(context.Background(), podName3, firstDir+utils.PathSeperator+secondDir)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -157,19 +158,19 @@ func TestPod_RemoveDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName3)
+		err = pod1.DeletePod(context.Background(), podName3)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("rmdir-multiple-dirs-from-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName4, "password")
+		info, err := pod1.CreatePod(context.Background(), podName4, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName4)
 		}
 
-		err = pod1.MakeDir(podName4, thirdAndFourthDir)
+		err = pod1.MakeDir(context.Background(), podName4, thirdAndFourthDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", thirdAndFourthDir)
 		}
@@ -187,7 +188,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName4, "dir3")
+		err = pod1.RemoveDir(context.Background(), podName4, "dir3")
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -203,19 +204,19 @@ func TestPod_RemoveDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName4)
+		err = pod1.DeletePod(context.Background(), podName4)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
 	})
 
 	t.Run("rmdir-with-slash-on-pod", func(t *testing.T) {
-		info, err := pod1.CreatePod(podName5, "password")
+		info, err := pod1.CreatePod(context.Background(), podName5, "password")
 		if err != nil {
 			t.Fatalf("error creating pod %s", podName5)
 		}
 
-		err = pod1.MakeDir(podName5, fifthDir)
+		err = pod1.MakeDir(context.Background(), podName5, fifthDir)
 		if err != nil {
 			t.Fatalf("error creating directory %s", fifthDir)
 		}
@@ -225,7 +226,7 @@ func TestPod_RemoveDir(t *testing.T) {
 			t.Fatalf("directory not created")
 		}
 
-		err = pod1.RemoveDir(podName5, fifthDir)
+		err = pod1.RemoveDir(context.Background(), podName5, fifthDir)
 		if err != nil {
 			t.Fatalf("error removing directory")
 		}
@@ -236,7 +237,7 @@ func TestPod_RemoveDir(t *testing.T) {
 		}
 
 		// cleanup pod
-		err = pod1.DeletePod(podName5)
+		err = pod1.DeletePod(context.Background(), podName5)
 		if err != nil {
 			t.Fatalf("could not delete pod")
 		}
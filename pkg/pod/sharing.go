@@ -17,10 +17,11 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
-	"time"
 
+	d "github.com/jmozah/intOS-dfs/pkg/dir"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
@@ -42,7 +43,7 @@ func (p *Pod) GetMetaReferenceOfFile(podName, filePath string) ([]byte, string,
 	return podInfo.getFile().GetFileReference(fpath)
 }
 
-func (p *Pod) ReceiveFileAndStore(podName, podDir, fileName, metaHexRef string) error {
+func (p *Pod) ReceiveFileAndStore(ctx context.Context, podName, podDir, fileName, metaHexRef string) error {
 	if !p.isPodOpened(podName) {
 		return fmt.Errorf("login to pod to do this operation")
 	}
@@ -55,7 +56,7 @@ func (p *Pod) ReceiveFileAndStore(podName, podDir, fileName, metaHexRef string)
 	path := p.getFilePath(podDir, podInfo)
 	dir := podInfo.getDirectory()
 
-	_, dirInode, err := dir.GetDirNode(path, podInfo.getFeed(), podInfo.getAccountInfo())
+	_, _, err = dir.GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
 	if err != nil {
 		return err
 	}
@@ -71,21 +72,34 @@ func (p *Pod) ReceiveFileAndStore(podName, podDir, fileName, metaHexRef string)
 	if err != nil {
 		return err
 	}
-	dirInode.Hashes = append(dirInode.Hashes, metaReference.Bytes())
-	dirInode.Meta.ModificationTime = time.Now().Unix()
-	topic, err := dir.UpdateDirectory(dirInode)
+
+	// the received file gets its own pins, so it outlives the sender's copy
+	refs, err := podInfo.getFile().GetReferences(ctx, metaReference.Bytes())
+	if err != nil {
+		return err
+	}
+	err = p.pinAll(ctx, refs)
+	if err != nil {
+		return err
+	}
+
+	_, topic, err := dir.ModifyDirectory(ctx, path, func(dirInode *d.DirInode) error {
+		dirInode.Hashes = append(dirInode.Hashes, metaReference.Bytes())
+		return nil
+	})
 	if err != nil {
+		p.unpinAll(ctx, refs)
 		return err
 	}
 
 	// if the directory path is not root.. then update all the parents too
 	if path != podInfo.GetCurrentPodPathAndName() {
-		err = p.UpdateTillThePod(podName, podInfo.getDirectory(), topic, path, true)
+		err = p.UpdateTillThePod(ctx, podName, podInfo.getDirectory(), topic, path, true)
 		if err != nil {
 			return err
 		}
 	}
 
 	// Add to file path map
-	return podInfo.getFile().AddFileToPath(fpath, metaHexRef)
+	return podInfo.getFile().AddFileToPath(ctx, fpath, metaHexRef)
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -52,7 +53,7 @@ func (p *Pod) PodStat(podName string) (*PodStat, error) {
 	}, nil
 }
 
-func (p *Pod) DirectoryStat(podName, podFileOrDir string, printNames bool) (*dir.DirStats, error) {
+func (p *Pod) DirectoryStat(ctx context.Context, podName, podFileOrDir string, printNames bool) (*dir.DirStats, error) {
 	if !p.isPodOpened(podName) {
 		return nil, ErrPodNotOpened
 	}
@@ -68,7 +69,7 @@ func (p *Pod) DirectoryStat(podName, podFileOrDir string, printNames bool) (*dir
 	dirInode := info.getDirectory().GetDirFromDirectoryMap(path)
 	if dirInode != nil {
 		meta := dirInode.Meta
-		addr, dirInode, err := info.getDirectory().GetDirNode(meta.Path+utils.PathSeperator+meta.Name, info.getFeed(), info.getAccountInfo())
+		addr, dirInode, err := info.getDirectory().GetDirNode(ctx, meta.Path+utils.PathSeperator+meta.Name, info.getFeed(), info.getAccountInfo())
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +79,7 @@ func (p *Pod) DirectoryStat(podName, podFileOrDir string, printNames bool) (*dir
 	return nil, fmt.Errorf("directory not found")
 }
 
-func (p *Pod) FileStat(podName, podFileOrDir string) (*file.FileStats, error) {
+func (p *Pod) FileStat(ctx context.Context, podName, podFileOrDir string) (*file.FileStats, error) {
 	if !p.isPodOpened(podName) {
 		return nil, fmt.Errorf("login to pod to do this operation")
 	}
@@ -94,5 +95,5 @@ func (p *Pod) FileStat(podName, podFileOrDir string) (*file.FileStats, error) {
 	if !info.file.IsFileAlreadyPResent(path) {
 		return nil, fmt.Errorf("file not present in pod")
 	}
-	return info.file.FileStat(podName, path, acc.String())
+	return info.file.FileStat(ctx, podName, path, acc.String())
 }
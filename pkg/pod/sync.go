@@ -17,6 +17,7 @@ limitations under the License.
 package pod
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -24,12 +25,13 @@ import (
 
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	d "github.com/jmozah/intOS-dfs/pkg/dir"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/logging"
 	m "github.com/jmozah/intOS-dfs/pkg/meta"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) SyncPod(podName string) error {
+func (p *Pod) SyncPod(ctx context.Context, podName string) error {
 	podName, err := CleanPodName(podName)
 	if err != nil {
 		return err
@@ -44,26 +46,44 @@ func (p *Pod) SyncPod(podName string) error {
 		return err
 	}
 
-	err = podInfo.SyncPod(podName, p.client, p.logger)
+	err = podInfo.SyncPod(ctx, podName, p.client, p.logger)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (pi *Info) SyncPod(podName string, client blockstore.Client, logger logging.Logger) error {
+func (pi *Info) SyncPod(ctx context.Context, podName string, client blockstore.Client, logger logging.Logger) error {
 	fd := pi.getFeed()
 	accountInfo := pi.getAccountInfo()
 
 	logger.Infof("Syncing pod: %v", podName)
+
+	// the entries which are directories are all read at once first. the
+	// reads of the whole tree share one bound of how many are made at the
+	// same time, so a worker is only held for the download of a file and
+	// not while the directory under an entry is loaded
+	workers := feed.NewWorkers()
+	hashes := pi.currentPodInode.Hashes
+	results := fd.GetFeedDataBatch(ctx, hashes, accountInfo.GetAddress(), workers)
+	errC := make(chan error, 1) // the first error of the workers
 	var wg sync.WaitGroup
-	for _, ref := range pi.currentPodInode.Hashes {
-		wg.Add(1)
-		go func(reference []byte) {
-			defer wg.Done()
-			_, data, err := fd.GetFeedData(reference, accountInfo.GetAddress())
-			if err != nil {
-				data, respCode, err := client.DownloadBlob(reference)
+	for i, ref := range hashes {
+		if results[i].Err != nil {
+			// only an entry which is not of a feed is a file, a feed which
+			// could not be read is an error
+			if !feed.IsNotFound(results[i].Err) {
+				wg.Wait()
+				return results[i].Err
+			}
+			wg.Add(1)
+			workers.Acquire()
+			go func(reference []byte) {
+				defer func() {
+					workers.Release()
+					wg.Done()
+				}()
+				data, respCode, err := client.DownloadBlob(ctx, reference)
 				if err != nil {
 					logger.Warningf("sync: download error: ", err)
 					return
@@ -84,27 +104,36 @@ func (pi *Info) SyncPod(podName string, client blockstore.Client, logger logging
 				pi.file.AddToFileMap(path, meta)
 				path = strings.TrimPrefix(path, podName)
 				logger.Infof(path)
-				return
-			}
+			}(ref)
+			continue
+		}
 
+		wg.Add(1)
+		go func(result *feed.FeedData) {
+			defer wg.Done()
 			var dirInode *d.DirInode
-			err = json.Unmarshal(data, &dirInode)
+			err := json.Unmarshal(result.Data, &dirInode)
 			if err != nil {
 				logger.Warningf("sync: unmarshall error: %w", err)
 				return
 			}
 
 			path := dirInode.Meta.Path + utils.PathSeperator + dirInode.Meta.Name
-			err = pi.getDirectory().LoadDirMeta(podName, dirInode, fd, accountInfo)
+			err = pi.getDirectory().LoadDirMeta(ctx, podName, dirInode, fd, accountInfo, workers)
 			if err != nil {
-				logger.Warningf("sync: load meta error: %w", err)
+				sendError(errC, err)
 				return
 			}
 			pi.getDirectory().AddToDirectoryMap(path, dirInode)
 			path = strings.TrimPrefix(path, podName)
 			logger.Infof(path)
-		}(ref)
+		}(results[i])
 	}
 	wg.Wait()
+	select {
+	case err := <-errC:
+		return err
+	default:
+	}
 	return nil
 }
@@ -17,17 +17,20 @@ limitations under the License.
 package pod
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"strings"
-	"time"
 
 	"github.com/dustin/go-humanize"
 
+	d "github.com/jmozah/intOS-dfs/pkg/dir"
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
+func (p *Pod) UploadFile(ctx context.Context, podName, fileName string, fileSize int64, fd io.Reader, podDir, blockSize, compression string) (string, error) {
 	if !p.isPodOpened(podName) {
 		return "", fmt.Errorf("login to pod to do this operation")
 	}
@@ -45,29 +48,56 @@ func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader,
 
 	path := p.getFilePath(podDir, podInfo)
 
-	_, dirInode, err := dir.GetDirNode(path, podInfo.getFeed(), podInfo.getAccountInfo())
+	_, _, err = dir.GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
 	if err != nil {
 		return "", err
 	}
 
+	// a file already at the path is replaced, and kept as a version of the
+	// new one
 	fpath := path + utils.PathSeperator + fileName
+	var oldMeta *m.FileMetaData
 	if podInfo.file.IsFileAlreadyPResent(fpath) {
-		return "", fmt.Errorf("file already present in the destination dir")
+		podInfo.modifyMu.Lock()
+		defer podInfo.modifyMu.Unlock()
+		oldMeta = podInfo.file.GetFromFileMap(fpath)
 	}
-	ref, err := podInfo.file.Upload(fd, fileName, fileSize, uint32(bs), fpath, compression)
+	ref, err := podInfo.file.Upload(ctx, fd, fileName, fileSize, uint32(bs), fpath, compression)
 	if err != nil {
 		return "", err
 	}
-	dirInode.Hashes = append(dirInode.Hashes, ref)
+	return p.linkFile(ctx, podName, podInfo, path, fpath, ref, oldMeta)
+}
 
-	dirInode.Meta.ModificationTime = time.Now().Unix()
-	topic, err := dir.UpdateDirectory(dirInode)
+// linkFile adds the file stored at fpath, whose metadata is at ref, to the
+// directory at path. The file replaces the one of oldMeta if it is not nil.
+func (p *Pod) linkFile(ctx context.Context, podName string, podInfo *Info, path, fpath string, ref []byte, oldMeta *m.FileMetaData) (string, error) {
+	dir := podInfo.getDirectory()
+	_, topic, err := dir.ModifyDirectory(ctx, path, func(dirInode *d.DirInode) error {
+		if oldMeta == nil {
+			dirInode.Hashes = append(dirInode.Hashes, ref)
+			return nil
+		}
+		for i, hash := range dirInode.Hashes {
+			if bytes.Equal(hash, oldMeta.MetaReference) {
+				dirInode.Hashes[i] = ref
+				return nil
+			}
+		}
+		return fmt.Errorf("file not present in directory")
+	})
 	if err != nil {
+		// the file is not linked, so it is not a part of the pod
+		if oldMeta != nil {
+			podInfo.getFile().AddToFileMap(fpath, oldMeta)
+		} else {
+			podInfo.getFile().RemoveFromFileMap(fpath)
+		}
 		return "", err
 	}
 
 	if path != podInfo.GetCurrentPodPathAndName() {
-		err = p.UpdateTillThePod(podName, podInfo.getDirectory(), topic, path, true)
+		err = p.UpdateTillThePod(ctx, podName, podInfo.getDirectory(), topic, path, true)
 		if err != nil {
 			return "", err
 		}
@@ -76,6 +106,15 @@ func (p *Pod) UploadFile(podName, fileName string, fileSize int64, fd io.Reader,
 	return utils.NewReference(ref).String(), nil
 }
 
+// getPodFilePath returns the path of a file of the pod, podFile being relative
+// to the current directory.
+func (p *Pod) getPodFilePath(podFile string, podInfo *Info) string {
+	if podInfo.IsCurrentDirRoot() {
+		return podInfo.GetCurrentPodPathAndName() + podFile
+	}
+	return podInfo.GetCurrentDirPathAndName() + utils.PathSeperator + podFile
+}
+
 func (p *Pod) getFilePath(podDir string, podInfo *Info) string {
 	var path string
 	if podDir == utils.PathSeperator || podDir == podInfo.GetCurrentPodPathAndName() {
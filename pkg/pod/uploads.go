@@ -0,0 +1,250 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+
+	f "github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// OpenUpload starts a resumable upload of a file to podDir, on behalf of the
+// user userName. The upload is kept in uploads until it is committed or
+// aborted.
+func (p *Pod) OpenUpload(ctx context.Context, uploads *f.Uploads, userName, podName, fileName string, fileSize int64, podDir, blockSize, compression string) (*f.UploadSession, error) {
+	if !p.isPodOpened(podName) {
+		return nil, fmt.Errorf("login to pod to do this operation")
+	}
+
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := humanize.ParseBytes(blockSize)
+	if err != nil {
+		return nil, err
+	}
+	if bs == 0 || bs > math.MaxUint32 || fileSize < 0 {
+		return nil, f.ErrInvalidBlock
+	}
+
+	path := p.getFilePath(podDir, podInfo)
+	_, _, err = podInfo.getDirectory().GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
+	if err != nil {
+		return nil, err
+	}
+	if podInfo.file.IsFileAlreadyPResent(path + utils.PathSeperator + fileName) {
+		return nil, fmt.Errorf("file already present in the destination dir")
+	}
+
+	session := &f.UploadSession{
+		UserName:     userName,
+		PodName:      podName,
+		DirPath:      path,
+		FileName:     fileName,
+		FileSize:     uint64(fileSize),
+		BlockSize:    uint32(bs),
+		Compression:  compression,
+		CreationTime: podInfo.getFeed().Clock().Now().Unix(),
+	}
+	err = uploads.Open(session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetUpload returns the upload with the given id to the pod.
+func (p *Pod) GetUpload(uploads *f.Uploads, podName, uploadId string) (*f.UploadSession, error) {
+	if !p.isPodOpened(podName) {
+		return nil, fmt.Errorf("login to pod to do this operation")
+	}
+	session, err := uploads.Get(uploadId)
+	if err != nil {
+		return nil, err
+	}
+	if session.PodName != podName {
+		return nil, f.ErrUploadNotFound
+	}
+	return session, nil
+}
+
+// UploadBlocks stores the blocks of an upload read from r, the first of them
+// being the block at index first. Every block is recorded in the upload as
+// soon as it is stored, so the ones stored stay even if a later one fails.
+// It returns the number of blocks stored.
+func (p *Pod) UploadBlocks(ctx context.Context, uploads *f.Uploads, podName, uploadId string, first int, r io.Reader) (int, error) {
+	session, err := p.GetUpload(uploads, podName, uploadId)
+	if err != nil {
+		return 0, err
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return 0, err
+	}
+	_, err = session.BlockLength(first)
+	if err != nil {
+		return 0, err
+	}
+
+	var stored int
+	errC := make(chan error, 1) // the first error of the workers
+	worker := make(chan bool, f.NoOfParallelWorkers)
+	var wg sync.WaitGroup
+	var storedMu sync.Mutex
+	for index := first; index < session.NoOfBlocks(); index++ {
+		length, _ := session.BlockLength(index)
+		data := make([]byte, length)
+		_, err = io.ReadFull(r, data)
+		if err == io.EOF && index > first {
+			err = nil // the blocks sent are over
+			break
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = f.ErrInvalidBlock
+		}
+		if err != nil {
+			break
+		}
+
+		wg.Add(1)
+		worker <- true
+		go func(index int) {
+			defer func() {
+				<-worker
+				wg.Done()
+			}()
+			fb, err := podInfo.getFile().UploadBlock(ctx, data, index, session.BlockSize, session.Compression)
+			if err != nil {
+				sendError(errC, err)
+				return
+			}
+			replaced, err := uploads.AddBlock(uploadId, index, fb)
+			if err != nil {
+				sendError(errC, err)
+				return
+			}
+
+			// the block is kept by the upload until it is committed or
+			// aborted, it is not garbage of the pod. the one it replaces is
+			podInfo.pins.forget(fb.Address)
+			if replaced != nil && !bytes.Equal(replaced.Address, fb.Address) {
+				p.unpin(ctx, podInfo, []garbage{{ref: replaced.Address}})
+			}
+			storedMu.Lock()
+			stored++
+			storedMu.Unlock()
+		}(index)
+	}
+	if err == nil {
+		// nothing can follow the last block
+		if n, _ := io.ReadFull(r, make([]byte, 1)); n > 0 {
+			err = f.ErrInvalidBlock
+		}
+	}
+
+	wg.Wait()
+	select {
+	case werr := <-errC:
+		if err == nil {
+			err = werr
+		}
+	default:
+	}
+	return stored, err
+}
+
+// CommitUpload makes the file of an upload whose blocks are all stored, and
+// adds it to its directory. The upload ends with it.
+func (p *Pod) CommitUpload(ctx context.Context, uploads *f.Uploads, podName, uploadId string) (string, error) {
+	session, err := p.GetUpload(uploads, podName, uploadId)
+	if err != nil {
+		return "", err
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return "", err
+	}
+	blocks, err := session.FileBlocks()
+	if err != nil {
+		return "", err
+	}
+
+	path := session.DirPath
+	_, _, err = podInfo.getDirectory().GetDirNode(ctx, path, podInfo.getFeed(), podInfo.getAccountInfo())
+	if err != nil {
+		return "", err
+	}
+	fpath := path + utils.PathSeperator + session.FileName
+	if podInfo.file.IsFileAlreadyPResent(fpath) {
+		return "", fmt.Errorf("file already present in the destination dir")
+	}
+	ref, err := podInfo.file.UploadFromBlocks(ctx, blocks, session.FileName, session.FileSize, session.BlockSize, fpath, session.Compression)
+	if err != nil {
+		return "", err
+	}
+	reference, err := p.linkFile(ctx, podName, podInfo, path, fpath, ref, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = uploads.Remove(uploadId)
+	if err != nil {
+		p.logger.Warningf("upload %s committed but not removed: %v", uploadId, err)
+	}
+	return reference, nil
+}
+
+// AbortUpload ends an upload without making its file, and unpins the blocks
+// it stored.
+func (p *Pod) AbortUpload(ctx context.Context, uploads *f.Uploads, podName, uploadId string) error {
+	session, err := p.GetUpload(uploads, podName, uploadId)
+	if err != nil {
+		return err
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return err
+	}
+	err = uploads.Remove(uploadId)
+	if err != nil {
+		return err
+	}
+	var refs []garbage
+	for _, fb := range session.Blocks {
+		refs = append(refs, garbage{ref: fb.Address})
+	}
+	p.unpin(ctx, podInfo, refs)
+	return nil
+}
+
+// sendError keeps the first error of the upload workers and drops the rest.
+func sendError(errC chan error, err error) {
+	select {
+	case errC <- err:
+	default:
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestPod_ResumableUpload(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+	podName1 := "test1"
+	_, err = pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+	err = pod1.MakeDir(context.Background(), podName1, "dir1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataDir, err := ioutil.TempDir("", "uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dataDir)
+	uploads, err := file.NewUploads(dataDir, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 1050) // 11 blocks of 100 bytes, the last of 50
+	_, err = rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// send sends the blocks from first up to, not including, last
+	send := func(t *testing.T, uploadId string, first, last int) {
+		t.Helper()
+		end := last * 100
+		if end > len(data) {
+			end = len(data)
+		}
+		stored, err := pod1.UploadBlocks(context.Background(), uploads, podName1, uploadId, first, bytes.NewReader(data[first*100:end]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored != last-first {
+			t.Fatalf("expected %d blocks stored, got %d", last-first, stored)
+		}
+	}
+
+	t.Run("resume-after-restart", func(t *testing.T) {
+		session, err := pod1.OpenUpload(context.Background(), uploads, "user1", podName1, "file1", int64(len(data)), "/dir1", "100", "snappy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session.NoOfBlocks() != 11 {
+			t.Fatalf("expected 11 blocks, got %d", session.NoOfBlocks())
+		}
+		send(t, session.Id, 5, 11)
+		send(t, session.Id, 0, 3)
+
+		// the blocks stored are not garbage of the pod
+		_, err = pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a restart keeps the blocks stored
+		uploads, err = file.NewUploads(dataDir, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session, err = pod1.GetUpload(uploads, podName1, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if missing := session.MissingBlocks(); !reflect.DeepEqual(missing, []int{3, 4}) {
+			t.Fatalf("expected blocks 3 and 4 missing, got %v", missing)
+		}
+		_, err = pod1.CommitUpload(context.Background(), uploads, podName1, session.Id)
+		if err != file.ErrUploadIncomplete {
+			t.Fatalf("expected %v, got %v", file.ErrUploadIncomplete, err)
+		}
+
+		send(t, session.Id, 3, 5)
+		_, err = pod1.CommitUpload(context.Background(), uploads, podName1, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = uploads.Get(session.Id)
+		if err != file.ErrUploadNotFound {
+			t.Fatalf("committed upload not removed")
+		}
+
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/dir1/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("file committed is not the one uploaded")
+		}
+	})
+
+	t.Run("invalid-blocks", func(t *testing.T) {
+		session, err := pod1.OpenUpload(context.Background(), uploads, "user1", podName1, "file2", int64(len(data)), ".", "100", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range []struct {
+			name  string
+			first int
+			data  []byte
+		}{
+			{name: "partial-block", first: 0, data: data[:150]},
+			{name: "past-last-block", first: 11, data: data[:50]},
+			{name: "after-last-block", first: 10, data: data[:60]},
+			{name: "negative-index", first: -1, data: data[:100]},
+			{name: "no-block", first: 0, data: nil},
+		} {
+			_, err = pod1.UploadBlocks(context.Background(), uploads, podName1, session.Id, tc.first, bytes.NewReader(tc.data))
+			if err != file.ErrInvalidBlock {
+				t.Fatalf("%s: expected %v, got %v", tc.name, file.ErrInvalidBlock, err)
+			}
+		}
+		session, err = pod1.GetUpload(uploads, podName1, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the whole blocks before the invalid data are kept
+		if stored := session.StoredBlocks(); !reflect.DeepEqual(stored, []int{0, 10}) {
+			t.Fatalf("expected blocks 0 and 10 stored, got %v", stored)
+		}
+		_, err = pod1.GetUpload(uploads, "other", session.Id)
+		if err == nil {
+			t.Fatalf("upload found from another pod")
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		session, err := pod1.OpenUpload(context.Background(), uploads, "user1", podName1, "file3", int64(len(data)), ".", "100", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// blocks of their own, the ones of the other files are pinned by them
+		blocks := make([]byte, 200)
+		_, err = rand.Read(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pod1.UploadBlocks(context.Background(), uploads, podName1, session.Id, 0, bytes.NewReader(blocks))
+		if err != nil {
+			t.Fatal(err)
+		}
+		session, err = pod1.GetUpload(uploads, podName1, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, fb := range session.Blocks {
+			if !mockClient.IsBlobPinned(fb.Address) {
+				t.Fatalf("block of the upload not pinned")
+			}
+		}
+
+		err = pod1.AbortUpload(context.Background(), uploads, podName1, session.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = uploads.Get(session.Id)
+		if err != file.ErrUploadNotFound {
+			t.Fatalf("aborted upload not removed")
+		}
+		for _, fb := range session.Blocks {
+			if mockClient.IsBlobPinned(fb.Address) {
+				t.Fatalf("block of the aborted upload still pinned")
+			}
+		}
+	})
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	f "github.com/jmozah/intOS-dfs/pkg/file"
+	m "github.com/jmozah/intOS-dfs/pkg/meta"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// ListFileVersions returns the earlier versions of a file of the pod, the
+// latest first.
+func (p *Pod) ListFileVersions(podName, podFile string) ([]m.FileVersion, error) {
+	if !p.isPodOpened(podName) {
+		return nil, fmt.Errorf("login to pod to do this operation")
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo.getFile().ListVersions(p.getPodFilePath(podFile, podInfo))
+}
+
+// DownloadFileVersion returns a reader of an earlier version of a file of
+// the pod, version being the reference of the version.
+func (p *Pod) DownloadFileVersion(ctx context.Context, podName, podFile, version string) (*f.Reader, string, string, error) {
+	if !p.isPodOpened(podName) {
+		return nil, "", "", fmt.Errorf("login to pod to do this operation")
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, "", "", err
+	}
+	ref, err := utils.ParseHexReference(version)
+	if err != nil {
+		return nil, "", "", f.ErrVersionNotFound
+	}
+	return podInfo.getFile().DownloadVersion(ctx, p.getPodFilePath(podFile, podInfo), ref.Bytes())
+}
+
+// RestoreFileVersion makes an earlier version of a file of the pod its
+// current one, and returns the new reference of the file.
+func (p *Pod) RestoreFileVersion(ctx context.Context, podName, podFile, version string) (string, error) {
+	ref, err := utils.ParseHexReference(version)
+	if err != nil {
+		return "", f.ErrVersionNotFound
+	}
+	return p.modifyFile(ctx, podName, podFile, func(file *f.File, path string) (*f.Modification, error) {
+		return file.RestoreVersion(ctx, path, ref.Bytes())
+	})
+}
+
+// PruneFileVersions drops the versions of a file of the pod which are older
+// than maxAge or past the latest maxCount of them, and unpins what only they
+// used. It returns the new reference of the file.
+func (p *Pod) PruneFileVersions(ctx context.Context, podName, podFile string, maxAge time.Duration, maxCount int) (string, error) {
+	return p.modifyFile(ctx, podName, podFile, func(file *f.File, path string) (*f.Modification, error) {
+		return file.PruneVersions(ctx, path, maxAge, maxCount)
+	})
+}
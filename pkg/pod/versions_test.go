@@ -0,0 +1,328 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/clock"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/file"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+func TestPod_FileVersions(t *testing.T) {
+	mockClient := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1600000000, 0)
+	clk := clock.NewMock(start)
+	fd := feed.NewWithOptions(acc.GetUserAccountInfo(), mockClient, feed.Options{Clock: clk}, logger)
+	pod1 := NewPod(mockClient, fd, acc, logger)
+	podName1 := "test1"
+	info, err := pod1.CreatePod(context.Background(), podName1, "password")
+	if err != nil {
+		t.Fatalf("error creating pod %s", podName1)
+	}
+
+	// read returns the content of a reader of a file
+	read := func(t *testing.T, reader *file.Reader, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	// references returns all the blobs the file holds, its versions included
+	references := func(t *testing.T, podFile string) [][]byte {
+		t.Helper()
+		meta := info.getFile().GetFromFileMap("/" + podName1 + podFile)
+		refs, err := info.getFile().GetReferences(context.Background(), meta.MetaReference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return refs
+	}
+
+	// the file as uploaded, written over an hour later and appended to an
+	// hour after that
+	data0 := randomBytes(t, 1000)
+	_, err = pod1.UploadFile(context.Background(), podName1, "file1", 1000, bytes.NewReader(data0), ".", "100", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Add(time.Hour)
+	data1 := append([]byte{}, data0...)
+	copy(data1[150:], randomBytes(t, 100))
+	_, err = pod1.WriteFile(context.Background(), podName1, "/file1", data1[150:250], 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Add(time.Hour)
+	data2 := append(append([]byte{}, data1...), randomBytes(t, 100)...)
+	_, err = pod1.AppendFile(context.Background(), podName1, "/file1", data2[1000:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions [2]string
+	t.Run("list", func(t *testing.T) {
+		list, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(list))
+		}
+		if list[0].ModificationTime != start.Add(time.Hour).Unix() || list[1].ModificationTime != start.Unix() {
+			t.Fatalf("versions are not the latest first: %d, %d", list[0].ModificationTime, list[1].ModificationTime)
+		}
+		for i, version := range list {
+			if version.FileSize != 1000 {
+				t.Fatalf("version %d of size %d, expected 1000", i, version.FileSize)
+			}
+		}
+		versions[0] = utils.NewReference(list[1].Reference).String()
+		versions[1] = utils.NewReference(list[0].Reference).String()
+	})
+
+	t.Run("download-version", func(t *testing.T) {
+		for i, expected := range [][]byte{data0, data1} {
+			reader, _, size, err := pod1.DownloadFileVersion(context.Background(), podName1, "/file1", versions[i])
+			if size != "1000" {
+				t.Fatalf("version %d of size %s, expected 1000", i, size)
+			}
+			if !bytes.Equal(read(t, reader, err), expected) {
+				t.Fatalf("version %d is not the one written", i)
+			}
+		}
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if !bytes.Equal(read(t, reader, err), data2) {
+			t.Fatalf("current file is not the latest written")
+		}
+
+		_, _, _, err = pod1.DownloadFileVersion(context.Background(), podName1, "/file1", hex.EncodeToString(make([]byte, 32)))
+		if err != file.ErrVersionNotFound {
+			t.Fatalf("expected %v, got %v", file.ErrVersionNotFound, err)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		clk.Add(time.Hour)
+		_, err := pod1.RestoreFileVersion(context.Background(), podName1, "/file1", versions[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, _, size, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if size != "1000" {
+			t.Fatalf("restored file of size %s, expected 1000", size)
+		}
+		if !bytes.Equal(read(t, reader, err), data0) {
+			t.Fatalf("restored file is not the version restored")
+		}
+
+		// the file before the restore is a version now
+		list, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 || list[0].FileSize != 1100 {
+			t.Fatalf("file before the restore not kept as a version")
+		}
+	})
+
+	t.Run("survives-sync", func(t *testing.T) {
+		err := pod1.SyncPod(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 {
+			t.Fatalf("expected 3 versions after sync, got %d", len(list))
+		}
+
+		// the versions are reachable, so the gc keeps them
+		before := references(t, "/file1")
+		_, err = pod1.CollectGarbage(context.Background(), podName1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range before {
+			if !mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %x of a version unpinned by the gc", ref)
+			}
+		}
+	})
+
+	// checkPruned checks that what the file holds after a prune is pinned,
+	// and that the rest of what it held before is not
+	checkPruned := func(t *testing.T, before [][]byte) {
+		t.Helper()
+		after := make(map[string]bool)
+		for _, ref := range references(t, "/file1") {
+			after[hex.EncodeToString(ref)] = true
+		}
+		for _, ref := range before {
+			if mockClient.IsBlobPinned(ref) != after[hex.EncodeToString(ref)] {
+				t.Fatalf("blob %x pinned: %v, expected %v", ref, !after[hex.EncodeToString(ref)], after[hex.EncodeToString(ref)])
+			}
+		}
+	}
+
+	t.Run("prune-by-count", func(t *testing.T) {
+		before := references(t, "/file1")
+		_, err := pod1.PruneFileVersions(context.Background(), podName1, "/file1", 0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].FileSize != 1100 || list[1].ModificationTime != start.Add(time.Hour).Unix() {
+			t.Fatalf("not the latest 2 versions kept")
+		}
+		checkPruned(t, before)
+		_, _, _, err = pod1.DownloadFileVersion(context.Background(), podName1, "/file1", versions[0])
+		if err != file.ErrVersionNotFound {
+			t.Fatalf("expected %v, got %v", file.ErrVersionNotFound, err)
+		}
+
+		// the file itself is left as it is
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file1")
+		if !bytes.Equal(read(t, reader, err), data0) {
+			t.Fatalf("file changed by the prune")
+		}
+	})
+
+	t.Run("prune-by-age", func(t *testing.T) {
+		// nothing is older than a day
+		ref, err := pod1.PruneFileVersions(context.Background(), podName1, "/file1", 24*time.Hour, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref != utils.NewReference(info.getFile().GetFromFileMap("/"+podName1+"/file1").MetaReference).String() {
+			t.Fatalf("file changed by a prune which dropped nothing")
+		}
+
+		// the version of an hour after the start is dropped, the one two
+		// hours after it is not
+		before := references(t, "/file1")
+		clk.Add(90 * time.Minute)
+		_, err = pod1.PruneFileVersions(context.Background(), podName1, "/file1", 3*time.Hour, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := pod1.ListFileVersions(podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].FileSize != 1100 {
+			t.Fatalf("expected the latest version kept, got %d versions", len(list))
+		}
+		checkPruned(t, before)
+	})
+
+	t.Run("upload-replaces", func(t *testing.T) {
+		first := randomBytes(t, 300)
+		_, err := pod1.UploadFile(context.Background(), podName1, "file2", 300, bytes.NewReader(first), ".", "100", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		clk.Add(time.Hour)
+		second := randomBytes(t, 450)
+		_, err = pod1.UploadFile(context.Background(), podName1, "file2", 450, bytes.NewReader(second), ".", "100", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the directory holds the new file in the place of the old one
+		entries, err := pod1.ListEntiesInDir(context.Background(), podName1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, entry := range entries {
+			if entry.Name == "file2" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Fatalf("expected one entry of the file, got %d", count)
+		}
+		reader, _, _, err := pod1.DownloadFile(context.Background(), podName1, "/file2")
+		if !bytes.Equal(read(t, reader, err), second) {
+			t.Fatalf("file is not the one uploaded last")
+		}
+
+		list, err := pod1.ListFileVersions(podName1, "/file2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].FileSize != 300 {
+			t.Fatalf("file replaced by the upload not kept as a version")
+		}
+		version := utils.NewReference(list[0].Reference).String()
+		reader, _, _, err = pod1.DownloadFileVersion(context.Background(), podName1, "/file2", version)
+		if !bytes.Equal(read(t, reader, err), first) {
+			t.Fatalf("version is not the file uploaded first")
+		}
+
+		_, err = pod1.RestoreFileVersion(context.Background(), podName1, "/file2", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, _, _, err = pod1.DownloadFile(context.Background(), podName1, "/file2")
+		if !bytes.Equal(read(t, reader, err), first) {
+			t.Fatalf("restored file is not the file uploaded first")
+		}
+	})
+
+	t.Run("rm-unpins-versions", func(t *testing.T) {
+		before := references(t, "/file1")
+		err := pod1.RemoveFile(context.Background(), podName1, "/file1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range before {
+			if mockClient.IsBlobPinned(ref) {
+				t.Fatalf("blob %x of a removed file still pinned", ref)
+			}
+		}
+	})
+}
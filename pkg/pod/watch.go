@@ -0,0 +1,41 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pod
+
+import (
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/utils"
+)
+
+// PodsFeed returns the topic and the owner of the feed which holds the list of
+// pods of the user.
+func (p *Pod) PodsFeed() ([]byte, utils.Address) {
+	return utils.HashString(podFile), p.acc.GetAddress(account.UserAccountIndex)
+}
+
+// DirectoryFeed returns the topic and the owner of the feed of a directory of
+// an open pod, so that it can be watched for changes.
+func (p *Pod) DirectoryFeed(podName, podDir string) ([]byte, utils.Address, error) {
+	if !p.isPodOpened(podName) {
+		return nil, utils.Address{}, ErrPodNotOpened
+	}
+	podInfo, err := p.GetPodInfoFromPodMap(podName)
+	if err != nil {
+		return nil, utils.Address{}, err
+	}
+	path := p.getFilePath(podDir, podInfo)
+	return utils.HashString(path), podInfo.getAccountInfo().GetAddress(), nil
+}
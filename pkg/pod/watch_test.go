@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 intOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package pod
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/jmozah/intOS-dfs/pkg/account"
+	"github.com/jmozah/intOS-dfs/pkg/blockstore/bee/mock"
+	"github.com/jmozah/intOS-dfs/pkg/feed"
+	"github.com/jmozah/intOS-dfs/pkg/logging"
+)
+
+func TestPod_Watch(t *testing.T) {
+	client := mock.NewMockBeeClient()
+	logger := logging.New(ioutil.Discard, 0)
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd1 := feed.New(acc.GetUserAccountInfo(), client, logger)
+	device1 := NewPod(client, fd1, acc, logger)
+	device2 := NewPod(client, feed.New(acc.GetUserAccountInfo(), client, logger), acc, logger)
+	podName1 := "test1"
+	podName2 := "test2"
+
+	expectEvent := func(t *testing.T, w *feed.Watcher, topic []byte) {
+		t.Helper()
+		select {
+		case event := <-w.Events():
+			if !bytes.Equal(event.Topic, topic) {
+				t.Fatalf("event for the wrong feed %x", event.Topic)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event for the change")
+		}
+	}
+
+	t.Run("change-on-other-device", func(t *testing.T) {
+		_, err := device1.CreatePod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName1)
+		}
+		_, err = device2.OpenPod(context.Background(), podName1, "password")
+		if err != nil {
+			t.Fatalf("error opening pod %s on the other device: %v", podName1, err)
+		}
+
+		w := fd1.NewWatcher(10 * time.Millisecond)
+		defer w.Close()
+		dirTopic, dirUser, err := device1.DirectoryFeed(podName1, "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Watch(context.Background(), dirTopic, dirUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		podsTopic, podsUser := device1.PodsFeed()
+		err = w.Watch(context.Background(), podsTopic, podsUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = device2.MakeDir(context.Background(), podName1, "dir1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectEvent(t, w, dirTopic)
+
+		_, err = device2.CreatePod(context.Background(), podName2, "password")
+		if err != nil {
+			t.Fatalf("error creating pod %s", podName2)
+		}
+		expectEvent(t, w, podsTopic)
+	})
+
+	t.Run("pod-not-open", func(t *testing.T) {
+		_, _, err := device1.DirectoryFeed(podName2, "/")
+		if err != ErrPodNotOpened {
+			t.Fatalf("expected %v, got %v", ErrPodNotOpened, err)
+		}
+	})
+}
@@ -17,10 +17,11 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"net/http"
 )
 
-func (u *Users) DeleteUser(userName, dataDir, password, sessionId string, response http.ResponseWriter, ui *Info) error {
+func (u *Users) DeleteUser(ctx context.Context, userName, dataDir, password, sessionId string, response http.ResponseWriter, ui *Info) error {
 
 	if !u.IsUsernameAvailable(userName, dataDir) {
 		return ErrInvalidUserName
@@ -48,7 +49,7 @@ func (u *Users) DeleteUser(userName, dataDir, password, sessionId string, respon
 	if err != nil {
 		return err
 	}
-	err = u.deleteMnemonic(userName, address, ui.GetFeed(), u.client)
+	err = u.deleteMnemonic(ctx, userName, address, ui.GetFeed(), u.client)
 	if err != nil {
 		return err
 	}
@@ -17,6 +17,7 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -29,20 +30,20 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (u *Users) ImportUsingAddress(userName, passPhrase, addressString, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) error {
+func (u *Users) ImportUsingAddress(ctx context.Context, userName, passPhrase, addressString, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) error {
 	if u.IsUsernameAvailable(userName, dataDir) {
 		return ErrUserAlreadyPresent
 	}
 
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
-	fd := feed.New(accountInfo, client, u.logger)
+	fd := feed.NewWithOptions(accountInfo, client, u.feedOptions, u.logger)
 	file := f.NewFile(userName, client, fd, accountInfo, u.logger)
 
 	address := utils.HexToAddress(addressString)
 
 	// load the encrypted mnemonic and see if it is valid
-	encryptedMnemonic, err := u.getEncryptedMnemonic(userName, address, fd)
+	encryptedMnemonic, err := u.getEncryptedMnemonic(ctx, userName, address, fd)
 	if err != nil {
 		return err
 	}
@@ -72,7 +73,7 @@ func (u *Users) ImportUsingAddress(userName, passPhrase, addressString, dataDir
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPodWithOptions(u.client, fd, acc, u.podOptions, u.logger),
 	}
 
 	// set cookie and add user to map
@@ -17,6 +17,7 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -28,7 +29,7 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/pod"
 )
 
-func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) error {
+func (u *Users) LoginUser(ctx context.Context, userName, passPhrase, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) error {
 	if u.IsUserLoggedIn(sessionId) {
 		return ErrUserAlreadyLoggedIn
 	}
@@ -39,7 +40,7 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
-	fd := feed.New(accountInfo, client, u.logger)
+	fd := feed.NewWithOptions(accountInfo, client, u.feedOptions, u.logger)
 	file := f.NewFile(userName, client, fd, accountInfo, u.logger)
 
 	// load address from userName
@@ -49,7 +50,7 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 	}
 
 	// load encrypted mnemonic from Swarm
-	encryptedMnemonic, err := u.getEncryptedMnemonic(userName, address, fd)
+	encryptedMnemonic, err := u.getEncryptedMnemonic(ctx, userName, address, fd)
 	if err != nil {
 		return err
 	}
@@ -74,7 +75,7 @@ func (u *Users) LoginUser(userName, passPhrase, dataDir string, client blockstor
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPodWithOptions(u.client, fd, acc, u.podOptions, u.logger),
 	}
 
 	// set cookie and add user to map
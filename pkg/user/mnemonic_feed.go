@@ -17,33 +17,34 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"github.com/jmozah/intOS-dfs/pkg/blockstore"
 	"github.com/jmozah/intOS-dfs/pkg/feed"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (u *Users) uploadEncryptedMnemonic(userName string, address utils.Address, encryptedMnemonic string, fd *feed.API) error {
+func (u *Users) uploadEncryptedMnemonic(ctx context.Context, userName string, address utils.Address, encryptedMnemonic string, fd *feed.API) error {
 	topic := utils.HashString(userName)
 	data := []byte(encryptedMnemonic)
-	_, err := fd.CreateFeed(topic, address, data)
+	_, err := fd.CreateFeed(ctx, topic, address, data)
 	return err
 }
 
-func (u *Users) getEncryptedMnemonic(userName string, address utils.Address, fd *feed.API) (string, error) {
+func (u *Users) getEncryptedMnemonic(ctx context.Context, userName string, address utils.Address, fd *feed.API) (string, error) {
 	topic := utils.HashString(userName)
-	_, data, err := fd.GetFeedData(topic, address)
+	_, data, err := fd.GetFeedData(ctx, topic, address)
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
 
-func (u *Users) deleteMnemonic(userName string, address utils.Address, fd *feed.API, client blockstore.Client) error {
+func (u *Users) deleteMnemonic(ctx context.Context, userName string, address utils.Address, fd *feed.API, client blockstore.Client) error {
 	topic := utils.HashString(userName)
-	feedAddress, _, err := fd.GetFeedData(topic, address)
+	feedAddress, _, err := fd.GetFeedData(ctx, topic, address)
 	if err != nil {
 		return err
 	}
 	ref := utils.NewReference(feedAddress)
-	return client.UnpinChunk(ref)
+	return client.UnpinChunk(ctx, ref)
 }
@@ -25,7 +25,9 @@ import (
 )
 
 const (
-	userDirectoryName = "user"
+	userDirectoryName      = "user"
+	feedHintsDirectoryName = "feed"
+	podPinsDirectoryName   = "pins"
 )
 
 func (u *Users) isUserMappingPresent(userName, dataDir string) bool {
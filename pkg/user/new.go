@@ -17,6 +17,7 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -28,13 +29,13 @@ import (
 	"github.com/jmozah/intOS-dfs/pkg/pod"
 )
 
-func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) (string, string, *Info, error) {
+func (u *Users) CreateNewUser(ctx context.Context, userName, passPhrase, mnemonic, dataDir string, client blockstore.Client, response http.ResponseWriter, sessionId string) (string, string, *Info, error) {
 	if u.IsUsernameAvailable(userName, dataDir) {
 		return "", "", nil, ErrUserAlreadyPresent
 	}
 	acc := account.New(u.logger)
 	accountInfo := acc.GetUserAccountInfo()
-	fd := feed.New(accountInfo, client, u.logger)
+	fd := feed.NewWithOptions(accountInfo, client, u.feedOptions, u.logger)
 	file := f.NewFile(userName, client, fd, accountInfo, u.logger)
 
 	mnemonic, encryptedMnemonic, err := acc.CreateUserAccount(passPhrase, mnemonic)
@@ -49,7 +50,7 @@ func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, cl
 	}
 
 	// store the ecnrypted mnemonic in Swarm
-	err = u.uploadEncryptedMnemonic(userName, accountInfo.GetAddress(), encryptedMnemonic, fd)
+	err = u.uploadEncryptedMnemonic(ctx, userName, accountInfo.GetAddress(), encryptedMnemonic, fd)
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -67,7 +68,7 @@ func (u *Users) CreateNewUser(userName, passPhrase, mnemonic, dataDir string, cl
 		account:   acc,
 		file:      file,
 		dir:       dir,
-		pods:      pod.NewPod(u.client, fd, acc, u.logger),
+		pods:      pod.NewPodWithOptions(u.client, fd, acc, u.podOptions, u.logger),
 	}
 
 	// set cookie and add user to map
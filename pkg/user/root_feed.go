@@ -17,26 +17,27 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
 	"github.com/jmozah/intOS-dfs/pkg/utils"
 )
 
-func (u *Users) CreateRootFeeds(userInfo *Info) error {
+func (u *Users) CreateRootFeeds(ctx context.Context, userInfo *Info) error {
 	rootAddress := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	err := u.CreateSettingsFeeds(rootAddress, userInfo)
+	err := u.CreateSettingsFeeds(ctx, rootAddress, userInfo)
 	if err != nil {
 		return err
 	}
-	err = u.CreateSharingFeeds(rootAddress, userInfo)
+	err = u.CreateSharingFeeds(ctx, rootAddress, userInfo)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (u *Users) CreateSettingsFeeds(rootAddress utils.Address, userInfo *Info) error {
+func (u *Users) CreateSettingsFeeds(ctx context.Context, rootAddress utils.Address, userInfo *Info) error {
 	// create name feed
 	name := &Name{}
 	data, err := json.Marshal(&name)
@@ -44,7 +45,7 @@ func (u *Users) CreateSettingsFeeds(rootAddress utils.Address, userInfo *Info) e
 		return err
 	}
 	topic := utils.HashString(nameFeedName)
-	_, err = userInfo.GetFeed().CreateFeed(topic, rootAddress, data)
+	_, err = userInfo.GetFeed().CreateFeed(ctx, topic, rootAddress, data)
 	if err != nil {
 		return err
 	}
@@ -56,7 +57,7 @@ func (u *Users) CreateSettingsFeeds(rootAddress utils.Address, userInfo *Info) e
 		return err
 	}
 	topic = utils.HashString(contactsFeedName)
-	_, err = userInfo.GetFeed().CreateFeed(topic, rootAddress, data)
+	_, err = userInfo.GetFeed().CreateFeed(ctx, topic, rootAddress, data)
 	if err != nil {
 		return err
 	}
@@ -64,7 +65,7 @@ func (u *Users) CreateSettingsFeeds(rootAddress utils.Address, userInfo *Info) e
 	// create avatar feed
 	topic = utils.HashString(avatarFeedName)
 	data = make([]byte, 0)
-	_, err = userInfo.GetFeed().CreateFeed(topic, rootAddress, data)
+	_, err = userInfo.GetFeed().CreateFeed(ctx, topic, rootAddress, data)
 	if err != nil {
 		return err
 	}
@@ -72,7 +73,7 @@ func (u *Users) CreateSettingsFeeds(rootAddress utils.Address, userInfo *Info) e
 	return nil
 }
 
-func (u *Users) CreateSharingFeeds(rootAddress utils.Address, userInfo *Info) error {
+func (u *Users) CreateSharingFeeds(ctx context.Context, rootAddress utils.Address, userInfo *Info) error {
 	// create inbox feed data
 	inboxFile := &Inbox{Entries: make([]SharingEntry, 0)}
 	inboxFileBytes, err := json.Marshal(&inboxFile)
@@ -81,14 +82,14 @@ func (u *Users) CreateSharingFeeds(rootAddress utils.Address, userInfo *Info) er
 	}
 
 	// store the new inbox file data
-	newInboxRef, err := u.client.UploadBlob(inboxFileBytes, true, true)
+	newInboxRef, err := u.client.UploadBlob(ctx, inboxFileBytes, true, true)
 	if err != nil {
 		return err
 	}
 
 	// store the inbox reference in to inbox feed
 	topic := utils.HashString(inboxFeedName)
-	_, err = userInfo.GetFeed().CreateFeed(topic, rootAddress, newInboxRef)
+	_, err = userInfo.GetFeed().CreateFeed(ctx, topic, rootAddress, newInboxRef)
 	if err != nil {
 		return err
 	}
@@ -101,14 +102,14 @@ func (u *Users) CreateSharingFeeds(rootAddress utils.Address, userInfo *Info) er
 	}
 
 	// store the new outbox file data
-	newOutboxRef, err := u.client.UploadBlob(outboxFileBytes, true, true)
+	newOutboxRef, err := u.client.UploadBlob(ctx, outboxFileBytes, true, true)
 	if err != nil {
 		return err
 	}
 
 	// store the outbox reference in to ourbox feed
 	topic = utils.HashString(outboxFeedName)
-	_, err = userInfo.GetFeed().CreateFeed(topic, rootAddress, newOutboxRef)
+	_, err = userInfo.GetFeed().CreateFeed(ctx, topic, rootAddress, newOutboxRef)
 	if err != nil {
 		return err
 	}
@@ -17,6 +17,7 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -28,6 +29,10 @@ const (
 	avatarFeedName   = "Avatar"
 	nameFeedName     = "Name"
 	contactsFeedName = "Contacts"
+
+	// updateAttempts is how many times a feed of the user is read and
+	// updated before giving up on other writers updating it at the same time
+	updateAttempts = 5
 )
 
 type Name struct {
@@ -50,16 +55,19 @@ type Address struct {
 	ZipCode      string `json:"zip_code"`
 }
 
-func (u *Users) SaveName(firstName, lastName, middleName, surName string, userInfo *Info) error {
+func (u *Users) SaveName(ctx context.Context, firstName, lastName, middleName, surName string, userInfo *Info) error {
 	rootAddress := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	data, err := getFeedData(nameFeedName, rootAddress, userInfo.GetFeed())
-	if err != nil {
-		return err
-	}
+	return modifyFeedData(ctx, nameFeedName, rootAddress, userInfo.GetFeed(), func(data []byte) ([]byte, error) {
+		return changeName(data, firstName, lastName, middleName, surName)
+	})
+}
+
+// changeName returns the name in data with the given parts of it changed.
+func changeName(data []byte, firstName, lastName, middleName, surName string) ([]byte, error) {
 	name := &Name{}
-	err = json.Unmarshal(data, name)
+	err := json.Unmarshal(data, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if firstName != "" {
 		name.FirstName = firstName
@@ -74,16 +82,12 @@ func (u *Users) SaveName(firstName, lastName, middleName, surName string, userIn
 		name.SurName = surName
 	}
 
-	nameData, err := json.Marshal(name)
-	if err != nil {
-		return err
-	}
-	return putFeedData(nameFeedName, rootAddress, nameData, userInfo.GetFeed())
+	return json.Marshal(name)
 }
 
-func (u *Users) GetName(userInfo *Info) (*Name, error) {
+func (u *Users) GetName(ctx context.Context, userInfo *Info) (*Name, error) {
 	rootAddress := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	data, err := getFeedData(nameFeedName, rootAddress, userInfo.GetFeed())
+	data, err := getFeedData(ctx, nameFeedName, rootAddress, userInfo.GetFeed())
 	if err != nil {
 		return nil, err
 	}
@@ -95,16 +99,19 @@ func (u *Users) GetName(userInfo *Info) (*Name, error) {
 	return name, nil
 }
 
-func (u *Users) SaveContacts(phone, mobile string, address *Address, userInfo *Info) error {
+func (u *Users) SaveContacts(ctx context.Context, phone, mobile string, address *Address, userInfo *Info) error {
 	rootAddress := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	data, err := getFeedData(contactsFeedName, rootAddress, userInfo.GetFeed())
-	if err != nil {
-		return err
-	}
+	return modifyFeedData(ctx, contactsFeedName, rootAddress, userInfo.GetFeed(), func(data []byte) ([]byte, error) {
+		return changeContacts(data, phone, mobile, address)
+	})
+}
+
+// changeContacts returns the contacts in data with the given ones changed.
+func changeContacts(data []byte, phone, mobile string, address *Address) ([]byte, error) {
 	contacts := &Contacts{}
-	err = json.Unmarshal(data, contacts)
+	err := json.Unmarshal(data, contacts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if phone != "" {
@@ -119,16 +126,12 @@ func (u *Users) SaveContacts(phone, mobile string, address *Address, userInfo *I
 		contacts.Addr.State = address.State
 		contacts.Addr.ZipCode = address.ZipCode
 	}
-	contactData, err := json.Marshal(contacts)
-	if err != nil {
-		return err
-	}
-	return putFeedData(contactsFeedName, rootAddress, contactData, userInfo.GetFeed())
+	return json.Marshal(contacts)
 }
 
-func (u *Users) GetContacts(userInfo *Info) (*Contacts, error) {
+func (u *Users) GetContacts(ctx context.Context, userInfo *Info) (*Contacts, error) {
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	data, err := getFeedData(contactsFeedName, rootReference, userInfo.GetFeed())
+	data, err := getFeedData(ctx, contactsFeedName, rootReference, userInfo.GetFeed())
 	if err != nil {
 		return nil, err
 	}
@@ -140,30 +143,56 @@ func (u *Users) GetContacts(userInfo *Info) (*Contacts, error) {
 	return contacts, nil
 }
 
-func (u *Users) SaveAvatar(avatar []byte, userInfo *Info) error {
+func (u *Users) SaveAvatar(ctx context.Context, avatar []byte, userInfo *Info) error {
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	return putFeedData(avatarFeedName, rootReference, avatar, userInfo.GetFeed())
+	return putFeedData(ctx, avatarFeedName, rootReference, avatar, userInfo.GetFeed())
 }
 
-func (u *Users) GetAvatar(userInfo *Info) ([]byte, error) {
+func (u *Users) GetAvatar(ctx context.Context, userInfo *Info) ([]byte, error) {
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	return getFeedData(avatarFeedName, rootReference, userInfo.GetFeed())
+	return getFeedData(ctx, avatarFeedName, rootReference, userInfo.GetFeed())
 }
 
-func getFeedData(fileName string, rootReference utils.Address, fd *feed.API) ([]byte, error) {
+func getFeedData(ctx context.Context, fileName string, rootReference utils.Address, fd *feed.API) ([]byte, error) {
 	topic := utils.HashString(fileName)
-	_, data, err := fd.GetFeedData(topic, rootReference)
+	_, data, err := fd.GetFeedData(ctx, topic, rootReference)
 	if err != nil {
 		return nil, err
 	}
 	return data, nil
 }
 
-func putFeedData(fileName string, rootReference utils.Address, data []byte, fd *feed.API) error {
+func putFeedData(ctx context.Context, fileName string, rootReference utils.Address, data []byte, fd *feed.API) error {
 	topic := utils.HashString(fileName)
-	_, err := fd.UpdateFeed(topic, rootReference, data)
+	_, err := fd.UpdateFeed(ctx, topic, rootReference, data)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// modifyFeedData applies change to the latest data of the feed of fileName
+// and stores what it returns, unless the feed was updated by someone else in
+// the meantime, like the same user on another device. Then the data is read
+// again and change applied to it afresh. An error returned by change aborts
+// the update.
+func modifyFeedData(ctx context.Context, fileName string, rootReference utils.Address, fd *feed.API, change func(data []byte) ([]byte, error)) error {
+	topic := utils.HashString(fileName)
+	for attempt := 1; ; attempt++ {
+		addr, data, err := fd.GetFeedData(ctx, topic, rootReference)
+		if err != nil {
+			return err
+		}
+		newData, err := change(data)
+		if err != nil {
+			return err
+		}
+		_, err = fd.UpdateFeedIf(ctx, topic, rootReference, newData, addr)
+		if err == nil {
+			return nil
+		}
+		if !feed.IsConflict(err) || attempt == updateAttempts {
+			return err
+		}
+	}
+}
@@ -17,11 +17,11 @@ limitations under the License.
 package user
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -67,7 +67,7 @@ type ReceiveFileInfo struct {
 	SharedTime     string `json:"shared_time"`
 }
 
-func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, userInfo *Info, pod *pod.Pod) (string, error) {
+func (u *Users) ShareFileWithUser(ctx context.Context, podName, podFilePath, destinationRef string, userInfo *Info, pod *pod.Pod) (string, error) {
 	// Get the meta reference of the file to share
 	metaRef, fileName, err := pod.GetMetaReferenceOfFile(podName, podFilePath)
 	if err != nil {
@@ -76,7 +76,7 @@ func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, u
 
 	// Create a outbox entry
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	now := time.Now()
+	now := userInfo.feedApi.Clock().Now()
 	sharingEntry := SharingEntry{
 		FileName:     fileName,
 		PodName:      userInfo.podName,
@@ -86,38 +86,29 @@ func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, u
 		SharedTime:   strconv.FormatInt(now.Unix(), 10),
 	}
 
-	// get the outbox reference from outbox feed
-	outboxRef, err := getFeedData(outboxFeedName, rootReference, userInfo.GetFeed())
-	if err != nil {
-		return "", err
-	}
-
-	// download the entire outbox file
-	outboxFileBytes, respCode, err := u.client.DownloadBlob(outboxRef)
-	if err != nil && respCode != http.StatusOK {
-		return "", err
-	}
-
-	// unmarshall, add a new outbox entry, marshall the data again
-	outbox := &Outbox{}
-	err = json.Unmarshal(outboxFileBytes, outbox)
-	if err != nil {
-		return "", err
-	}
-	outbox.Entries = append(outbox.Entries, sharingEntry)
-	outData, err := json.Marshal(outbox)
-	if err != nil {
-		return "", err
-	}
-
-	// store the new outbox file data
-	newOutboxRef, err := u.client.UploadBlob(outData, true, true)
-	if err != nil {
-		return "", err
-	}
-
-	// update the outbox feed with the new outbox file reference
-	err = putFeedData(outboxFeedName, rootReference, newOutboxRef, userInfo.GetFeed())
+	// add the entry to the outbox
+	err = modifyFeedData(ctx, outboxFeedName, rootReference, userInfo.GetFeed(), func(outboxRef []byte) ([]byte, error) {
+		// download the entire outbox file
+		outboxFileBytes, respCode, err := u.client.DownloadBlob(ctx, outboxRef)
+		if err != nil && respCode != http.StatusOK {
+			return nil, err
+		}
+
+		// unmarshall, add a new outbox entry, marshall the data again
+		outbox := &Outbox{}
+		err = json.Unmarshal(outboxFileBytes, outbox)
+		if err != nil {
+			return nil, err
+		}
+		outbox.Entries = append(outbox.Entries, sharingEntry)
+		outData, err := json.Marshal(outbox)
+		if err != nil {
+			return nil, err
+		}
+
+		// store the new outbox file data, its reference updates the outbox feed
+		return u.client.UploadBlob(ctx, outData, true, true)
+	})
 	if err != nil {
 		return "", err
 	}
@@ -135,7 +126,7 @@ func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, u
 	}
 
 	// upload the encrypted data and get the reference
-	ref, err := u.client.UploadBlob(encryptedData, true, true)
+	ref, err := u.client.UploadBlob(ctx, encryptedData, true, true)
 	if err != nil {
 		return "", err
 	}
@@ -145,12 +136,12 @@ func (u *Users) ShareFileWithUser(podName, podFilePath, destinationRef string, u
 	return sharingRef.String(), nil
 }
 
-func (u *Users) ReceiveFileFromUser(podName string, sharingRef utils.SharingReference, userInfo *Info, pod *pod.Pod, podDir string) (string, string, error) {
+func (u *Users) ReceiveFileFromUser(ctx context.Context, podName string, sharingRef utils.SharingReference, userInfo *Info, pod *pod.Pod, podDir string) (string, string, error) {
 	metaRef := sharingRef.GetRef()
 	unixTime := sharingRef.GetNonce()
 
 	// get the encrypted meta
-	encryptedData, respCode, err := u.client.DownloadBlob(metaRef)
+	encryptedData, respCode, err := u.client.DownloadBlob(ctx, metaRef)
 	if err != nil || respCode != http.StatusOK {
 		return "", "", err
 	}
@@ -171,44 +162,35 @@ func (u *Users) ReceiveFileFromUser(podName string, sharingRef utils.SharingRefe
 	// add the file to the pod directory specified
 	fileName := sharingEntry.FileName
 	sharingEntry.PodName = podName
-	err = pod.ReceiveFileAndStore(podName, podDir, fileName, sharingEntry.FileMetaHash)
+	err = pod.ReceiveFileAndStore(ctx, podName, podDir, fileName, sharingEntry.FileMetaHash)
 	if err != nil {
 		return "", "", err
 	}
 
-	// get the inbox reference from inbox feed
+	// add the entry to the inbox
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	inboxRef, err := getFeedData(inboxFeedName, rootReference, userInfo.GetFeed())
-	if err != nil {
-		return "", "", err
-	}
-
-	// download the entire inbox file
-	inboxFileBytes, respCode, err := u.client.DownloadBlob(inboxRef)
-	if err != nil && respCode != http.StatusOK {
-		return "", "", err
-	}
-
-	// unmarshall, add a new inbox entry, marshall the data again
-	inbox := &Inbox{}
-	err = json.Unmarshal(inboxFileBytes, inbox)
-	if err != nil {
-		return "", "", err
-	}
-	inbox.Entries = append(inbox.Entries, sharingEntry)
-	inData, err := json.Marshal(inbox)
-	if err != nil {
-		return "", "", err
-	}
-
-	// store the new inbox file data
-	newInboxRef, err := u.client.UploadBlob(inData, true, true)
-	if err != nil {
-		return "", "", err
-	}
-
-	// update the inbox feed with the new inbox file reference
-	err = putFeedData(inboxFeedName, rootReference, newInboxRef, userInfo.GetFeed())
+	err = modifyFeedData(ctx, inboxFeedName, rootReference, userInfo.GetFeed(), func(inboxRef []byte) ([]byte, error) {
+		// download the entire inbox file
+		inboxFileBytes, respCode, err := u.client.DownloadBlob(ctx, inboxRef)
+		if err != nil && respCode != http.StatusOK {
+			return nil, err
+		}
+
+		// unmarshall, add a new inbox entry, marshall the data again
+		inbox := &Inbox{}
+		err = json.Unmarshal(inboxFileBytes, inbox)
+		if err != nil {
+			return nil, err
+		}
+		inbox.Entries = append(inbox.Entries, sharingEntry)
+		inData, err := json.Marshal(inbox)
+		if err != nil {
+			return nil, err
+		}
+
+		// store the new inbox file data, its reference updates the inbox feed
+		return u.client.UploadBlob(ctx, inData, true, true)
+	})
 	if err != nil {
 		return "", "", err
 	}
@@ -220,10 +202,16 @@ func (u *Users) ReceiveFileFromUser(podName string, sharingRef utils.SharingRefe
 	return podDir + utils.PathSeperator + fileName, sharingEntry.FileMetaHash, nil
 }
 
-func (u *Users) GetSharingInbox(userInfo *Info) (*Inbox, error) {
+// InboxFeed returns the topic and the owner of the feed which holds the
+// reference of the sharing inbox of the user.
+func (u *Users) InboxFeed(userInfo *Info) ([]byte, utils.Address) {
+	return utils.HashString(inboxFeedName), userInfo.GetAccount().GetAddress(account.UserAccountIndex)
+}
+
+func (u *Users) GetSharingInbox(ctx context.Context, userInfo *Info) (*Inbox, error) {
 	// get the inbox reference from the inbox feed
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	inboxRef, err := getFeedData(inboxFeedName, rootReference, userInfo.GetFeed())
+	inboxRef, err := getFeedData(ctx, inboxFeedName, rootReference, userInfo.GetFeed())
 	if err != nil {
 		return nil, err
 	}
@@ -233,7 +221,7 @@ func (u *Users) GetSharingInbox(userInfo *Info) (*Inbox, error) {
 	}
 
 	// download the entire inbox file
-	inboxFileBytes, respCode, err := u.client.DownloadBlob(inboxRef)
+	inboxFileBytes, respCode, err := u.client.DownloadBlob(ctx, inboxRef)
 	if err != nil && respCode != http.StatusOK {
 		return nil, err
 	}
@@ -247,10 +235,10 @@ func (u *Users) GetSharingInbox(userInfo *Info) (*Inbox, error) {
 	return inbox, nil
 }
 
-func (u *Users) GetSharingOutbox(userInfo *Info) (*Outbox, error) {
+func (u *Users) GetSharingOutbox(ctx context.Context, userInfo *Info) (*Outbox, error) {
 	// get the outbox reference from the inbox feed
 	rootReference := userInfo.GetAccount().GetAddress(account.UserAccountIndex)
-	outboxRef, err := getFeedData(outboxFeedName, rootReference, userInfo.GetFeed())
+	outboxRef, err := getFeedData(ctx, outboxFeedName, rootReference, userInfo.GetFeed())
 	if err != nil {
 		return nil, err
 	}
@@ -260,7 +248,7 @@ func (u *Users) GetSharingOutbox(userInfo *Info) (*Outbox, error) {
 	}
 
 	// download the entire outbox file
-	outboxFileBytes, respCode, err := u.client.DownloadBlob(outboxRef)
+	outboxFileBytes, respCode, err := u.client.DownloadBlob(ctx, outboxRef)
 	if err != nil && respCode != http.StatusOK {
 		return nil, err
 	}
@@ -291,12 +279,12 @@ func decryptData(data []byte, now int64) ([]byte, error) {
 	return btcec.Decrypt(&privateKey, data)
 }
 
-func (u *Users) ReceiveFileInfo(podName string, sharingRef utils.SharingReference, userInfo *Info, pod *pod.Pod) (*ReceiveFileInfo, error) {
+func (u *Users) ReceiveFileInfo(ctx context.Context, podName string, sharingRef utils.SharingReference, userInfo *Info, pod *pod.Pod) (*ReceiveFileInfo, error) {
 	metaRef := sharingRef.GetRef()
 	unixTime := sharingRef.GetNonce()
 
 	// get the encrypted meta
-	encryptedData, respCode, err := u.client.DownloadBlob(metaRef)
+	encryptedData, respCode, err := u.client.DownloadBlob(ctx, metaRef)
 	if err != nil || respCode != http.StatusOK {
 		return nil, err
 	}
@@ -319,7 +307,7 @@ func (u *Users) ReceiveFileInfo(podName string, sharingRef utils.SharingReferenc
 		return nil, err
 	}
 
-	fileMetaBytes, respCode, err := u.client.DownloadBlob(fileMetaRef.Bytes())
+	fileMetaBytes, respCode, err := u.client.DownloadBlob(ctx, fileMetaRef.Bytes())
 	if err != nil || respCode != http.StatusOK {
 		return nil, err
 	}
@@ -334,7 +322,7 @@ func (u *Users) ReceiveFileInfo(podName string, sharingRef utils.SharingReferenc
 		compression = "None"
 	}
 
-	fileInodeBytes, respCode, err := u.client.DownloadBlob(meta.InodeAddress)
+	fileInodeBytes, respCode, err := u.client.DownloadBlob(ctx, meta.InodeAddress)
 	if err != nil || respCode != http.StatusOK {
 		return nil, err
 	}
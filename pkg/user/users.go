@@ -17,6 +17,7 @@ limitations under the License.
 package user
 
 import (
+	"path/filepath"
 	"sync"
 
 	"github.com/jmozah/intOS-dfs/pkg/account"
@@ -40,17 +41,40 @@ type Info struct {
 }
 
 type Users struct {
-	dataDir string
-	client  blockstore.Client
-	userMap map[string]*Info
-	userMu  *sync.RWMutex
-	logger  logging.Logger
+	dataDir     string
+	client      blockstore.Client
+	feedOptions feed.Options // options of the feeds of all users
+	podOptions  pod.Options  // options of the pods of all users
+	userMap     map[string]*Info
+	userMu      *sync.RWMutex
+	logger      logging.Logger
 }
 
-func NewUsers(dataDir string, client blockstore.Client, logger logging.Logger) *Users {
+// NewUsers creates the users kept in dataDir. The sequence feeds they create,
+// like the ones of their directories, are written in feedFormat.
+func NewUsers(dataDir string, client blockstore.Client, feedFormat feed.Format, logger logging.Logger) *Users {
+	// the feeds of all users share the hints of where their updates are, the
+	// lookups just start from scratch without them
+	hints, err := feed.NewHints(filepath.Join(dataDir, feedHintsDirectoryName), logger)
+	if err != nil {
+		logger.Warningf("feed lookup hints not kept: %v", err)
+	}
+	// the pins of the pods are kept so that their garbage is still found
+	// after a restart, it is only found till then without them
+	pins, err := pod.NewPins(filepath.Join(dataDir, podPinsDirectoryName), logger)
+	if err != nil {
+		logger.Warningf("pod pins not kept: %v", err)
+	}
 	return &Users{
 		dataDir: dataDir,
 		client:  client,
+		feedOptions: feed.Options{
+			Hints:  hints,
+			Format: feedFormat,
+		},
+		podOptions: pod.Options{
+			Pins: pins,
+		},
 		userMap: make(map[string]*Info),
 		userMu:  &sync.RWMutex{},
 		logger:  logger,
@@ -20,6 +20,9 @@ import (
 	"bytes"
 	"crypto/rand"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/ethersphere/bee/pkg/content"
@@ -50,3 +53,44 @@ func Test_Address(t *testing.T) {
 	}
 
 }
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	for _, data := range [][]byte{[]byte("first"), []byte("second, longer")} {
+		err = WriteFileAtomic(path, data, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("expected %q, got %q", data, got)
+		}
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Fatalf("expected mode 0600, got %v", fi.Mode().Perm())
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("temporary file left behind, %d entries", len(entries))
+	}
+
+	err = WriteFileAtomic(filepath.Join(dir, "missing", "file"), []byte("data"), 0600)
+	if err == nil {
+		t.Fatalf("wrote into a missing directory")
+	}
+}